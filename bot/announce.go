@@ -0,0 +1,97 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// parseLanguages splits a comma separated list of language codes.
+func parseLanguages(value string) []string {
+	var languages []string
+	for _, language := range strings.Split(value, ",") {
+		language = strings.ToLower(strings.TrimSpace(language))
+		if language != "" {
+			languages = append(languages, language)
+		}
+	}
+	return languages
+}
+
+// translateAnnouncement posts the announcement translated into each of the
+// channel's configured languages and publishes the translations, so servers
+// following the channel receive them too.
+func translateAnnouncement(s *discordgo.Session, m *discordgo.MessageCreate, languages []string) {
+	if strings.TrimSpace(m.Content) == "" || containsBannedWord(m.GuildID, m.ChannelID, m.Content) {
+		return
+	}
+
+	sourceLang := detectLanguage(m.Content)
+	characters := len([]rune(m.Content))
+	for _, language := range languages {
+		if language == sourceLang {
+			continue
+		}
+		if !checkQuota(s, m.GuildID, characters) {
+			return
+		}
+
+		translated, err := translateTo(m.GuildID, m.Content, language)
+		if err != nil {
+			log.Println("Error translating announcement,", err)
+			if err := recordError(m.GuildID); err != nil {
+				log.Println("Error recording error count,", err)
+			}
+			continue
+		}
+		if err := recordUsage(m.GuildID, characters); err != nil {
+			log.Println("Error recording usage,", err)
+		}
+
+		content := fmt.Sprintf("%s %s", languageFlag(language), translated)
+		if isDryRun(m.GuildID) {
+			postTranslation(s, m, m.ChannelID, content)
+			continue
+		}
+
+		message, err := sendMessage(s, m.ChannelID, content)
+		if err != nil {
+			log.Println("Error posting announcement translation,", err)
+			continue
+		}
+		_, err = s.ChannelMessageCrosspost(m.ChannelID, message.ID)
+		if err != nil {
+			log.Println("Error publishing announcement translation,", err)
+		}
+	}
+}
+
+func handleConfigAnnounceCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var channel *discordgo.Channel
+	var languages []string
+	for _, option := range i.ApplicationCommandData().Options[0].Options {
+		if option.Name == "channel" {
+			channel = option.ChannelValue(s)
+		} else if option.Name == "languages" {
+			languages = parseLanguages(option.StringValue())
+		}
+	}
+
+	err := setChannelSetting(i.GuildID, channel.ID, settingAnnounceLanguages, strings.Join(languages, ","))
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to update announcement languages: %s", err.Error()),
+		})
+		return
+	}
+
+	responseContent := fmt.Sprintf("Announcements in %s will no longer be translated.", channel.Mention())
+	if len(languages) > 0 {
+		responseContent = fmt.Sprintf("Announcements in %s will be translated into %s and published.", channel.Mention(), strings.Join(languages, ", "))
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
@@ -0,0 +1,109 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+
+	"translate-bot/translation"
+)
+
+func handleAPIKeyCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	subCommand := i.ApplicationCommandData().Options[0].Name
+
+	switch subCommand {
+	case "set":
+		handleAPIKeySetCommand(s, i)
+	case "clear":
+		handleAPIKeyClearCommand(s, i)
+	case "status":
+		handleAPIKeyStatusCommand(s, i)
+	}
+}
+
+func handleAPIKeySetCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var provider, apiKey string
+	for _, option := range i.ApplicationCommandData().Options[0].Options {
+		if option.Name == "provider" {
+			provider = option.StringValue()
+		} else if option.Name == "key" {
+			apiKey = strings.TrimSpace(option.StringValue())
+		}
+	}
+
+	encryptedKey, err := encryptSecret(apiKey)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "Error: This bot is not configured to store API keys securely. Ask the bot host to set a master key.",
+		})
+		return
+	}
+
+	b, err := translation.NewKeyed(provider, apiKey)
+	if err == nil {
+		_, err = translation.Translate(b, "Hallo", targetLanguage, translation.Options{})
+	}
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Error: The API key could not be verified: %s", err.Error()),
+		})
+		return
+	}
+
+	err = setGuildSetting(i.GuildID, settingAPIProvider, provider)
+	if err == nil {
+		err = setGuildSetting(i.GuildID, settingAPIKey, encryptedKey)
+	}
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to store API key: %s", err.Error()),
+		})
+		return
+	}
+
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: fmt.Sprintf("This server's translations will now use its own %s API key (%s).", provider, maskAPIKey(apiKey)),
+	})
+}
+
+func handleAPIKeyClearCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	err := setGuildSetting(i.GuildID, settingAPIKey, "")
+	if err == nil {
+		err = setGuildSetting(i.GuildID, settingAPIProvider, "")
+	}
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to clear API key: %s", err.Error()),
+		})
+		return
+	}
+
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: "API key removed. This server's translations will use the bot's default backend.",
+	})
+}
+
+func handleAPIKeyStatusCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	responseContent := fmt.Sprintf("This server uses the bot's default backend (%s).", activeBackend.Name())
+	if storedKey := getGuildSetting(i.GuildID, settingAPIKey); storedKey != "" {
+		apiKey, err := decryptSecret(storedKey)
+		if err != nil {
+			responseContent = "This server's API key can't be decrypted. Set it again with /apikey set."
+		} else {
+			responseContent = fmt.Sprintf("This server uses its own %s API key (%s).", getGuildSetting(i.GuildID, settingAPIProvider), maskAPIKey(apiKey))
+		}
+	}
+
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
+
+// maskAPIKey hides all but the last four characters of a key.
+func maskAPIKey(apiKey string) string {
+	if len(apiKey) <= 4 {
+		return "****"
+	}
+	return "****" + apiKey[len(apiKey)-4:]
+}
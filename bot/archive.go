@@ -0,0 +1,469 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// maxArchiveMessages bounds how many messages one archive job covers.
+	maxArchiveMessages = 5000
+	// archivePace is the pause between archived messages, which keeps a job
+	// from crowding out live translations.
+	archivePace = time.Second
+
+	archiveThread = "thread"
+	archiveFile   = "file"
+)
+
+// archiveJob is a server's bulk translation of a channel's history. It is
+// saved after every message so a job interrupted by a restart or an empty
+// quota picks up where it stopped.
+type archiveJob struct {
+	ChannelID string `json:"channel_id"`
+	Language  string `json:"language"`
+	Output    string `json:"output"`
+	// ReportChannelID is where the finished file, and notices, are posted.
+	ReportChannelID string `json:"report_channel_id"`
+	ThreadID        string `json:"thread_id,omitempty"`
+	File            string `json:"file,omitempty"`
+	// After is the ID of the last archived message; the job continues with
+	// the messages posted after it.
+	After string `json:"after"`
+	// Until is the ID past which messages are left out, if any.
+	Until     string `json:"until,omitempty"`
+	Remaining int    `json:"remaining"`
+	Done      int    `json:"done"`
+}
+
+var (
+	archiveMu sync.Mutex
+	// archiveRunning marks the servers whose archive job is being worked on.
+	archiveRunning = make(map[string]bool)
+)
+
+func loadArchiveJob(serverID string) (*archiveJob, bool) {
+	value := getGuildSetting(serverID, settingArchiveJob)
+	if value == "" {
+		return nil, false
+	}
+	var job archiveJob
+	if err := json.Unmarshal([]byte(value), &job); err != nil {
+		log.Println("Error reading archive job,", err)
+		return nil, false
+	}
+	return &job, true
+}
+
+func saveArchiveJob(serverID string, job *archiveJob) error {
+	if job == nil {
+		return setGuildSetting(serverID, settingArchiveJob, "")
+	}
+	value, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return setGuildSetting(serverID, settingArchiveJob, string(value))
+}
+
+// resumeArchiveJobs restarts the archive jobs that were running when the
+// bot stopped.
+func resumeArchiveJobs(s *discordgo.Session) {
+	for guildID, guild := range settings.Guilds() {
+		if guild[settingArchiveJob] != "" {
+			startArchiveJob(s, guildID)
+		}
+	}
+}
+
+// startArchiveJob works on the server's archive job in the background,
+// unless that is already happening.
+func startArchiveJob(s *discordgo.Session, serverID string) {
+	archiveMu.Lock()
+	defer archiveMu.Unlock()
+	if archiveRunning[serverID] {
+		return
+	}
+	archiveRunning[serverID] = true
+	go func() {
+		runArchiveJob(s, serverID)
+		archiveMu.Lock()
+		delete(archiveRunning, serverID)
+		archiveMu.Unlock()
+	}()
+}
+
+func runArchiveJob(s *discordgo.Session, serverID string) {
+	for {
+		job, ok := loadArchiveJob(serverID)
+		if !ok {
+			// Cancelled.
+			return
+		}
+
+		messages, err := s.ChannelMessages(job.ChannelID, 100, "", job.After, "")
+		if err != nil {
+			log.Println("Error fetching messages to archive,", err)
+			notifyArchive(s, job, fmt.Sprintf("⚠️ Archiving <#%s> stopped: %s. Continue with `/archive resume`.", job.ChannelID, err.Error()))
+			return
+		}
+		sort.Slice(messages, func(a, b int) bool { return snowflakeLess(messages[a].ID, messages[b].ID) })
+
+		for _, message := range messages {
+			if job.Remaining <= 0 || (job.Until != "" && snowflakeLess(job.Until, message.ID)) {
+				finishArchiveJob(s, serverID, job)
+				return
+			}
+			if !archiveMessage(s, serverID, job, message) {
+				notifyArchive(s, job, fmt.Sprintf("⚠️ Archiving <#%s> paused because the translation quota is used up. Continue with `/archive resume`.", job.ChannelID))
+				return
+			}
+			job.After = message.ID
+			job.Remaining--
+			job.Done++
+
+			// A cancel while translating wins over this save.
+			if _, ok := loadArchiveJob(serverID); !ok {
+				return
+			}
+			if err := saveArchiveJob(serverID, job); err != nil {
+				log.Println("Error saving archive job,", err)
+			}
+			time.Sleep(archivePace)
+		}
+		if len(messages) == 0 {
+			finishArchiveJob(s, serverID, job)
+			return
+		}
+	}
+}
+
+// archiveMessage adds the message's translation to the archive. It returns
+// false when the quota is used up.
+func archiveMessage(s *discordgo.Session, serverID string, job *archiveJob, message *discordgo.Message) bool {
+	if strings.TrimSpace(message.Content) == "" || message.Author == nil {
+		return true
+	}
+
+	translated := message.Content
+	if detectLanguage(message.Content) != job.Language {
+		characters := len([]rune(message.Content))
+		if !checkQuota(s, serverID, characters) {
+			return false
+		}
+		var err error
+		translated, err = translateTo(serverID, message.Content, job.Language)
+		if err != nil {
+			log.Println("Error translating archived message,", err)
+			translated = message.Content
+		} else if err := recordUsage(serverID, characters); err != nil {
+			log.Println("Error recording usage,", err)
+		}
+	}
+
+	if job.Output == archiveFile {
+		line := fmt.Sprintf("[%s] %s: %s\n", message.Timestamp.UTC().Format("2006-01-02 15:04"), message.Author.Username, translated)
+		file, err := os.OpenFile(job.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			log.Println("Error opening archive file,", err)
+			return true
+		}
+		defer file.Close()
+		if _, err := file.WriteString(line); err != nil {
+			log.Println("Error writing archive file,", err)
+		}
+		return true
+	}
+
+	queueMessage(s, job.ThreadID, fmt.Sprintf("-# %s · %s\n**%s**: %s",
+		message.Timestamp.UTC().Format("2006-01-02 15:04"), messageJumpURL(serverID, message.ChannelID, message.ID), message.Author.Username, translated))
+	return true
+}
+
+func finishArchiveJob(s *discordgo.Session, serverID string, job *archiveJob) {
+	if err := saveArchiveJob(serverID, nil); err != nil {
+		log.Println("Error clearing archive job,", err)
+	}
+
+	if job.Output != archiveFile {
+		notifyArchive(s, job, fmt.Sprintf("✅ Archived %d messages of <#%s> into <#%s>.", job.Done, job.ChannelID, job.ThreadID))
+		return
+	}
+
+	file, err := os.Open(job.File)
+	if err != nil {
+		notifyArchive(s, job, fmt.Sprintf("✅ Archived %d messages of <#%s>, but none had text.", job.Done, job.ChannelID))
+		return
+	}
+	defer os.Remove(job.File)
+	defer file.Close()
+	_, err = sendQueued(s, job.ReportChannelID, &discordgo.MessageSend{
+		Content:         fmt.Sprintf("✅ Archived %d messages of <#%s> in %s %s.", job.Done, job.ChannelID, languageFlag(job.Language), job.Language),
+		Files:           []*discordgo.File{{Name: filepath.Base(job.File), ContentType: "text/plain", Reader: file}},
+		AllowedMentions: translationMentions,
+	})
+	if err != nil {
+		log.Println("Error posting archive file,", err)
+	}
+}
+
+func notifyArchive(s *discordgo.Session, job *archiveJob, content string) {
+	queueMessage(s, job.ReportChannelID, content)
+}
+
+// snowflakeLess reports whether the first ID is older than the second.
+func snowflakeLess(a, b string) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return a < b
+}
+
+// snowflakeAt returns the smallest ID a message posted at the time can have.
+func snowflakeAt(t time.Time) string {
+	const discordEpoch = 1420070400000
+	return strconv.FormatInt((t.UnixMilli()-discordEpoch)<<22, 10)
+}
+
+// archiveDir is where file archives are written while their job runs.
+func archiveDir() string {
+	if dir := os.Getenv("ARCHIVE_DIR"); dir != "" {
+		return dir
+	}
+	return "archives"
+}
+
+func handleArchiveCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	subCommand := i.ApplicationCommandData().Options[0].Name
+
+	switch subCommand {
+	case "start":
+		handleArchiveStartCommand(s, i)
+	case "status":
+		handleArchiveStatusCommand(s, i)
+	case "resume":
+		handleArchiveResumeCommand(s, i)
+	case "cancel":
+		handleArchiveCancelCommand(s, i)
+	}
+}
+
+func handleArchiveStartCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if _, ok := loadArchiveJob(i.GuildID); ok {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "Error: An archive job is already running. Check it with `/archive status` or stop it with `/archive cancel`.",
+		})
+		return
+	}
+
+	job := &archiveJob{
+		Language:        guildTargetLanguage(i.GuildID),
+		Output:          archiveThread,
+		ReportChannelID: i.ChannelID,
+		Remaining:       maxArchiveMessages,
+	}
+	var messages int64
+	var since, until string
+	for _, option := range i.ApplicationCommandData().Options[0].Options {
+		switch option.Name {
+		case "channel":
+			job.ChannelID = option.ChannelValue(s).ID
+		case "messages":
+			messages = option.IntValue()
+		case "since":
+			since = option.StringValue()
+		case "until":
+			until = option.StringValue()
+		case "language":
+			job.Language = strings.ToLower(strings.TrimSpace(option.StringValue()))
+		case "output":
+			job.Output = option.StringValue()
+		}
+	}
+
+	if messages < 0 || messages > maxArchiveMessages {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Error: The number of messages must be between 1 and %d.", maxArchiveMessages),
+		})
+		return
+	}
+	if messages == 0 && since == "" {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "Error: Give the number of messages to archive or the date to start from.",
+		})
+		return
+	}
+
+	if since != "" {
+		start, err := time.Parse("2006-01-02", since)
+		if err != nil {
+			respond(s, i, &discordgo.InteractionResponseData{
+				Content: "Error: Dates must look like 2024-01-31.",
+			})
+			return
+		}
+		job.After = snowflakeAt(start)
+	}
+	if until != "" {
+		end, err := time.Parse("2006-01-02", until)
+		if err != nil {
+			respond(s, i, &discordgo.InteractionResponseData{
+				Content: "Error: Dates must look like 2024-01-31.",
+			})
+			return
+		}
+		// The until date is included.
+		job.Until = snowflakeAt(end.Add(24 * time.Hour))
+	}
+	if messages > 0 {
+		job.Remaining = int(messages)
+		if since == "" {
+			after, err := lastMessagesStart(s, job.ChannelID, int(messages), job.Until)
+			if err != nil {
+				respond(s, i, &discordgo.InteractionResponseData{
+					Content: fmt.Sprintf("Failed to find the messages to archive: %s", err.Error()),
+				})
+				return
+			}
+			job.After = after
+		}
+	}
+
+	if job.Output == archiveFile {
+		if err := os.MkdirAll(archiveDir(), 0o700); err != nil {
+			respond(s, i, &discordgo.InteractionResponseData{
+				Content: fmt.Sprintf("Failed to start the archive: %s", err.Error()),
+			})
+			return
+		}
+		job.File = filepath.Join(archiveDir(), fmt.Sprintf("%s-%s-%d.txt", i.GuildID, job.ChannelID, time.Now().Unix()))
+	} else {
+		channel, err := lookupChannel(s, job.ChannelID)
+		name := "Translation archive"
+		if err == nil {
+			name = fmt.Sprintf("%s %s archive", languageFlag(job.Language), channel.Name)
+		}
+		thread, err := s.ThreadStart(i.ChannelID, name, discordgo.ChannelTypeGuildPublicThread, 10080)
+		if err != nil {
+			respond(s, i, &discordgo.InteractionResponseData{
+				Content: fmt.Sprintf("Failed to create the archive thread: %s", err.Error()),
+			})
+			return
+		}
+		job.ThreadID = thread.ID
+	}
+
+	err := saveArchiveJob(i.GuildID, job)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to start the archive: %s", err.Error()),
+		})
+		return
+	}
+	startArchiveJob(s, i.GuildID)
+
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: fmt.Sprintf("Archiving <#%s> in %s %s. This takes about a second per message; I'll post here when it's done.", job.ChannelID, languageFlag(job.Language), job.Language),
+	})
+}
+
+// lastMessagesStart returns the ID after which the channel's last count
+// messages, before until if given, begin.
+func lastMessagesStart(s *discordgo.Session, channelID string, count int, until string) (string, error) {
+	before := until
+	oldest := ""
+	for count > 0 {
+		limit := min(count, 100)
+		messages, err := s.ChannelMessages(channelID, limit, before, "", "")
+		if err != nil {
+			return "", err
+		}
+		if len(messages) == 0 {
+			break
+		}
+		// Messages come newest first.
+		oldest = messages[len(messages)-1].ID
+		before = oldest
+		count -= len(messages)
+		if len(messages) < limit {
+			break
+		}
+	}
+	if oldest == "" {
+		return before, nil
+	}
+	id, err := strconv.ParseUint(oldest, 10, 64)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatUint(id-1, 10), nil
+}
+
+func handleArchiveStatusCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	job, ok := loadArchiveJob(i.GuildID)
+	if !ok {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "No archive job is running.",
+		})
+		return
+	}
+
+	archiveMu.Lock()
+	running := archiveRunning[i.GuildID]
+	archiveMu.Unlock()
+	state := "paused, continue with `/archive resume`"
+	if running {
+		state = "running"
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: fmt.Sprintf("Archiving <#%s> in %s %s: %d messages done, %s.", job.ChannelID, languageFlag(job.Language), job.Language, job.Done, state),
+	})
+}
+
+func handleArchiveResumeCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	job, ok := loadArchiveJob(i.GuildID)
+	if !ok {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "Error: No archive job is running.",
+		})
+		return
+	}
+	startArchiveJob(s, i.GuildID)
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: fmt.Sprintf("Archiving <#%s> continues after %d messages.", job.ChannelID, job.Done),
+	})
+}
+
+func handleArchiveCancelCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	job, ok := loadArchiveJob(i.GuildID)
+	if !ok {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "Error: No archive job is running.",
+		})
+		return
+	}
+
+	err := saveArchiveJob(i.GuildID, nil)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to cancel the archive: %s", err.Error()),
+		})
+		return
+	}
+	if job.File != "" {
+		os.Remove(job.File)
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: fmt.Sprintf("Cancelled archiving <#%s> after %d messages.", job.ChannelID, job.Done),
+	})
+}
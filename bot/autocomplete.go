@@ -0,0 +1,174 @@
+package bot
+
+import (
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+
+	"translate-bot/filter"
+)
+
+// maxChoices is the most autocomplete suggestions Discord shows.
+const maxChoices = 25
+
+// autocompleters suggest values for command options, by the full name of the
+// option such as "banword remove word". They get what the user typed so far.
+var autocompleters = map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate, typed string) []*discordgo.ApplicationCommandOptionChoice{
+	"banword remove word":      banwordChoices,
+	"translate remove channel": translateChannelChoices,
+	"route remove from":        routeSourceChoices,
+	"route remove to":          routeDestinationChoices,
+	"config timezone zone":     timezoneChoices,
+}
+
+// handleAutocomplete answers an autocomplete request with suggestions for
+// the option being typed.
+func handleAutocomplete(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+	path, option := focusedOption(data.Name, data.Options)
+	suggest, ok := autocompleters[path]
+	if option == nil || !ok {
+		return
+	}
+
+	typed, _ := option.Value.(string)
+	choices := suggest(s, i, typed)
+	if len(choices) > maxChoices {
+		choices = choices[:maxChoices]
+	}
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: choices,
+		},
+	})
+	if err != nil {
+		log.Println("Error responding to autocomplete,", err)
+	}
+}
+
+// focusedOption finds the option being typed, returning it with its full
+// name.
+func focusedOption(name string, options []*discordgo.ApplicationCommandInteractionDataOption) (string, *discordgo.ApplicationCommandInteractionDataOption) {
+	for _, option := range options {
+		switch {
+		case option.Type == discordgo.ApplicationCommandOptionSubCommand || option.Type == discordgo.ApplicationCommandOptionSubCommandGroup:
+			if path, focused := focusedOption(name+" "+option.Name, option.Options); focused != nil {
+				return path, focused
+			}
+		case option.Focused:
+			return name + " " + option.Name, option
+		}
+	}
+	return "", nil
+}
+
+// banwordChoices suggests banned words containing what was typed, as they
+// are stored.
+func banwordChoices(s *discordgo.Session, i *discordgo.InteractionCreate, typed string) []*discordgo.ApplicationCommandOptionChoice {
+	words, err := store.BannedWords()
+	if err != nil {
+		log.Println("Error loading banned words,", err)
+		return nil
+	}
+	typed = filter.Normalize(strings.TrimSpace(typed))
+
+	var matching []string
+	for _, word := range words {
+		if strings.Contains(filter.Normalize(word.Word), typed) {
+			matching = append(matching, word.Word)
+		}
+	}
+	slices.Sort(matching)
+
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, min(len(matching), maxChoices))
+	for _, word := range matching {
+		if len(choices) == maxChoices {
+			break
+		}
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: word, Value: word})
+	}
+	return choices
+}
+
+// translateChannelChoices suggests the server's translated channels.
+func translateChannelChoices(s *discordgo.Session, i *discordgo.InteractionCreate, typed string) []*discordgo.ApplicationCommandOptionChoice {
+	var channelIDs []string
+	for _, channelID := range translateChannels[i.GuildID] {
+		if channelID != "" {
+			channelIDs = append(channelIDs, channelID)
+		}
+	}
+	return channelChoices(s, channelIDs, typed)
+}
+
+// routeSourceChoices suggests the channels the server's routes leave from.
+func routeSourceChoices(s *discordgo.Session, i *discordgo.InteractionCreate, typed string) []*discordgo.ApplicationCommandOptionChoice {
+	var channelIDs []string
+	for sourceChannelID, channelRoutes := range routes {
+		if len(channelRoutes) > 0 && channelRoutes[0].ServerID == i.GuildID {
+			channelIDs = append(channelIDs, sourceChannelID)
+		}
+	}
+	return channelChoices(s, channelIDs, typed)
+}
+
+// routeDestinationChoices suggests the channels routes lead to, only from
+// the chosen source channel once there is one.
+func routeDestinationChoices(s *discordgo.Session, i *discordgo.InteractionCreate, typed string) []*discordgo.ApplicationCommandOptionChoice {
+	sourceChannelID := ""
+	for _, option := range i.ApplicationCommandData().Options[0].Options {
+		if option.Name == "from" {
+			sourceChannelID = channelIDValue(option.StringValue())
+		}
+	}
+
+	var channelIDs []string
+	for routeSourceID, channelRoutes := range routes {
+		if sourceChannelID != "" && routeSourceID != sourceChannelID {
+			continue
+		}
+		for _, route := range channelRoutes {
+			if route.ServerID == i.GuildID && !slices.Contains(channelIDs, route.DestinationChannelID) {
+				channelIDs = append(channelIDs, route.DestinationChannelID)
+			}
+		}
+	}
+	return channelChoices(s, channelIDs, typed)
+}
+
+// channelChoices suggests the channels whose name contains what was typed,
+// sorted by name. The value of each is the channel ID.
+func channelChoices(s *discordgo.Session, channelIDs []string, typed string) []*discordgo.ApplicationCommandOptionChoice {
+	typed = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(typed), "#"))
+
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, channelID := range channelIDs {
+		name := channelID
+		if channel, err := s.State.Channel(channelID); err == nil {
+			name = "#" + channel.Name
+		}
+		if strings.Contains(strings.ToLower(name), typed) {
+			choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: name, Value: channelID})
+		}
+	}
+	slices.SortFunc(choices, func(a, b *discordgo.ApplicationCommandOptionChoice) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return choices
+}
+
+// channelIDValue reads a channel from a string option, which holds its ID
+// when picked from the suggestions but may also be a typed mention.
+func channelIDValue(value string) string {
+	value = strings.TrimSpace(value)
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "<#"), ">")
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return ""
+		}
+	}
+	return value
+}
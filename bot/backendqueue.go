@@ -0,0 +1,122 @@
+package bot
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// While a guild's backend is down, up to backendQueueLimit messages per
+// channel wait for it to recover, as long as they aren't older than
+// backendQueueAge by then.
+const (
+	backendQueueLimit = 50
+	backendQueueAge   = 6 * time.Hour
+)
+
+var (
+	backendQueueMu sync.Mutex
+	// backendQueue holds the messages waiting for the backend, oldest first,
+	// by channel.
+	backendQueue = make(map[string][]*pipelineMessage)
+)
+
+// queueUntilRecovered holds the message back for translation once the
+// guild's backend recovers, if the guild wants that and the backend's
+// breaker is open. It reports whether the message was queued.
+func queueUntilRecovered(p *pipelineMessage) bool {
+	if getGuildSetting(p.m.GuildID, settingBackendQueue) == "" || !backendDown(breakerKey(guildBackend(p.m.GuildID))) {
+		return false
+	}
+
+	backendQueueMu.Lock()
+	defer backendQueueMu.Unlock()
+
+	queued := backendQueue[p.m.ChannelID]
+	if p.delayed {
+		// A message that failed again while draining keeps its place.
+		backendQueue[p.m.ChannelID] = append([]*pipelineMessage{p}, queued...)
+		return true
+	}
+	if len(queued) >= backendQueueLimit {
+		return false
+	}
+	p.delayed = true
+	backendQueue[p.m.ChannelID] = append(queued, p)
+	return true
+}
+
+// drainBackendQueues translates the queued messages of the channels whose
+// backend is back, in the order they were posted.
+func drainBackendQueues(s *discordgo.Session) {
+	backendQueueMu.Lock()
+	channelIDs := sortedKeys(backendQueue)
+	backendQueueMu.Unlock()
+
+	for _, channelID := range channelIDs {
+		drainBackendQueue(channelID)
+	}
+}
+
+func drainBackendQueue(channelID string) {
+	for {
+		backendQueueMu.Lock()
+		queued := backendQueue[channelID]
+		if len(queued) == 0 {
+			delete(backendQueue, channelID)
+			backendQueueMu.Unlock()
+			return
+		}
+		p := queued[0]
+		backendQueue[channelID] = queued[1:]
+		backendQueueMu.Unlock()
+
+		if time.Since(p.m.Timestamp) > backendQueueAge {
+			reportSkipped(p.s, p.m, "the translation backend was down for too long")
+			continue
+		}
+		resumePipeline(p, "translate")
+
+		backendQueueMu.Lock()
+		requeued := len(backendQueue[channelID]) > 0 && backendQueue[channelID][0] == p
+		backendQueueMu.Unlock()
+		if requeued {
+			return
+		}
+	}
+}
+
+func handleConfigBackendQueueCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	enabled := i.ApplicationCommandData().Options[0].Options[0].BoolValue()
+
+	value := ""
+	if enabled {
+		value = "on"
+	}
+	err := setGuildSetting(i.GuildID, settingBackendQueue, value)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to update the backend queue: %s", err.Error()),
+		})
+		return
+	}
+	if !enabled {
+		backendQueueMu.Lock()
+		for channelID, queued := range backendQueue {
+			if len(queued) > 0 && queued[0].m.GuildID == i.GuildID {
+				delete(backendQueue, channelID)
+			}
+		}
+		backendQueueMu.Unlock()
+	}
+
+	responseContent := "Messages that arrive while the translation backend is down will be left untranslated."
+	if enabled {
+		responseContent = fmt.Sprintf("While the translation backend is down I'll hold up to %d messages per channel and translate them when it recovers, marked as delayed.", backendQueueLimit)
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
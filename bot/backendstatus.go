@@ -0,0 +1,165 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"translate-bot/translation"
+)
+
+// backendCallsKept is how many of each backend's latest calls /backend
+// status reports on.
+const backendCallsKept = 100
+
+// backendCall is the outcome of one call to a backend.
+type backendCall struct {
+	latency time.Duration
+	failed  bool
+}
+
+var (
+	backendCallsMu sync.Mutex
+	// backendCalls holds the latest calls by breaker key, oldest first.
+	backendCalls = make(map[string][]backendCall)
+)
+
+func recordBackendCall(key string, latency time.Duration, err error) {
+	backendCallsMu.Lock()
+	defer backendCallsMu.Unlock()
+
+	calls := append(backendCalls[key], backendCall{latency: latency, failed: err != nil})
+	if len(calls) > backendCallsKept {
+		calls = calls[len(calls)-backendCallsKept:]
+	}
+	backendCalls[key] = calls
+}
+
+// backendCallSummary returns how many of the backend's latest calls there
+// were, how many failed and how long the successful ones took on average.
+func backendCallSummary(key string) (calls, failures int, average time.Duration) {
+	backendCallsMu.Lock()
+	defer backendCallsMu.Unlock()
+
+	var total time.Duration
+	for _, call := range backendCalls[key] {
+		calls++
+		if call.failed {
+			failures++
+			continue
+		}
+		total += call.latency
+	}
+	if succeeded := calls - failures; succeeded > 0 {
+		average = total / time.Duration(succeeded)
+	}
+	return calls, failures, average
+}
+
+// chainedBackend is one of the backends a guild's translations may go to,
+// in the order guildBackend considers them.
+type chainedBackend struct {
+	role    string
+	backend translation.Backend
+	// err says why the backend can't be used, when it can't.
+	err error
+}
+
+func backendChain(serverID string) []chainedBackend {
+	var chain []chainedBackend
+	if b, err := guildKeyedBackend(serverID); b != nil || err != nil {
+		chain = append(chain, chainedBackend{role: "server API key", backend: b, err: err})
+	}
+	if premiumBackend != nil {
+		entry := chainedBackend{role: "premium", backend: premiumBackend}
+		if !hasPremium(serverID) {
+			entry.err = fmt.Errorf("this server has no license")
+		}
+		chain = append(chain, entry)
+	}
+	return append(chain, chainedBackend{role: "default", backend: activeBackend})
+}
+
+// backendHealth describes the state of the backend's breaker.
+func backendHealth(key string) string {
+	failures, openedAt := breakerState(key)
+	switch {
+	case !openedAt.IsZero():
+		return fmt.Sprintf("⛔ down since <t:%d:R>, next check <t:%d:R>", openedAt.Unix(), openedAt.Add(breakerCooldown).Unix())
+	case failures > 0:
+		return fmt.Sprintf("⚠️ failed the last %d times", failures)
+	}
+	return "✅ up"
+}
+
+func handleBackendCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	switch i.ApplicationCommandData().Options[0].Name {
+	case "status":
+		handleBackendStatusCommand(s, i)
+	case "race":
+		handleBackendRaceCommand(s, i)
+	case "stream":
+		handleBackendStreamCommand(s, i)
+	case "llm":
+		handleBackendLLMCommand(s, i)
+	}
+}
+
+func handleBackendStatusCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	b := guildBackend(i.GuildID)
+	key := breakerKey(b)
+
+	lines := []string{
+		fmt.Sprintf("**Backend:** %s", b.Name()),
+		fmt.Sprintf("**Health:** %s", backendHealth(key)),
+	}
+
+	calls, failures, average := backendCallSummary(key)
+	if calls == 0 {
+		lines = append(lines, "**Recent calls:** none since the bot started")
+	} else {
+		lines = append(lines, fmt.Sprintf("**Recent calls:** %d, %d failed (%.1f%%), %d ms on average",
+			calls, failures, 100*float64(failures)/float64(calls), average.Milliseconds()))
+	}
+
+	quota := "not reported by this backend"
+	if reporter, ok := b.(translation.QuotaReporter); ok {
+		used, limit, err := reporter.Quota()
+		switch {
+		case err != nil:
+			quota = "unavailable: " + err.Error()
+		case limit > 0:
+			quota = fmt.Sprintf("%d of %d characters used, %d left", used, limit, limit-used)
+		default:
+			quota = fmt.Sprintf("%d characters used, no limit", used)
+		}
+	}
+	lines = append(lines, "**Quota:** "+quota)
+
+	lines = append(lines, "**Fallback chain:**")
+	inUse := false
+	for n, entry := range backendChain(i.GuildID) {
+		var state string
+		switch {
+		case entry.err != nil:
+			state = "skipped, " + entry.err.Error()
+		case !inUse && breakerKey(entry.backend) == key:
+			state = "in use, " + backendHealth(breakerKey(entry.backend))
+			inUse = true
+		default:
+			state = backendHealth(breakerKey(entry.backend))
+		}
+		name := getGuildSetting(i.GuildID, settingAPIProvider)
+		if entry.backend != nil {
+			name = entry.backend.Name()
+		}
+		lines = append(lines, fmt.Sprintf("%d. %s (%s): %s", n+1, entry.role, name, state))
+	}
+
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: strings.Join(lines, "\n"),
+	})
+}
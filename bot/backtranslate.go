@@ -0,0 +1,92 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// backTranslationThreshold is the share of words a round trip has to keep
+// before a translation is considered trustworthy.
+const backTranslationThreshold = 0.4
+
+// roundTripScore returns the overlap between the words of the original text
+// and its back-translation, from 0 (nothing in common) to 1 (identical).
+func roundTripScore(original, back string) float64 {
+	words := func(text string) map[string]bool {
+		set := make(map[string]bool)
+		for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+		}) {
+			set[word] = true
+		}
+		return set
+	}
+
+	originalWords, backWords := words(original), words(back)
+	if len(originalWords) == 0 || len(backWords) == 0 {
+		return 1
+	}
+	shared := 0
+	for word := range originalWords {
+		if backWords[word] {
+			shared++
+		}
+	}
+	return 2 * float64(shared) / float64(len(originalWords)+len(backWords))
+}
+
+// backTranslationScore translates the output back into the source language
+// and returns how much of the original survived the round trip. The second
+// result is false when the check is disabled for the server or couldn't run.
+func backTranslationScore(s *discordgo.Session, serverID, original, translated string) (float64, bool) {
+	if getGuildSetting(serverID, settingBackTranslate) == "" {
+		return 0, false
+	}
+	sourceLang := detectLanguage(original)
+	if sourceLang == "?" || sourceLang == guildTargetLanguage(serverID) {
+		return 0, false
+	}
+
+	characters := len([]rune(translated))
+	if !checkQuota(s, serverID, characters) {
+		return 0, false
+	}
+	back, err := translateTo(serverID, translated, sourceLang)
+	if err != nil {
+		log.Println("Error back-translating message,", err)
+		return 0, false
+	}
+	if err := recordUsage(serverID, characters); err != nil {
+		log.Println("Error recording usage,", err)
+	}
+
+	return roundTripScore(original, back), true
+}
+
+func handleConfigBackTranslateCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	enabled := i.ApplicationCommandData().Options[0].Options[0].BoolValue()
+
+	value := ""
+	if enabled {
+		value = "on"
+	}
+	err := setGuildSetting(i.GuildID, settingBackTranslate, value)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to update back-translation checks: %s", err.Error()),
+		})
+		return
+	}
+
+	responseContent := "Translations will no longer be checked by translating them back."
+	if enabled {
+		responseContent = "Translations will be translated back and flagged with ⚠️ when the round trip diverges. This uses extra quota."
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
@@ -0,0 +1,544 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"translate-bot/filter"
+	"translate-bot/storage"
+)
+
+// Banned word severities decide what happens to a message with the word.
+const (
+	// severityWarn lets the message be translated and alerts moderators.
+	severityWarn = "warn"
+	// severityBlock holds the message back from translation.
+	severityBlock = "block"
+	// severityEscalate holds the message back and deletes the original.
+	severityEscalate = "escalate"
+)
+
+// banwordExemptAll exempts a channel from the whole banword filter.
+const banwordExemptAll = "*"
+
+// maxBanDays is the longest a word can be banned for temporarily.
+const maxBanDays = 365
+
+// severities lists the severities from the mildest to the most severe.
+var severities = []string{severityWarn, severityBlock, severityEscalate}
+
+func loadBannedWords() error {
+	words, err := store.BannedWords()
+	if err != nil {
+		return err
+	}
+	bannedWords = make(map[string]storage.BannedWord, len(words))
+	for _, word := range words {
+		bannedWords[filter.Normalize(word.Word)] = word
+	}
+	return nil
+}
+
+// wordSeverity returns the severity of a ban list entry. Words banned before
+// severities existed block messages.
+func wordSeverity(word storage.BannedWord) string {
+	if word.Severity == "" {
+		return severityBlock
+	}
+	return word.Severity
+}
+
+// severityIn returns the severity of a ban list entry in the server. The ban
+// list is shared by all servers, and escalation deletes messages, so it only
+// applies in the server that banned the word. Elsewhere the word blocks.
+func severityIn(serverID string, word storage.BannedWord) string {
+	severity := wordSeverity(word)
+	if severity == severityEscalate && (word.ServerID == "" || word.ServerID != serverID) {
+		return severityBlock
+	}
+	return severity
+}
+
+// banwordMatch is a banned word found in a text and the rule banning it.
+type banwordMatch struct {
+	word     string
+	severity string
+	// pack is the preset pack banning the word, or empty for the ban list.
+	pack string
+	// exempt is set when the channel may use the word.
+	exempt bool
+	// local is set when the server banned the word itself, on the ban list
+	// or through a preset pack it enabled.
+	local bool
+}
+
+// matchBannedWords returns the banned words of the text, from the ban list
+// first and then the preset packs the server enabled, each once.
+func matchBannedWords(serverID, channelID, text string) []banwordMatch {
+	exempt := getChannelSetting(channelID, settingBanwordExempt)
+	exemptWords := strings.Split(filter.Normalize(exempt), ",")
+	isExempt := func(word string) bool {
+		return exempt == banwordExemptAll || slices.Contains(exemptWords, word)
+	}
+
+	var matches []banwordMatch
+	for _, word := range filter.FindBannedWords(text, bannedWords) {
+		entry := bannedWords[word]
+		matches = append(matches, banwordMatch{
+			word:     word,
+			severity: severityIn(serverID, entry),
+			exempt:   isExempt(word),
+			local:    serverID != "" && entry.ServerID == serverID,
+		})
+	}
+	// Words of preset packs block messages unless the ban list says
+	// otherwise.
+	for _, pack := range enabledPacks(serverID) {
+		for _, word := range filter.FindBannedWords(text, filter.PackWords(pack)) {
+			if slices.ContainsFunc(matches, func(match banwordMatch) bool { return match.word == word }) {
+				continue
+			}
+			matches = append(matches, banwordMatch{word: word, severity: severityBlock, pack: pack, exempt: isExempt(word), local: true})
+		}
+	}
+	return matches
+}
+
+// bannedWordSeverity returns the most severe of the text's banned words and
+// the banned words it contains, leaving out the words the channel is exempt
+// from. The severity is empty when there are none.
+func bannedWordSeverity(serverID, channelID, text string) (string, []string) {
+	return strongestMatch(matchBannedWords(serverID, channelID, text), false)
+}
+
+// strongestMatch returns the most severe of the matches the channel isn't
+// exempt from and their words, only of the server's own words when local is
+// set.
+func strongestMatch(matches []banwordMatch, local bool) (string, []string) {
+	var words []string
+	severity := ""
+	for _, match := range matches {
+		if match.exempt || (local && !match.local) {
+			continue
+		}
+		words = append(words, match.word)
+		if slices.Index(severities, match.severity) > slices.Index(severities, severity) {
+			severity = match.severity
+		}
+	}
+	return severity, words
+}
+
+// containsBannedWord reports whether the text has a banned word that keeps it
+// from being translated in the channel.
+func containsBannedWord(serverID, channelID, text string) bool {
+	severity, _ := bannedWordSeverity(serverID, channelID, text)
+	return severity == severityBlock || severity == severityEscalate
+}
+
+// recordBanwordHits counts a match of each word in the server.
+func recordBanwordHits(serverID string, words []string) {
+	now := time.Now()
+	banwordHitsMu.Lock()
+	for _, word := range words {
+		banwordHitCounts[[2]string{serverID, word}]++
+	}
+	banwordHitsMu.Unlock()
+
+	for _, word := range words {
+		if err := store.RecordBanwordHit(serverID, word, now); err != nil {
+			log.Println("Error recording banword hit,", err)
+		}
+	}
+}
+
+// enabledPacks returns the languages of the preset banword packs the server
+// enabled.
+func enabledPacks(serverID string) []string {
+	packs := getGuildSetting(serverID, settingBanwordPacks)
+	if packs == "" {
+		return nil
+	}
+	return strings.Split(packs, ",")
+}
+
+// pruneBannedWords removes the words whose ban expired.
+func pruneBannedWords(s *discordgo.Session) {
+	removed, err := store.PruneBannedWords(time.Now())
+	if err != nil {
+		log.Println("Error removing expired banned words,", err)
+		return
+	}
+	if removed > 0 {
+		if err := reloadShared(changeBannedWords); err != nil {
+			log.Println("Error loading banned words,", err)
+		}
+	}
+}
+
+// deleteOriginal removes a message with an escalated banned word. Dry runs
+// and simulations leave it in place.
+func deleteOriginal(s *discordgo.Session, m *discordgo.MessageCreate) {
+	if isDryRun(m.GuildID) || simulatedPost != nil {
+		return
+	}
+	if err := s.ChannelMessageDelete(m.ChannelID, m.ID); err != nil {
+		log.Println("Error deleting message with a banned word,", err)
+	}
+}
+
+func handleBanwordCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	subCommand := i.ApplicationCommandData().Options[0].Name
+
+	switch subCommand {
+	case "add":
+		handleBanwordAddCommand(s, i)
+	case "remove":
+		handleBanwordRemoveCommand(s, i)
+	case "list":
+		handleBanwordListCommand(s, i)
+	case "preset":
+		handleBanwordPresetCommand(s, i)
+	case "channel":
+		handleBanwordChannelCommand(s, i)
+	case "test":
+		handleBanwordTestCommand(s, i)
+	}
+}
+
+func handleBanwordAddCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Member == nil || i.Member.Permissions&discordgo.PermissionManageServer == 0 {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "Error: Banning words requires the Manage Server permission.",
+		})
+		return
+	}
+
+	var words string
+	var days int64
+	severity := severityBlock
+	for _, option := range i.ApplicationCommandData().Options[0].Options {
+		switch option.Name {
+		case "words":
+			words = option.StringValue()
+		case "severity":
+			severity = option.StringValue()
+		case "days":
+			days = option.IntValue()
+		}
+	}
+	if days < 0 || days > maxBanDays {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Error: Words can be banned for 1 to %d days, or 0 for good.", maxBanDays),
+		})
+		return
+	}
+	var expiresAt time.Time
+	if days > 0 {
+		expiresAt = time.Now().AddDate(0, 0, int(days))
+	}
+
+	wordList := strings.Split(words, ",")
+	var addedWords []string
+	for _, word := range wordList {
+		word = strings.TrimSpace(strings.ToLower(word))
+		if word == "" {
+			continue
+		}
+		added, err := store.AddBannedWord(storage.BannedWord{Word: word, Severity: severity, ExpiresAt: expiresAt, ServerID: i.GuildID})
+		if err != nil {
+			respond(s, i, &discordgo.InteractionResponseData{
+				Content: fmt.Sprintf("Failed to add word '%s' to ban list: %s", word, err.Error()),
+			})
+			return
+		}
+		if added {
+			addedWords = append(addedWords, word)
+		}
+	}
+
+	if len(addedWords) > 0 {
+		// Refresh the banned words in memory
+		err := reloadShared(changeBannedWords)
+		if err != nil {
+			log.Fatalf("Failed to load banned words: %s", err.Error())
+		}
+
+		responseContent := fmt.Sprintf("Added words to ban list with severity %s: %s", severity, strings.Join(addedWords, ", "))
+		if days > 0 {
+			responseContent += fmt.Sprintf("\nThey will be removed again <t:%d:R>.", expiresAt.Unix())
+		}
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: responseContent,
+		})
+	} else {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "No new words were added to the ban list. Words another server banned stay as that server set them.",
+		})
+	}
+}
+
+func handleBanwordRemoveCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Member == nil || i.Member.Permissions&discordgo.PermissionManageServer == 0 {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "Error: Removing banned words requires the Manage Server permission.",
+		})
+		return
+	}
+
+	word := i.ApplicationCommandData().Options[0].Options[0].StringValue()
+	word = strings.TrimSpace(strings.ToLower(word))
+	if word == "" {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "No word provided to remove.",
+		})
+		return
+	}
+	removed, err := store.RemoveBannedWord(i.GuildID, word)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to remove word '%s' from ban list: %s", word, err.Error()),
+		})
+		return
+	}
+	if !removed {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("'%s' isn't on the ban list, or another server banned it.", word),
+		})
+		return
+	}
+
+	// Refresh the banned words in memory
+	err = reloadShared(changeBannedWords)
+	if err != nil {
+		log.Fatalf("Failed to load banned words: %s", err.Error())
+	}
+
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: fmt.Sprintf("Removed word from ban list: %s", word),
+	})
+}
+
+func handleBanwordListCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	bannedWords, err := store.BannedWords()
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to retrieve banned words: %s", err.Error()),
+		})
+		return
+	}
+
+	bySeverity := make(map[string][]string)
+	for _, word := range bannedWords {
+		severity := wordSeverity(word)
+		entry := word.Word
+		if !word.ExpiresAt.IsZero() {
+			entry += fmt.Sprintf(" (until <t:%d:d>)", word.ExpiresAt.Unix())
+		}
+		bySeverity[severity] = append(bySeverity[severity], entry)
+	}
+	lines := []string{"Banned words:"}
+	for _, severity := range severities {
+		if words := bySeverity[severity]; len(words) > 0 {
+			slices.Sort(words)
+			lines = append(lines, fmt.Sprintf("%s: %s", severity, strings.Join(words, ", ")))
+		}
+	}
+
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: strings.Join(lines, "\n"),
+	})
+}
+
+func handleBanwordPresetCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Member == nil || i.Member.Permissions&discordgo.PermissionManageServer == 0 {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "Error: Managing preset packs requires the Manage Server permission.",
+		})
+		return
+	}
+
+	subCommand := i.ApplicationCommandData().Options[0].Options[0]
+	packs := enabledPacks(i.GuildID)
+	if subCommand.Name == "list" {
+		var lines []string
+		for _, language := range filter.Packs() {
+			state := "disabled"
+			if slices.Contains(packs, language) {
+				state = "enabled"
+			}
+			lines = append(lines, fmt.Sprintf("%s: %d words, %s", language, len(filter.PackWords(language)), state))
+		}
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "Preset packs:\n" + strings.Join(lines, "\n"),
+		})
+		return
+	}
+
+	language := strings.ToLower(strings.TrimSpace(subCommand.Options[0].StringValue()))
+	if filter.PackWords(language) == nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Error: There is no preset pack for %q. Packs exist for: %s.", language, strings.Join(filter.Packs(), ", ")),
+		})
+		return
+	}
+
+	var responseContent string
+	switch subCommand.Name {
+	case "enable":
+		if slices.Contains(packs, language) {
+			respond(s, i, &discordgo.InteractionResponseData{
+				Content: fmt.Sprintf("The %s preset pack is already enabled.", language),
+			})
+			return
+		}
+		packs = append(packs, language)
+		responseContent = fmt.Sprintf("Enabled the %s preset pack. Messages with its words won't be translated.", language)
+	case "disable":
+		packs = slices.DeleteFunc(packs, func(pack string) bool { return pack == language })
+		responseContent = fmt.Sprintf("Disabled the %s preset pack.", language)
+	}
+
+	err := setGuildSetting(i.GuildID, settingBanwordPacks, strings.Join(packs, ","))
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to update preset packs: %s", err.Error()),
+		})
+		return
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
+
+func handleBanwordChannelCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Member == nil || i.Member.Permissions&discordgo.PermissionManageServer == 0 {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "Error: Changing a channel's banword filter requires the Manage Server permission.",
+		})
+		return
+	}
+
+	subCommand := i.ApplicationCommandData().Options[0].Options[0]
+	var channel *discordgo.Channel
+	var words []string
+	for _, option := range subCommand.Options {
+		switch option.Name {
+		case "channel":
+			channel = option.ChannelValue(s)
+		case "words":
+			for _, word := range strings.Split(option.StringValue(), ",") {
+				if word = strings.TrimSpace(strings.ToLower(word)); word != "" {
+					words = append(words, word)
+				}
+			}
+		}
+	}
+
+	value := ""
+	var responseContent string
+	switch {
+	case subCommand.Name == "reset":
+		responseContent = fmt.Sprintf("%s uses the server's banword filter again.", channel.Mention())
+	case len(words) == 0:
+		value = banwordExemptAll
+		responseContent = fmt.Sprintf("%s is now exempt from the banword filter.", channel.Mention())
+	default:
+		exempt := getChannelSetting(channel.ID, settingBanwordExempt)
+		if exempt != "" && exempt != banwordExemptAll {
+			for _, word := range strings.Split(exempt, ",") {
+				if !slices.Contains(words, word) {
+					words = append(words, word)
+				}
+			}
+		}
+		slices.Sort(words)
+		value = strings.Join(words, ",")
+		responseContent = fmt.Sprintf("%s is now exempt from these banned words: %s", channel.Mention(), strings.Join(words, ", "))
+	}
+
+	err := setChannelSetting(i.GuildID, channel.ID, settingBanwordExempt, value)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to update the channel's banword filter: %s", err.Error()),
+		})
+		return
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
+
+// testToxicity scores the phrase when the server has the toxicity check on.
+func testToxicity(serverID, phrase string) (percent, threshold int, ok bool) {
+	threshold, _ = strconv.Atoi(getGuildSetting(serverID, settingToxicity))
+	if threshold <= 0 || toxicityScorer == nil {
+		return 0, 0, false
+	}
+	percent, err := scoreToxicity(phrase)
+	if err != nil {
+		log.Printf("Error checking toxicity with %s, %s", toxicityScorer.Name(), err)
+		return 0, 0, false
+	}
+	return percent, threshold, true
+}
+
+func handleBanwordTestCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var phrase string
+	channelID := i.ChannelID
+	for _, option := range i.ApplicationCommandData().Options[0].Options {
+		switch option.Name {
+		case "phrase":
+			phrase = option.StringValue()
+		case "channel":
+			channelID = option.ChannelValue(s).ID
+		}
+	}
+
+	lines := []string{fmt.Sprintf("Matched as: `%s`", strings.Join(strings.Fields(filter.Normalize(phrase)), " "))}
+	for _, match := range matchBannedWords(i.GuildID, channelID, phrase) {
+		rule := "ban list"
+		if match.pack != "" {
+			rule = match.pack + " preset pack"
+		}
+		line := fmt.Sprintf("`%s` — %s, severity %s", match.word, rule, match.severity)
+		if match.exempt {
+			line += fmt.Sprintf(", but <#%s> is exempt", channelID)
+		}
+		lines = append(lines, line)
+	}
+	if filter.IsOnlyEmoji(phrase) {
+		lines = append(lines, "The phrase contains only emoji, which are never translated.")
+	}
+
+	var result string
+	switch severity, _ := bannedWordSeverity(i.GuildID, channelID, phrase); severity {
+	case "":
+		result = "would be translated"
+		if len(lines) == 1 {
+			lines = append(lines, "No banned words matched.")
+		}
+	case severityWarn:
+		result = "would be translated and moderators alerted"
+	case severityBlock:
+		result = "would be held back"
+	case severityEscalate:
+		result = "would be held back and deleted"
+	}
+	if percent, threshold, ok := testToxicity(i.GuildID, phrase); ok {
+		lines = append(lines, fmt.Sprintf("Toxicity: %d%%, held back at %d%%.", percent, threshold))
+		if percent >= threshold && !containsBannedWord(i.GuildID, channelID, phrase) {
+			result = "would be held back for toxicity"
+		}
+	}
+	lines = append(lines, fmt.Sprintf("Result in <#%s>: the message %s.", channelID, result))
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: strings.Join(lines, "\n"),
+	})
+}
@@ -0,0 +1,52 @@
+package bot
+
+import (
+	"slices"
+	"testing"
+
+	"translate-bot/storage"
+)
+
+func TestSeverityIn(t *testing.T) {
+	tests := []struct {
+		word     storage.BannedWord
+		serverID string
+		want     string
+	}{
+		{storage.BannedWord{Word: "a", Severity: severityEscalate, ServerID: "1"}, "1", severityEscalate},
+		// Other servers only have the word blocked.
+		{storage.BannedWord{Word: "a", Severity: severityEscalate, ServerID: "1"}, "2", severityBlock},
+		{storage.BannedWord{Word: "a", Severity: severityEscalate}, "1", severityBlock},
+		{storage.BannedWord{Word: "a", Severity: severityWarn, ServerID: "1"}, "2", severityWarn},
+		{storage.BannedWord{Word: "a"}, "1", severityBlock},
+	}
+	for _, test := range tests {
+		if got := severityIn(test.serverID, test.word); got != test.want {
+			t.Errorf("severityIn(%q, %+v) = %q, want %q", test.serverID, test.word, got, test.want)
+		}
+	}
+}
+
+func TestStrongestMatchLocal(t *testing.T) {
+	initTestStore(t)
+	oldWords := bannedWords
+	defer func() { bannedWords = oldWords }()
+	bannedWords = map[string]storage.BannedWord{
+		"spam": {Word: "spam", Severity: severityWarn, ServerID: "1"},
+		"scam": {Word: "scam", Severity: severityEscalate, ServerID: "2"},
+	}
+
+	matches := matchBannedWords("1", "channel", "spam and scam")
+	if severity, words := strongestMatch(matches, false); severity != severityBlock || !slices.Equal(words, []string{"spam", "scam"}) {
+		t.Errorf("strongestMatch() = %q, %q, want block for both words", severity, words)
+	}
+	// Only the server's own word counts towards warnings.
+	if severity, words := strongestMatch(matches, true); severity != severityWarn || !slices.Equal(words, []string{"spam"}) {
+		t.Errorf("strongestMatch() of local words = %q, %q, want warn for spam", severity, words)
+	}
+
+	matches = matchBannedWords("3", "channel", "spam and scam")
+	if severity, words := strongestMatch(matches, true); severity != "" || words != nil {
+		t.Errorf("strongestMatch() of local words in another server = %q, %q, want none", severity, words)
+	}
+}
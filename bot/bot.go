@@ -0,0 +1,1990 @@
+// Package bot implements the Discord side of the translation bot: command
+// and event handlers, message formatting and the per-guild features built on
+// top of the translation, filter and storage packages.
+package bot
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+
+	"translate-bot/filter"
+	"translate-bot/storage"
+	"translate-bot/translation"
+)
+
+var (
+	store             storage.Store
+	settings          *storage.Settings
+	bannedWords       map[string]storage.BannedWord
+	translateChannels map[string][3]string
+	// translateChannelGuilds indexes translateChannels by channel ID.
+	translateChannelGuilds map[string]string
+)
+
+// Init loads the configuration from the store.
+func Init(s storage.Store) error {
+	store = s
+
+	var err error
+	settings, err = storage.LoadSettings(store)
+	if err != nil {
+		return err
+	}
+	err = migrateGlobalPause()
+	if err != nil {
+		return err
+	}
+	err = loadBannedWords()
+	if err != nil {
+		return err
+	}
+	err = loadRoutes()
+	if err != nil {
+		return err
+	}
+	err = loadSubscriptions()
+	if err != nil {
+		return err
+	}
+	return loadTranslateChannels()
+}
+
+// Run connects to Discord with the token and handles events until the
+// process exits.
+func Run(token string) error {
+	err := initBackends()
+	if err != nil {
+		return err
+	}
+	err = selfTestBackends()
+	if err != nil {
+		return err
+	}
+
+	err = initRedis()
+	if err != nil {
+		return err
+	}
+	go reloadOnHangup()
+
+	dg, err := discordgo.New("Bot " + token)
+	if err != nil {
+		return fmt.Errorf("error creating Discord session: %w", err)
+	}
+	err = initSharding(dg)
+	if err != nil {
+		return err
+	}
+
+	dg.AddHandler(gatewayMessageCreate)
+	dg.AddHandler(messageUpdate)
+	dg.AddHandler(ready)
+	dg.AddHandler(interactionCreate)
+	// Member joins need the privileged Server Members intent, which the
+	// application must have enabled in the developer portal. Without it,
+	// joins are picked up from the system messages announcing them.
+	if os.Getenv("GUILD_MEMBERS_INTENT") != "" {
+		dg.Identify.Intents |= discordgo.IntentsGuildMembers
+		dg.AddHandler(guildMemberAdd)
+		dg.AddHandler(suggestTransliteration)
+	} else {
+		dg.AddHandler(memberJoinMessage)
+	}
+	dg.AddHandler(guildCreate)
+	dg.AddHandler(channelUpdate)
+	dg.AddHandler(channelDelete)
+	dg.AddHandler(threadUpdate)
+	dg.AddHandler(threadDelete)
+	dg.AddHandler(messageReactionAdd)
+	dg.AddHandler(messageReactionRemove)
+
+	err = dg.Open()
+	if err != nil {
+		return fmt.Errorf("error opening Discord session: %w", err)
+	}
+
+	registerCommands(dg)
+	if err := loadOwners(dg); err != nil {
+		log.Println(err)
+	}
+
+	updateQuietHours(dg)
+	go runScheduler(dg)
+	resumeArchiveJobs(dg)
+
+	// Pipeline and job metrics are published by expvar under /debug/vars,
+	// and along with banword hits and shard health in the Prometheus format
+	// under /metrics. Health is also reported as JSON under /health for this
+	// instance and /shards for all of them, and with OWNER_TOKEN set the
+	// owner can pause translation under /pause.
+	if addr := os.Getenv("METRICS_ADDR"); addr != "" {
+		go func() {
+			log.Println("Error serving metrics,", http.ListenAndServe(addr, nil))
+		}()
+	}
+
+	log.Println("Bot is running. Press CTRL+C to exit.")
+	select {}
+}
+
+// initBackends sets up the translation backends and the message hook from
+// the environment.
+func initBackends() error {
+	var err error
+	activeBackend, err = translation.NewFromEnv()
+	if err != nil {
+		return err
+	}
+	premiumBackend = translation.NewLLMFromEnv()
+	toxicityScorer = filter.NewToxicityScorerFromEnv()
+	return loadMessageHook()
+}
+
+func loadTranslateChannels() error {
+	channels, err := store.TranslateChannels()
+	if err != nil {
+		return err
+	}
+	guilds := make(map[string]string)
+	for serverID, channelIDs := range channels {
+		for _, channelID := range channelIDs {
+			if channelID != "" {
+				guilds[channelID] = serverID
+			}
+		}
+	}
+	translateChannels, translateChannelGuilds = channels, guilds
+	return nil
+}
+
+// commandDefinitions returns the application commands the bot provides.
+func commandDefinitions() []*discordgo.ApplicationCommand {
+	manageGuildPermission := int64(discordgo.PermissionManageServer)
+
+	return []*discordgo.ApplicationCommand{
+		{
+			Name:        "translate",
+			Description: "Manage translation",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "set",
+					Description: "Set the channels for translation (formerly /translate channel1…)",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:         "channel1",
+							Description:  "First channel to set for translation",
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							ChannelTypes: translatableChannelTypes,
+							Required:     false,
+						},
+						{
+							Name:         "channel2",
+							Description:  "Second channel to set for translation",
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							ChannelTypes: translatableChannelTypes,
+							Required:     false,
+						},
+						{
+							Name:         "channel3",
+							Description:  "Third channel to set for translation",
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							ChannelTypes: translatableChannelTypes,
+							Required:     false,
+						},
+					},
+				},
+				{
+					Name:        "remove",
+					Description: "Stop translating a channel",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:         "channel",
+							Description:  "Translated channel to remove",
+							Type:         discordgo.ApplicationCommandOptionString,
+							Required:     true,
+							Autocomplete: true,
+						},
+					},
+				},
+				{
+					Name:        "list",
+					Description: "List the translated channels",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+				},
+				{
+					Name:        "roles",
+					Description: "Only translate messages from members with one of these roles in a channel",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:         "channel",
+							Description:  "Channel to restrict",
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							ChannelTypes: translatableChannelTypes,
+							Required:     true,
+						},
+						{
+							Name:        "role1",
+							Description: "Role whose members are translated (leave all empty to translate everyone)",
+							Type:        discordgo.ApplicationCommandOptionRole,
+							Required:    false,
+						},
+						{
+							Name:        "role2",
+							Description: "Another role whose members are translated",
+							Type:        discordgo.ApplicationCommandOptionRole,
+							Required:    false,
+						},
+						{
+							Name:        "role3",
+							Description: "Another role whose members are translated",
+							Type:        discordgo.ApplicationCommandOptionRole,
+							Required:    false,
+						},
+					},
+				},
+				{
+					Name:        "topic",
+					Description: "Translate this channel's topic",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "language",
+							Description: "Language code to translate into, e.g. es",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    true,
+						},
+						{
+							Name:        "append",
+							Description: "Append the translation to the channel topic",
+							Type:        discordgo.ApplicationCommandOptionBoolean,
+							Required:    false,
+						},
+					},
+				},
+				{
+					Name:        "pins",
+					Description: "Translate this channel's pinned messages",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "language",
+							Description: "Language code to translate into (defaults to the server's language)",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    false,
+						},
+					},
+				},
+				{
+					Name:        "pair",
+					Description: "Mirror two channels into each other, each in its own language",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:         "channel_a",
+							Description:  "First channel of the pair",
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							ChannelTypes: postableChannelTypes,
+							Required:     true,
+						},
+						{
+							Name:         "channel_b",
+							Description:  "Second channel of the pair",
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							ChannelTypes: postableChannelTypes,
+							Required:     true,
+						},
+						{
+							Name:        "language_a",
+							Description: "Language code of the first channel, e.g. en",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    true,
+						},
+						{
+							Name:        "language_b",
+							Description: "Language code of the second channel, e.g. es",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    true,
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:                     "route",
+			Description:              "Translate messages from one channel into another",
+			DefaultMemberPermissions: &manageGuildPermission,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "add",
+					Description: "Post translations of a channel's messages to another channel",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:         "from",
+							Description:  "Channel whose messages are translated",
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							ChannelTypes: translatableChannelTypes,
+							Required:     true,
+						},
+						{
+							Name:         "to",
+							Description:  "Channel the translations are posted to",
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							ChannelTypes: postableChannelTypes,
+							Required:     true,
+						},
+						{
+							Name:        "language",
+							Description: "Language code to translate into, e.g. en",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    true,
+						},
+						{
+							Name:        "author",
+							Description: "How the author is shown (defaults to their name)",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    false,
+							Choices: []*discordgo.ApplicationCommandOptionChoice{
+								{Name: "Name", Value: routeAuthorName},
+								{Name: "Anonymous", Value: routeAuthorAnonymous},
+								{Name: "Webhook with their name and avatar", Value: routeAuthorWebhook},
+							},
+						},
+					},
+				},
+				{
+					Name:        "remove",
+					Description: "Stop translating from one channel into another",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:         "from",
+							Description:  "Source channel of the route",
+							Type:         discordgo.ApplicationCommandOptionString,
+							Required:     true,
+							Autocomplete: true,
+						},
+						{
+							Name:         "to",
+							Description:  "Destination channel of the route",
+							Type:         discordgo.ApplicationCommandOptionString,
+							Required:     true,
+							Autocomplete: true,
+						},
+					},
+				},
+				{
+					Name:        "list",
+					Description: "List this server's routes",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+				},
+				{
+					Name:        "hub",
+					Description: "Fan a channel out into per-language channels, posted as each author",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:         "hub",
+							Description:  "Channel whose messages are translated",
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							ChannelTypes: translatableChannelTypes,
+							Required:     true,
+						},
+						{
+							Name:         "channel1",
+							Description:  "Language channel 1",
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							ChannelTypes: postableChannelTypes,
+							Required:     true,
+						},
+						{
+							Name:        "language1",
+							Description: "Language code of channel 1, e.g. fr",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    true,
+						},
+						{
+							Name:         "channel2",
+							Description:  "Language channel 2",
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							ChannelTypes: postableChannelTypes,
+							Required:     false,
+						},
+						{
+							Name:        "language2",
+							Description: "Language code of channel 2, e.g. fr",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    false,
+						},
+						{
+							Name:         "channel3",
+							Description:  "Language channel 3",
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							ChannelTypes: postableChannelTypes,
+							Required:     false,
+						},
+						{
+							Name:        "language3",
+							Description: "Language code of channel 3, e.g. fr",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    false,
+						},
+						{
+							Name:         "channel4",
+							Description:  "Language channel 4",
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							ChannelTypes: postableChannelTypes,
+							Required:     false,
+						},
+						{
+							Name:        "language4",
+							Description: "Language code of channel 4, e.g. fr",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    false,
+						},
+						{
+							Name:         "channel5",
+							Description:  "Language channel 5",
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							ChannelTypes: postableChannelTypes,
+							Required:     false,
+						},
+						{
+							Name:        "language5",
+							Description: "Language code of channel 5, e.g. fr",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    false,
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:                     "archive",
+			Description:              "Translate a channel's history into a thread or file",
+			DefaultMemberPermissions: &manageGuildPermission,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "start",
+					Description: "Start translating a channel's history",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:         "channel",
+							Description:  "Channel whose history is translated",
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							ChannelTypes: translatableChannelTypes,
+							Required:     true,
+						},
+						{
+							Name:        "messages",
+							Description: "Number of messages to translate, the most recent unless since is given",
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Required:    false,
+						},
+						{
+							Name:        "since",
+							Description: "First day to translate, e.g. 2024-01-31",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    false,
+						},
+						{
+							Name:        "until",
+							Description: "Last day to translate, e.g. 2024-02-29",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    false,
+						},
+						{
+							Name:        "language",
+							Description: "Language code to translate into (defaults to the server's language)",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    false,
+						},
+						{
+							Name:        "output",
+							Description: "Where to put the translations (defaults to a thread)",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    false,
+							Choices: []*discordgo.ApplicationCommandOptionChoice{
+								{Name: "Thread in this channel", Value: archiveThread},
+								{Name: "Text file posted here", Value: archiveFile},
+							},
+						},
+					},
+				},
+				{
+					Name:        "status",
+					Description: "Show the progress of the archive job",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+				},
+				{
+					Name:        "resume",
+					Description: "Continue a paused archive job",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+				},
+				{
+					Name:        "cancel",
+					Description: "Stop the archive job",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+				},
+			},
+		},
+		{
+			Name:                     "skipped",
+			Description:              "Show recent messages that weren't translated and why",
+			DefaultMemberPermissions: &manageGuildPermission,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "channel",
+					Description: "Only show messages of this channel",
+					Type:        discordgo.ApplicationCommandOptionChannel,
+					Required:    false,
+				},
+			},
+		},
+		{
+			Name:        "subscribe",
+			Description: "Get a channel's messages translated into your language by DM",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:         "channel",
+					Description:  "Channel to subscribe to",
+					Type:         discordgo.ApplicationCommandOptionChannel,
+					ChannelTypes: translatableChannelTypes,
+					Required:     true,
+				},
+				{
+					Name:        "language",
+					Description: "Language code to translate into (defaults to your Discord language)",
+					Type:        discordgo.ApplicationCommandOptionString,
+					Required:    false,
+				},
+				{
+					Name:        "frequency",
+					Description: "How often to send translations (defaults to a daily digest)",
+					Type:        discordgo.ApplicationCommandOptionString,
+					Required:    false,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "Live", Value: frequencyLive},
+						{Name: "Hourly", Value: frequencyHourly},
+						{Name: "Daily", Value: frequencyDaily},
+					},
+				},
+			},
+		},
+		{
+			Name:        "unsubscribe",
+			Description: "Stop getting a channel's messages by DM",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "channel",
+					Description: "Channel to unsubscribe from",
+					Type:        discordgo.ApplicationCommandOptionChannel,
+					Required:    true,
+				},
+			},
+		},
+		{
+			Name:        "banword",
+			Description: "Manage banned words",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "add",
+					Description: "Add words to the ban list (comma separated)",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "words",
+							Description: "Words to add",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    true,
+						},
+						{
+							Name:        "severity",
+							Description: "What happens to messages with the words (defaults to block)",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    false,
+							Choices: []*discordgo.ApplicationCommandOptionChoice{
+								{Name: "Warn: translate and alert moderators", Value: severityWarn},
+								{Name: "Block: don't translate", Value: severityBlock},
+								{Name: "Escalate: don't translate and delete the message", Value: severityEscalate},
+							},
+						},
+						{
+							Name:        "days",
+							Description: "Remove the words from the ban list again after this many days",
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Required:    false,
+						},
+					},
+				},
+				{
+					Name:        "remove",
+					Description: "Remove a word from the ban list",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:         "word",
+							Description:  "Word to remove",
+							Type:         discordgo.ApplicationCommandOptionString,
+							Required:     true,
+							Autocomplete: true,
+						},
+					},
+				},
+				{
+					Name:        "list",
+					Description: "List all banned words",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+				},
+				{
+					Name:        "test",
+					Description: "Show which banword rules a phrase would match",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "phrase",
+							Description: "Phrase to run through the filter",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    true,
+						},
+						{
+							Name:         "channel",
+							Description:  "Channel whose exceptions apply (defaults to this one)",
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							ChannelTypes: translatableChannelTypes,
+							Required:     false,
+						},
+					},
+				},
+				{
+					Name:        "preset",
+					Description: "Manage the server's preset packs of profanity",
+					Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "enable",
+							Description: "Ban the words of a language's preset pack in this server",
+							Type:        discordgo.ApplicationCommandOptionSubCommand,
+							Options: []*discordgo.ApplicationCommandOption{
+								{
+									Name:        "lang",
+									Description: "Language code of the pack, e.g. es",
+									Type:        discordgo.ApplicationCommandOptionString,
+									Required:    true,
+								},
+							},
+						},
+						{
+							Name:        "disable",
+							Description: "Stop banning the words of a language's preset pack",
+							Type:        discordgo.ApplicationCommandOptionSubCommand,
+							Options: []*discordgo.ApplicationCommandOption{
+								{
+									Name:        "lang",
+									Description: "Language code of the pack, e.g. es",
+									Type:        discordgo.ApplicationCommandOptionString,
+									Required:    true,
+								},
+							},
+						},
+						{
+							Name:        "list",
+							Description: "List the preset packs and whether they are enabled",
+							Type:        discordgo.ApplicationCommandOptionSubCommand,
+						},
+					},
+				},
+				{
+					Name:        "channel",
+					Description: "Manage a channel's exceptions to the banword filter",
+					Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "exempt",
+							Description: "Exempt a channel from banned words, or from the whole filter without words",
+							Type:        discordgo.ApplicationCommandOptionSubCommand,
+							Options: []*discordgo.ApplicationCommandOption{
+								{
+									Name:         "channel",
+									Description:  "Channel to exempt",
+									Type:         discordgo.ApplicationCommandOptionChannel,
+									ChannelTypes: translatableChannelTypes,
+									Required:     true,
+								},
+								{
+									Name:        "words",
+									Description: "Banned words the channel may use (comma separated)",
+									Type:        discordgo.ApplicationCommandOptionString,
+									Required:    false,
+								},
+							},
+						},
+						{
+							Name:        "reset",
+							Description: "Apply the server's banword filter to a channel again",
+							Type:        discordgo.ApplicationCommandOptionSubCommand,
+							Options: []*discordgo.ApplicationCommandOption{
+								{
+									Name:         "channel",
+									Description:  "Channel to reset",
+									Type:         discordgo.ApplicationCommandOptionChannel,
+									ChannelTypes: translatableChannelTypes,
+									Required:     true,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:                     "moderation",
+			Description:              "Manage how messages are held back for moderation",
+			DefaultMemberPermissions: &manageGuildPermission,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "alerts",
+					Description: "Alert moderators of held back messages (no options turns alerts off)",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "role",
+							Description: "Moderator role to ping",
+							Type:        discordgo.ApplicationCommandOptionRole,
+							Required:    false,
+						},
+						{
+							Name:         "channel",
+							Description:  "Channel to post the redacted message to (defaults to the log channel)",
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							ChannelTypes: postableChannelTypes,
+							Required:     false,
+						},
+					},
+				},
+				{
+					Name:        "toxicity",
+					Description: "Hold back messages that score too high for toxicity",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "threshold",
+							Description: "Toxicity score in percent to hold back messages at (0 turns the check off)",
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Required:    true,
+						},
+					},
+				},
+				{
+					Name:        "timeout",
+					Description: "Time out members who keep hitting the filters",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "warnings",
+							Description: "Warnings within 24 hours that lead to a timeout (0 turns timeouts off)",
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Required:    true,
+						},
+						{
+							Name:        "minutes",
+							Description: "How long the timeout lasts (defaults to 60)",
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Required:    false,
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:                     "warnings",
+			Description:              "Show a member's banword and toxicity warnings",
+			DefaultMemberPermissions: &manageGuildPermission,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "user",
+					Description: "Member to show the warnings of",
+					Type:        discordgo.ApplicationCommandOptionUser,
+					Required:    true,
+				},
+				{
+					Name:        "clear",
+					Description: "Remove the member's warnings instead",
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Required:    false,
+				},
+			},
+		},
+		{
+			Name:                     "backend",
+			Description:              "Inspect and tune the translation backends",
+			DefaultMemberPermissions: &manageGuildPermission,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "status",
+					Description: "Show the backend's health, error rate, latency, quota and fallback chain",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+				},
+				{
+					Name:        "race",
+					Description: "Send a channel's messages to two backends at once and post the faster translation",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:         "channel",
+							Description:  "Channel to race backends in",
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							ChannelTypes: translatableChannelTypes,
+							Required:     true,
+						},
+						{
+							Name:        "enabled",
+							Description: "Whether to race backends in the channel",
+							Type:        discordgo.ApplicationCommandOptionBoolean,
+							Required:    true,
+						},
+					},
+				},
+				{
+					Name:        "llm",
+					Description: "Translate with the LLM backend instead of the bot-wide one (premium)",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "enabled",
+							Description: "Whether to translate with the LLM backend",
+							Type:        discordgo.ApplicationCommandOptionBoolean,
+							Required:    true,
+						},
+					},
+				},
+				{
+					Name:        "stream",
+					Description: "Show long translations while the backend writes them",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "enabled",
+							Description: "Whether to stream translations",
+							Type:        discordgo.ApplicationCommandOptionBoolean,
+							Required:    true,
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:                     "owner",
+			Description:              "Pause translation in an emergency (bot owner only)",
+			DefaultMemberPermissions: &manageGuildPermission,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "pause",
+					Description: "Stop translating right away, in one server or all of them",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "guild",
+							Description: "ID of the server to pause (leave empty for all servers)",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    false,
+						},
+						{
+							Name:        "reason",
+							Description: "Why translation is paused",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    false,
+						},
+					},
+				},
+				{
+					Name:        "resume",
+					Description: "Translate again in one server or all of them",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "guild",
+							Description: "ID of the server to resume (leave empty for all servers)",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    false,
+						},
+					},
+				},
+				{
+					Name:        "status",
+					Description: "List where translation is paused",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+				},
+			},
+		},
+		{
+			Name:                     "config",
+			Description:              "Manage server translation settings",
+			DefaultMemberPermissions: &manageGuildPermission,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "translations",
+					Description: "Post all translations to one channel (leave empty to disable)",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:         "channel",
+							Description:  "Channel that receives all translations",
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							ChannelTypes: postableChannelTypes,
+							Required:     false,
+						},
+					},
+				},
+				{
+					Name:        "template",
+					Description: "Set the translation message format (leave empty to reset)",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "text",
+							Description: "Placeholders: {author} {channel} {source_lang} {target_lang} {original} {translation} {jump_url}",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    false,
+						},
+					},
+				},
+				{
+					Name:        "style",
+					Description: "Set how translations from a channel are posted",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:         "channel",
+							Description:  "Translate channel to configure",
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							ChannelTypes: translatableChannelTypes,
+							Required:     true,
+						},
+						{
+							Name:        "mode",
+							Description: "Output style",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    true,
+							Choices: []*discordgo.ApplicationCommandOptionChoice{
+								{Name: "Default (server template)", Value: styleDefault},
+								{Name: "Minimal (translation only)", Value: styleMinimal},
+								{Name: "Spoiler (original hidden below)", Value: styleSpoiler},
+								{Name: "Compact (flags and author on one line)", Value: styleCompact},
+							},
+						},
+					},
+				},
+				{
+					Name:        "jumplinks",
+					Description: "Add a link back to the original message to every translation",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "enabled",
+							Description: "Whether to add jump links",
+							Type:        discordgo.ApplicationCommandOptionBoolean,
+							Required:    true,
+						},
+					},
+				},
+				{
+					Name:        "logchannel",
+					Description: "Set the channel for admin notifications (leave empty to use the system channel)",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:         "channel",
+							Description:  "Channel that receives admin notifications",
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							ChannelTypes: postableChannelTypes,
+							Required:     false,
+						},
+					},
+				},
+				{
+					Name:        "quota",
+					Description: "Limit how many characters are translated per day and month (0 for no limit)",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "daily",
+							Description: "Maximum characters per day",
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Required:    false,
+						},
+						{
+							Name:        "monthly",
+							Description: "Maximum characters per month",
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Required:    false,
+						},
+					},
+				},
+				{
+					Name:        "digest",
+					Description: "Post a weekly activity summary to a channel (leave empty to disable)",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:         "channel",
+							Description:  "Channel that receives the weekly digest",
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							ChannelTypes: postableChannelTypes,
+							Required:     false,
+						},
+					},
+				},
+				{
+					Name:        "announce",
+					Description: "Translate and publish posts in an announcement channel",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:         "channel",
+							Description:  "Announcement channel",
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildNews},
+							Required:     true,
+						},
+						{
+							Name:        "languages",
+							Description: "Comma separated language codes, e.g. es,fr (leave empty to disable)",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    false,
+						},
+					},
+				},
+				{
+					Name:        "forumrename",
+					Description: "Rename forum posts to include their translated title",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "enabled",
+							Description: "Rename posts instead of adding the title to the first reply",
+							Type:        discordgo.ApplicationCommandOptionBoolean,
+							Required:    true,
+						},
+					},
+				},
+				{
+					Name:        "nicksuggest",
+					Description: "Suggest Latin transliterations of non-Latin names when members join",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "enabled",
+							Description: "Whether to post suggestions to the log channel",
+							Type:        discordgo.ApplicationCommandOptionBoolean,
+							Required:    true,
+						},
+					},
+				},
+				{
+					Name:        "backtranslate",
+					Description: "Flag translations that don't survive a round trip back to the source language",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "enabled",
+							Description: "Whether to check translations (uses extra quota)",
+							Type:        discordgo.ApplicationCommandOptionBoolean,
+							Required:    true,
+						},
+					},
+				},
+				{
+					Name:        "confidence",
+					Description: "Show an estimated confidence score under each translation",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "enabled",
+							Description: "Whether to show confidence scores",
+							Type:        discordgo.ApplicationCommandOptionBoolean,
+							Required:    true,
+						},
+					},
+				},
+				{
+					Name:        "formality",
+					Description: "Set the tone of translations in a channel (DeepL and LLM backends)",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:         "channel",
+							Description:  "Channel to configure",
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							ChannelTypes: translatableChannelTypes,
+							Required:     true,
+						},
+						{
+							Name:        "tone",
+							Description: "Formality of the translations",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    true,
+							Choices: []*discordgo.ApplicationCommandOptionChoice{
+								{Name: "Default", Value: "default"},
+								{Name: "Formal", Value: translation.FormalityFormal},
+								{Name: "Informal", Value: translation.FormalityInformal},
+							},
+						},
+					},
+				},
+				{
+					Name:        "profanity",
+					Description: "Choose what happens when a translation contains profanity",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "mode",
+							Description: "How to handle profanity produced by the translator",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    true,
+							Choices: []*discordgo.ApplicationCommandOptionChoice{
+								{Name: "Keep (post as translated)", Value: profanityKeep},
+								{Name: "Mask with asterisks", Value: profanityMask},
+								{Name: "Drop the translation", Value: profanityDrop},
+							},
+						},
+					},
+				},
+				{
+					Name:        "preset",
+					Description: "Tune LLM translations for the server's jargon",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "style",
+							Description: "Style preset for the LLM backend",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    true,
+							Choices: []*discordgo.ApplicationCommandOptionChoice{
+								{Name: "None", Value: "none"},
+								{Name: "Gaming slang", Value: "gaming"},
+								{Name: "Formal business", Value: "business"},
+								{Name: "Anime fandom", Value: "anime"},
+							},
+						},
+					},
+				},
+				{
+					Name:        "context",
+					Description: "Send recent channel messages to the LLM backend as context",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "messages",
+							Description: "Number of earlier messages to include (0 disables)",
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Required:    true,
+						},
+						{
+							Name:        "redaction",
+							Description: "What to remove from context messages (defaults to strict)",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    false,
+							Choices: []*discordgo.ApplicationCommandOptionChoice{
+								{Name: "Strict (names, mentions, links, emails, numbers)", Value: redactionStrict},
+								{Name: "Basic (mentions and emails)", Value: redactionBasic},
+							},
+						},
+					},
+				},
+				{
+					Name:        "dryrun",
+					Description: "Report what would be translated instead of posting translations",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "enabled",
+							Description: "Whether to run in dry-run mode",
+							Type:        discordgo.ApplicationCommandOptionBoolean,
+							Required:    true,
+						},
+					},
+				},
+				{
+					Name:        "webhook",
+					Description: "Post events as JSON to a webhook (leave the URL empty to stop)",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "url",
+							Description: "https:// URL that receives the events",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    false,
+						},
+						{
+							Name:        "events",
+							Description: "Comma separated: translation, banword, backend_error, quota (defaults to all)",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    false,
+						},
+					},
+				},
+				{
+					Name:        "editwindow",
+					Description: "Update translations of messages edited soon after posting",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "minutes",
+							Description: "How long after posting edits are translated (0 disables)",
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Required:    true,
+						},
+					},
+				},
+				{
+					Name:        "catchup",
+					Description: "Translate messages missed while the bot was offline when it reconnects",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "enabled",
+							Description: "Whether to catch up on missed messages",
+							Type:        discordgo.ApplicationCommandOptionBoolean,
+							Required:    true,
+						},
+					},
+				},
+				{
+					Name:        "backendqueue",
+					Description: "Hold messages while the translation backend is down and translate them later",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "enabled",
+							Description: "Whether to queue messages until the backend recovers",
+							Type:        discordgo.ApplicationCommandOptionBoolean,
+							Required:    true,
+						},
+					},
+				},
+				{
+					Name:        "quiethours",
+					Description: "Hold back translations at set times every day (leave empty to disable)",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "start",
+							Description: "When quiet hours start, e.g. 22:00",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    false,
+						},
+						{
+							Name:        "end",
+							Description: "When quiet hours end, e.g. 07:00",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    false,
+						},
+						{
+							Name:         "channel",
+							Description:  "Channel that receives translations during quiet hours (leave empty to skip them)",
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							ChannelTypes: postableChannelTypes,
+							Required:     false,
+						},
+					},
+				},
+				{
+					Name:        "timezone",
+					Description: "Set the timezone for quiet hours, quotas, digests and statistics (leave empty for UTC)",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:         "zone",
+							Description:  "IANA timezone name, e.g. Europe/Berlin",
+							Type:         discordgo.ApplicationCommandOptionString,
+							Required:     false,
+							Autocomplete: true,
+						},
+					},
+				},
+				{
+					Name:        "skiplog",
+					Description: "Log why messages weren't translated",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "mode",
+							Description: "Where to log skipped messages",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    true,
+							Choices: []*discordgo.ApplicationCommandOptionChoice{
+								{Name: "Off", Value: "off"},
+								{Name: "Log channel", Value: skipLogChannel},
+								{Name: "Database, shown with /skipped", Value: skipLogDatabase},
+							},
+						},
+					},
+				},
+				{
+					Name:        "anonymize",
+					Description: "Hide author names on translations mirrored to another channel",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "enabled",
+							Description: "Whether to post mirrored translations as \"Member\"",
+							Type:        discordgo.ApplicationCommandOptionBoolean,
+							Required:    true,
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:                     "license",
+			Description:              "Manage this server's license for premium features",
+			DefaultMemberPermissions: &manageGuildPermission,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "activate",
+					Description: "Activate a license key",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "key",
+							Description: "License key issued by the bot host",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    true,
+						},
+					},
+				},
+				{
+					Name:        "status",
+					Description: "Show whether premium features are available",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+				},
+			},
+		},
+		{
+			Name:        "stats",
+			Description: "Show translation statistics for this server",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "leaderboard",
+					Description: "Show the members whose messages are translated most",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+				},
+				{
+					Name:        "languages",
+					Description: "Show which language pairs are translated most",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+				},
+				{
+					Name:        "banwords",
+					Description: "Show which banned words match most, and which never do",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+				},
+			},
+		},
+		{
+			Name:                     "apikey",
+			Description:              "Manage this server's own translation API key",
+			DefaultMemberPermissions: &manageGuildPermission,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "set",
+					Description: "Use your own DeepL or Google API key for this server",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "provider",
+							Description: "Translation provider the key belongs to",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    true,
+							Choices: []*discordgo.ApplicationCommandOptionChoice{
+								{Name: "DeepL", Value: "deepl"},
+								{Name: "Google", Value: "google"},
+							},
+						},
+						{
+							Name:        "key",
+							Description: "API key",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    true,
+						},
+					},
+				},
+				{
+					Name:        "clear",
+					Description: "Remove this server's API key and use the bot's default backend",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+				},
+				{
+					Name:        "status",
+					Description: "Show which API key this server uses",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+				},
+			},
+		},
+		{
+			Name:        "cost",
+			Description: "Estimate translation API costs",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "estimate",
+					Description: "Project monthly API spend from this server's recent usage",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+				},
+			},
+		},
+		{
+			Name:                     "voice",
+			Description:              "Post translated captions for a voice or stage channel",
+			DefaultMemberPermissions: &manageGuildPermission,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "join",
+					Description: "Join a voice channel and start posting translated captions",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:         "channel",
+							Description:  "Voice or stage channel to caption",
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildVoice, discordgo.ChannelTypeGuildStageVoice},
+							Required:     true,
+						},
+						{
+							Name:         "captions",
+							Description:  "Text channel for captions (defaults to this channel, or the stage's chat)",
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText},
+							Required:     false,
+						},
+					},
+				},
+				{
+					Name:        "leave",
+					Description: "Stop captions and leave the voice channel",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+				},
+			},
+		},
+		{
+			Name:                     "welcome",
+			Description:              "Welcome new members in their own language",
+			DefaultMemberPermissions: &manageGuildPermission,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "set",
+					Description: "Set the welcome message and channel",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:         "channel",
+							Description:  "Channel to post welcome messages in",
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							ChannelTypes: postableChannelTypes,
+							Required:     true,
+						},
+						{
+							Name:        "message",
+							Description: "Welcome message, translated into each member's language",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    true,
+						},
+					},
+				},
+				{
+					Name:        "clear",
+					Description: "Stop welcoming new members",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+				},
+			},
+		},
+		{
+			Name:                     "rules",
+			Description:              "Publish the server rules in several languages",
+			DefaultMemberPermissions: &manageGuildPermission,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "set",
+					Description: "Save the rules text",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "text",
+							Description: "Rules text (use \\n for line breaks)",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    true,
+						},
+					},
+				},
+				{
+					Name:        "publish",
+					Description: "Post the rules translated into each language, replacing the last published set",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "languages",
+							Description: "Comma separated language codes, e.g. es,fr,de (defaults to the last set)",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    false,
+						},
+						{
+							Name:         "channel",
+							Description:  "Channel to publish in (defaults to this channel)",
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText},
+							Required:     false,
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:        "help",
+			Description: "List the bot's commands and this server's settings",
+		},
+		{
+			Name:                     "checkperms",
+			Description:              "Check that the bot has the permissions it needs in every configured channel",
+			DefaultMemberPermissions: &manageGuildPermission,
+		},
+		{
+			Name:                     "setup",
+			Description:              "Set up translation step by step",
+			DefaultMemberPermissions: &manageGuildPermission,
+		},
+		{
+			Name:        "detect",
+			Description: "Detect the language of some text without translating it",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "text",
+					Description: "Text to detect",
+					Type:        discordgo.ApplicationCommandOptionString,
+					Required:    true,
+				},
+			},
+		},
+		{
+			Name:        "define",
+			Description: "Look up dictionary senses and synonyms of a word",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "word",
+					Description: "Word to look up",
+					Type:        discordgo.ApplicationCommandOptionString,
+					Required:    true,
+				},
+				{
+					Name:        "from",
+					Description: "Language of the word, e.g. de (detected when omitted)",
+					Type:        discordgo.ApplicationCommandOptionString,
+					Required:    false,
+				},
+				{
+					Name:        "to",
+					Description: "Language to explain the word in (defaults to the server language)",
+					Type:        discordgo.ApplicationCommandOptionString,
+					Required:    false,
+				},
+			},
+		},
+		{
+			Name: "Transliterate name",
+			Type: discordgo.UserApplicationCommand,
+		},
+		{
+			Name: "Detect language",
+			Type: discordgo.MessageApplicationCommand,
+		},
+	}
+}
+
+func registerCommands(s *discordgo.Session) {
+	for _, command := range commandDefinitions() {
+		_, err := s.ApplicationCommandCreate(s.State.User.ID, "", command)
+		if err != nil {
+			log.Fatalf("Cannot create slash command: %v", err)
+		}
+	}
+}
+
+// ephemeralCommands lists the commands whose responses only the invoking user
+// sees. Subcommands are listed by their full name, such as "banword test".
+var ephemeralCommands = map[string]bool{
+	"apikey":             true,
+	"setup":              true,
+	"help":               true,
+	"skipped":            true,
+	"warnings":           true,
+	"banword test":       true,
+	"backend status":     true,
+	"owner pause":        true,
+	"owner resume":       true,
+	"owner status":       true,
+	"subscribe":          true,
+	"unsubscribe":        true,
+	"detect":             true,
+	"Transliterate name": true,
+	"Detect language":    true,
+}
+
+// subCommandName returns the full name of the invoked subcommand, or an empty
+// string when the command has none.
+func subCommandName(i *discordgo.InteractionCreate) string {
+	data := i.ApplicationCommandData()
+	if len(data.Options) == 0 || data.Options[0].Type != discordgo.ApplicationCommandOptionSubCommand {
+		return ""
+	}
+	return data.Name + " " + data.Options[0].Name
+}
+
+func interactionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Member != nil {
+		if err := recordUserLocale(i.Member.User.ID, i.Locale); err != nil {
+			log.Println("Error recording user locale,", err)
+		}
+	}
+	if (i.Type == discordgo.InteractionApplicationCommand || i.Type == discordgo.InteractionMessageComponent) && !allowInteraction(s, i) {
+		return
+	}
+	if i.Type == discordgo.InteractionApplicationCommandAutocomplete {
+		handleAutocomplete(s, i)
+		return
+	}
+	if i.Type == discordgo.InteractionMessageComponent {
+		if strings.HasPrefix(i.MessageComponentData().CustomID, "setup:") {
+			handleSetupComponent(s, i)
+		}
+		return
+	}
+	if i.Type != discordgo.InteractionApplicationCommand {
+		return
+	}
+
+	// Handlers query the database and the translation backend, which can
+	// take longer than the three seconds Discord allows for a response.
+	// Deferring first gives them up to 15 minutes to call respond.
+	name := i.ApplicationCommandData().Name
+	var flags discordgo.MessageFlags
+	if ephemeralCommands[name] || ephemeralCommands[subCommandName(i)] {
+		flags = discordgo.MessageFlagsEphemeral
+	}
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: flags,
+		},
+	})
+	if err != nil {
+		log.Println("Error deferring interaction response,", err)
+		return
+	}
+
+	switch name {
+	case "translate":
+		handleTranslateCommand(s, i)
+	case "banword":
+		handleBanwordCommand(s, i)
+	case "moderation":
+		handleModerationCommand(s, i)
+	case "warnings":
+		handleWarningsCommand(s, i)
+	case "archive":
+		handleArchiveCommand(s, i)
+	case "skipped":
+		handleSkippedCommand(s, i)
+	case "subscribe":
+		handleSubscribeCommand(s, i)
+	case "unsubscribe":
+		handleUnsubscribeCommand(s, i)
+	case "route":
+		handleRouteCommand(s, i)
+	case "backend":
+		handleBackendCommand(s, i)
+	case "owner":
+		handleOwnerCommand(s, i)
+	case "config":
+		handleConfigCommand(s, i)
+	case "license":
+		handleLicenseCommand(s, i)
+	case "stats":
+		handleStatsCommand(s, i)
+	case "apikey":
+		handleAPIKeyCommand(s, i)
+	case "cost":
+		handleCostCommand(s, i)
+	case "voice":
+		handleVoiceCommand(s, i)
+	case "welcome":
+		handleWelcomeCommand(s, i)
+	case "rules":
+		handleRulesCommand(s, i)
+	case "detect":
+		handleDetectCommand(s, i)
+	case "setup":
+		handleSetupCommand(s, i)
+	case "help":
+		handleHelpCommand(s, i)
+	case "checkperms":
+		handleCheckPermsCommand(s, i)
+	case "define":
+		handleDefineCommand(s, i)
+	case "Transliterate name":
+		handleTransliterateNameCommand(s, i)
+	case "Detect language":
+		handleDetectMessageCommand(s, i)
+	}
+}
+
+// respond completes the deferred response to a command interaction.
+// Responses can carry translated text, so they don't ping @everyone, @here
+// or roles unless the handler allows it.
+func respond(s *discordgo.Session, i *discordgo.InteractionCreate, data *discordgo.InteractionResponseData) {
+	allowedMentions := data.AllowedMentions
+	if allowedMentions == nil {
+		allowedMentions = translationMentions
+	}
+	edit := &discordgo.WebhookEdit{
+		Content:         &data.Content,
+		AllowedMentions: allowedMentions,
+	}
+	if len(data.Embeds) > 0 {
+		edit.Embeds = &data.Embeds
+	}
+	if len(data.Components) > 0 {
+		edit.Components = &data.Components
+	}
+
+	_, err := s.InteractionResponseEdit(i.Interaction, edit)
+	if err != nil {
+		log.Println("Error responding to interaction,", err)
+	}
+}
+
+func handleTranslateCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	subCommand := i.ApplicationCommandData().Options[0].Name
+
+	switch subCommand {
+	case "set":
+		handleTranslateSetCommand(s, i)
+	case "remove":
+		handleTranslateRemoveCommand(s, i)
+	case "list":
+		handleTranslateListCommand(s, i)
+	case "roles":
+		handleTranslateRolesCommand(s, i)
+	case "topic":
+		handleTranslateTopicCommand(s, i)
+	case "pair":
+		handleTranslatePairCommand(s, i)
+	case "pins":
+		handleTranslatePinsCommand(s, i)
+	}
+}
+
+func handleTranslateSetCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	options := i.ApplicationCommandData().Options[0].Options
+	var channel1, channel2, channel3 *discordgo.Channel
+	for _, option := range options {
+		if option.Name == "channel1" {
+			channel1 = option.ChannelValue(s)
+		} else if option.Name == "channel2" {
+			channel2 = option.ChannelValue(s)
+		} else if option.Name == "channel3" {
+			channel3 = option.ChannelValue(s)
+		}
+	}
+
+	if channel1 == nil && channel2 == nil && channel3 == nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "Error: At least one channel must be provided.",
+		})
+		return
+	}
+	for _, channel := range []*discordgo.Channel{channel1, channel2, channel3} {
+		if channel != nil && !isTranslatableChannel(channel) {
+			respond(s, i, &discordgo.InteractionResponseData{
+				Content: fmt.Sprintf("Error: %s is a %s. Translation works in text, announcement and forum channels and in threads.", channel.Mention(), channelTypeName(channel.Type)),
+			})
+			return
+		}
+	}
+	if problem := duplicateTranslateChannel(i.GuildID, [3]*discordgo.Channel{channel1, channel2, channel3}); problem != "" {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Error: %s Nothing was changed.\n%s", problem, describeTranslateChannels(i.GuildID)),
+		})
+		return
+	}
+
+	err := addTranslateChannels(i.GuildID, channel1, channel2, channel3)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to enable translation for channels: %s", err.Error()),
+		})
+		return
+	}
+
+	responseContent := "Translation enabled for"
+	if channel1 != nil {
+		responseContent += fmt.Sprintf(" channel 1: %s", channel1.Mention())
+	}
+	if channel2 != nil {
+		if channel1 != nil {
+			responseContent += " and"
+		}
+		responseContent += fmt.Sprintf(" channel 2: %s", channel2.Mention())
+	}
+	if channel3 != nil {
+		if channel1 != nil || channel2 != nil {
+			responseContent += " and"
+		}
+		responseContent += fmt.Sprintf(" channel 3: %s", channel3.Mention())
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
+
+func handleTranslateRemoveCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	channelID := channelIDValue(i.ApplicationCommandData().Options[0].Options[0].StringValue())
+
+	channelIDs := translateChannels[i.GuildID]
+	slot := slices.Index(channelIDs[:], channelID)
+	if channelID == "" || slot < 0 {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Error: That channel isn't translated.\n%s", describeTranslateChannels(i.GuildID)),
+		})
+		return
+	}
+
+	channelIDs[slot] = ""
+	err := setTranslateChannels(i.GuildID, channelIDs)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to stop translating the channel: %s", err.Error()),
+		})
+		return
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: fmt.Sprintf("Stopped translating <#%s>.", channelID),
+	})
+}
+
+func handleTranslateListCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: describeTranslateChannels(i.GuildID),
+	})
+}
+
+// duplicateTranslateChannel explains why the channels can't be set when one
+// of them is given for more than one slot or is already configured in another
+// slot. It returns an empty string when there is no duplicate.
+func duplicateTranslateChannel(serverID string, channels [3]*discordgo.Channel) string {
+	current := translateChannels[serverID]
+	for slot, channel := range channels {
+		if channel == nil {
+			continue
+		}
+		for other := slot + 1; other < len(channels); other++ {
+			if channels[other] != nil && channels[other].ID == channel.ID {
+				return fmt.Sprintf("%s was given for both channel %d and channel %d.", channel.Mention(), slot+1, other+1)
+			}
+		}
+		for other, channelID := range current {
+			if channelID == channel.ID && other != slot && channels[other] == nil {
+				return fmt.Sprintf("%s is already set as channel %d.", channel.Mention(), other+1)
+			}
+		}
+	}
+	return ""
+}
+
+// describeTranslateChannels lists the server's translated channels by slot.
+func describeTranslateChannels(serverID string) string {
+	lines := []string{"Current translated channels:"}
+	for n, channelID := range translateChannels[serverID] {
+		channel := "not set"
+		if channelID != "" {
+			channel = fmt.Sprintf("<#%s>", channelID)
+		}
+		lines = append(lines, fmt.Sprintf("Channel %d: %s", n+1, channel))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func addTranslateChannels(serverID string, channel1, channel2, channel3 *discordgo.Channel) error {
+	channelIDs := translateChannels[serverID]
+	for n, channel := range []*discordgo.Channel{channel1, channel2, channel3} {
+		if channel != nil {
+			channelIDs[n] = channel.ID
+		}
+	}
+
+	return setTranslateChannels(serverID, channelIDs)
+}
+
+// setTranslateChannels replaces the server's translated channels.
+func setTranslateChannels(serverID string, channelIDs [3]string) error {
+	err := store.SetTranslateChannels(serverID, channelIDs)
+	if err == nil {
+		err = reloadShared(changeChannels)
+	}
+	return err
+}
+
+// translatableChannelTypes are the channel types whose messages can be
+// translated. Messages in a forum arrive in its posts, which are threads.
+var translatableChannelTypes = []discordgo.ChannelType{
+	discordgo.ChannelTypeGuildText,
+	discordgo.ChannelTypeGuildNews,
+	discordgo.ChannelTypeGuildForum,
+	discordgo.ChannelTypeGuildPublicThread,
+	discordgo.ChannelTypeGuildPrivateThread,
+	discordgo.ChannelTypeGuildNewsThread,
+}
+
+// postableChannelTypes are the channel types the bot posts translations and
+// notices to.
+var postableChannelTypes = []discordgo.ChannelType{
+	discordgo.ChannelTypeGuildText,
+	discordgo.ChannelTypeGuildNews,
+	discordgo.ChannelTypeGuildPublicThread,
+	discordgo.ChannelTypeGuildPrivateThread,
+	discordgo.ChannelTypeGuildNewsThread,
+}
+
+func isTranslatableChannel(channel *discordgo.Channel) bool {
+	return slices.Contains(translatableChannelTypes, channel.Type)
+}
+
+// channelTypeName describes a channel type in error messages.
+func channelTypeName(channelType discordgo.ChannelType) string {
+	switch channelType {
+	case discordgo.ChannelTypeGuildVoice:
+		return "voice channel"
+	case discordgo.ChannelTypeGuildStageVoice:
+		return "stage channel"
+	case discordgo.ChannelTypeGuildCategory:
+		return "category"
+	case discordgo.ChannelTypeGuildMedia:
+		return "media channel"
+	case discordgo.ChannelTypeGuildDirectory:
+		return "directory channel"
+	}
+	return "channel of an unsupported type"
+}
+
+func isTranslateChannel(channelID string) bool {
+	_, ok := translateChannelGuilds[channelID]
+	return ok
+}
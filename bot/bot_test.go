@@ -0,0 +1,75 @@
+package bot
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+
+	"translate-bot/storage"
+	"translate-bot/translation"
+)
+
+// initTestStore loads the bot's state from an empty database of its own.
+func initTestStore(t *testing.T) {
+	t.Helper()
+	st, err := storage.Open(filepath.Join(t.TempDir(), "channels.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+	if err := Init(st); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// fakeBackend returns every text unchanged.
+type fakeBackend struct {
+	name string
+}
+
+func (b *fakeBackend) Name() string   { return b.name }
+func (b *fakeBackend) APIKey() string { return "" }
+
+func (b *fakeBackend) Translate(text, targetLang string, opts translation.Options) (string, error) {
+	return text, nil
+}
+
+func TestDuplicateTranslateChannel(t *testing.T) {
+	initTestStore(t)
+	translateChannels["guild"] = [3]string{"a", "b", ""}
+
+	a := &discordgo.Channel{ID: "a"}
+	b := &discordgo.Channel{ID: "b"}
+	c := &discordgo.Channel{ID: "c"}
+	tests := []struct {
+		channels [3]*discordgo.Channel
+		want     string
+	}{
+		{[3]*discordgo.Channel{c, nil, nil}, ""},
+		{[3]*discordgo.Channel{b, a, nil}, ""},
+		{[3]*discordgo.Channel{a, nil, c}, ""},
+		{[3]*discordgo.Channel{c, c, nil}, "given for both channel 1 and channel 2"},
+		{[3]*discordgo.Channel{nil, nil, a}, "already set as channel 1"},
+		{[3]*discordgo.Channel{b, nil, nil}, "already set as channel 2"},
+	}
+	for _, test := range tests {
+		got := duplicateTranslateChannel("guild", test.channels)
+		if (test.want == "") != (got == "") || !strings.Contains(got, test.want) {
+			t.Errorf("duplicateTranslateChannel(%s) = %q, want %q", channelIDs(test.channels), got, test.want)
+		}
+	}
+}
+
+func channelIDs(channels [3]*discordgo.Channel) string {
+	var ids []string
+	for _, channel := range channels {
+		id := "-"
+		if channel != nil {
+			id = channel.ID
+		}
+		ids = append(ids, id)
+	}
+	return strings.Join(ids, ",")
+}
@@ -0,0 +1,128 @@
+package bot
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"translate-bot/translation"
+)
+
+// A backend's breaker opens after breakerFailures failed translations in a
+// row. While it is open translations fail right away, until breakerCooldown
+// has passed and one translation is let through to probe the backend.
+const (
+	breakerFailures = 5
+	breakerCooldown = time.Minute
+)
+
+// errBackendDown is returned instead of calling a backend whose breaker is
+// open.
+var errBackendDown = errors.New("the translation backend is down, try again later")
+
+// breaker tracks the recent failures of a backend.
+type breaker struct {
+	failures int
+	// openedAt is when the breaker last opened, or zero while it is closed.
+	openedAt time.Time
+	probing  bool
+}
+
+var (
+	breakersMu sync.Mutex
+	// breakers holds a breaker per backend and API key, so one guild's bad
+	// key doesn't cut off the others.
+	breakers = make(map[string]*breaker)
+)
+
+func breakerKey(b translation.Backend) string {
+	key := b.Name()
+	if apiKey := b.APIKey(); apiKey != "" {
+		sum := sha256.Sum256([]byte(apiKey))
+		key += ":" + hex.EncodeToString(sum[:8])
+	}
+	return key
+}
+
+// breakerAllow reports whether the backend may be called, letting a single
+// probe through once the breaker has been open for breakerCooldown.
+func breakerAllow(key string) bool {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+
+	br := breakers[key]
+	if br == nil || br.openedAt.IsZero() {
+		return true
+	}
+	if br.probing || time.Since(br.openedAt) < breakerCooldown {
+		return false
+	}
+	br.probing = true
+	return true
+}
+
+// breakerResult records how a call to the backend went. Quota errors and
+// canceled calls say nothing about whether the backend is up, so they don't
+// count, though a canceled probe lets the next call probe instead.
+func breakerResult(key string, err error) {
+	if errors.Is(err, translation.ErrQuotaExceeded) {
+		return
+	}
+
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+
+	br := breakers[key]
+	if errors.Is(err, context.Canceled) {
+		if br != nil {
+			br.probing = false
+		}
+		return
+	}
+	if err == nil {
+		delete(breakers, key)
+		return
+	}
+	if br == nil {
+		br = &breaker{}
+		breakers[key] = br
+	}
+	br.failures++
+	br.probing = false
+	if br.failures >= breakerFailures {
+		br.openedAt = time.Now()
+	}
+}
+
+// backendDown reports whether the breaker of the backend is open.
+func backendDown(key string) bool {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+
+	br := breakers[key]
+	return br != nil && !br.openedAt.IsZero()
+}
+
+// breakerTrip opens the breaker of a backend known to be failing.
+func breakerTrip(key string) {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+
+	breakers[key] = &breaker{failures: breakerFailures, openedAt: time.Now()}
+}
+
+// breakerState returns how many times in a row the backend failed, and when
+// its breaker opened or the zero time while it is closed.
+func breakerState(key string) (int, time.Time) {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+
+	br := breakers[key]
+	if br == nil {
+		return 0, time.Time{}
+	}
+	return br.failures, br.openedAt
+}
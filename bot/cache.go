@@ -0,0 +1,62 @@
+package bot
+
+import (
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Channels the state cache doesn't hold, such as threads the bot wasn't told
+// about, are fetched from the API once and kept until Discord reports a
+// change. Without this, every message in such a thread costs an API call.
+var (
+	channelCacheMu sync.RWMutex
+	channelCache   = make(map[string]*discordgo.Channel)
+)
+
+// lookupChannel returns the channel from the state cache, falling back to the
+// bot's own cache and then the API for channels the state hasn't seen.
+func lookupChannel(s *discordgo.Session, channelID string) (*discordgo.Channel, error) {
+	if channel, err := s.State.Channel(channelID); err == nil {
+		return channel, nil
+	}
+
+	channelCacheMu.RLock()
+	channel, ok := channelCache[channelID]
+	channelCacheMu.RUnlock()
+	if ok {
+		return channel, nil
+	}
+
+	channel, err := s.Channel(channelID)
+	if err != nil {
+		return nil, err
+	}
+	channelCacheMu.Lock()
+	channelCache[channelID] = channel
+	channelCacheMu.Unlock()
+	return channel, nil
+}
+
+func forgetChannel(channelID string) {
+	channelCacheMu.Lock()
+	delete(channelCache, channelID)
+	channelCacheMu.Unlock()
+}
+
+func channelUpdate(s *discordgo.Session, e *discordgo.ChannelUpdate) {
+	forgetChannel(e.ID)
+}
+
+func channelDelete(s *discordgo.Session, e *discordgo.ChannelDelete) {
+	forgetChannel(e.ID)
+	forgetChannelWebhook(e.ID)
+}
+
+func threadUpdate(s *discordgo.Session, e *discordgo.ThreadUpdate) {
+	forgetChannel(e.ID)
+}
+
+func threadDelete(s *discordgo.Session, e *discordgo.ThreadDelete) {
+	forgetChannel(e.ID)
+}
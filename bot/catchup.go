@@ -0,0 +1,103 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Catch-up covers at most catchUpMessages messages per channel, none older
+// than catchUpAge, so a long outage doesn't flood channels with translations.
+const (
+	catchUpMessages = 50
+	catchUpAge      = 6 * time.Hour
+)
+
+// seenStage remembers the last message handled in each translated channel of
+// servers with catch-up enabled, so messages posted while the bot was down
+// can be found later.
+func seenStage(p *pipelineMessage) bool {
+	if getGuildSetting(p.m.GuildID, settingCatchUp) != "" && !p.catchUp {
+		if err := setChannelSetting(p.m.GuildID, p.m.ChannelID, settingLastMessage, p.m.ID); err != nil {
+			log.Println("Error saving last message,", err)
+		}
+	}
+	return true
+}
+
+// ready translates the messages missed while the bot was disconnected. A
+// resumed session gets missed events replayed by Discord, so only full
+// connects need this.
+func ready(s *discordgo.Session, r *discordgo.Ready) {
+	for _, guild := range r.Guilds {
+		if getGuildSetting(guild.ID, settingCatchUp) == "" {
+			continue
+		}
+		for _, channelID := range translateChannels[guild.ID] {
+			if channelID != "" {
+				catchUp(s, guild.ID, channelID)
+			}
+		}
+	}
+}
+
+// catchUp runs the channel's messages since the last one handled through the
+// pipeline, marked as catch-up.
+func catchUp(s *discordgo.Session, serverID, channelID string) {
+	lastID := getChannelSetting(channelID, settingLastMessage)
+	if lastID == "" {
+		return
+	}
+	oldest := snowflakeAt(time.Now().Add(-catchUpAge))
+	if snowflakeLess(lastID, oldest) {
+		lastID = oldest
+	}
+
+	messages, err := s.ChannelMessages(channelID, catchUpMessages, "", lastID, "")
+	if err != nil {
+		log.Println("Error fetching missed messages,", err)
+		return
+	}
+	sort.Slice(messages, func(a, b int) bool { return snowflakeLess(messages[a].ID, messages[b].ID) })
+
+	for _, message := range messages {
+		if message.Author == nil || message.Author.Bot {
+			continue
+		}
+		message.GuildID = serverID
+		runPipeline(&pipelineMessage{s: s, m: &discordgo.MessageCreate{Message: message}, catchUp: true})
+	}
+	if len(messages) > 0 {
+		last := messages[len(messages)-1].ID
+		if err := setChannelSetting(serverID, channelID, settingLastMessage, last); err != nil {
+			log.Println("Error saving last message,", err)
+		}
+	}
+}
+
+func handleConfigCatchUpCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	enabled := i.ApplicationCommandData().Options[0].Options[0].BoolValue()
+
+	value := ""
+	if enabled {
+		value = "on"
+	}
+	err := setGuildSetting(i.GuildID, settingCatchUp, value)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to update catch-up: %s", err.Error()),
+		})
+		return
+	}
+
+	responseContent := "Messages posted while I'm offline will be left untranslated."
+	if enabled {
+		responseContent = fmt.Sprintf("When I come back online I'll translate up to %d messages per channel from the last %d hours that I missed, marked as catch-up.", catchUpMessages, int(catchUpAge.Hours()))
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
@@ -0,0 +1,90 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// configuredChannels returns every channel the server has configured the bot
+// to read from or post in, with what it is used for.
+func configuredChannels(s *discordgo.Session, serverID string) map[string][]string {
+	channels := make(map[string][]string)
+	add := func(channelID, use string) {
+		if channelID != "" {
+			channels[channelID] = append(channels[channelID], use)
+		}
+	}
+
+	for _, channelID := range translateChannels[serverID] {
+		add(channelID, "translated")
+	}
+	add(getGuildSetting(serverID, settingTranslationsChannel), "translations")
+	add(getGuildSetting(serverID, settingLogChannel), "log")
+	add(getGuildSetting(serverID, settingDigestChannel), "digest")
+	add(getGuildSetting(serverID, settingWelcomeChannel), "welcome")
+	if rules := getGuildSetting(serverID, settingRulesMessages); rules != "" {
+		channelID, _, _ := strings.Cut(rules, ":")
+		add(channelID, "rules")
+	}
+	for _, channelID := range settings.ChannelsWith(settingAnnounceLanguages) {
+		if channel, err := s.State.Channel(channelID); err == nil && channel.GuildID == serverID {
+			add(channelID, "announcements")
+		}
+	}
+	return channels
+}
+
+func handleCheckPermsCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	channels := configuredChannels(s, i.GuildID)
+	if len(channels) == 0 {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "No channels are configured yet. Use /setup to get started.",
+		})
+		return
+	}
+
+	channelIDs := make([]string, 0, len(channels))
+	for channelID := range channels {
+		channelIDs = append(channelIDs, channelID)
+	}
+	sort.Strings(channelIDs)
+
+	var table strings.Builder
+	table.WriteString("```\nChannel             View Send Embd Hook Thrd  Crt\n")
+	var failing []string
+	for _, channelID := range channelIDs {
+		name := channelID
+		if channel, err := s.State.Channel(channelID); err == nil {
+			name = "#" + channel.Name
+		}
+		if len([]rune(name)) > 20 {
+			name = string([]rune(name)[:19]) + "…"
+		}
+
+		permissions, err := s.State.UserChannelPermissions(s.State.User.ID, channelID)
+		fmt.Fprintf(&table, "%-20s", name)
+		for _, checked := range checkedPermissions {
+			mark := "  ✅ "
+			if err != nil {
+				mark = "  ❔ "
+			} else if permissions&checked.permission == 0 {
+				mark = "  ❌ "
+				failing = append(failing, fmt.Sprintf("<#%s> (%s): %s", channelID, strings.Join(channels[channelID], ", "), checked.name))
+			}
+			table.WriteString(mark)
+		}
+		table.WriteString("\n")
+	}
+	table.WriteString("```")
+
+	summary := "All configured channels pass."
+	if len(failing) > 0 {
+		summary = "Missing:\n" + strings.Join(failing, "\n")
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: table.String() + "\n" + summary,
+	})
+}
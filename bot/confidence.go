@@ -0,0 +1,67 @@
+package bot
+
+import (
+	"fmt"
+
+	"github.com/abadojack/whatlanggo"
+	"github.com/bwmarrin/discordgo"
+)
+
+// translationConfidence estimates how reliable a translation is from 0 to 1.
+// None of the backends report a confidence of their own, so the score combines
+// how sure the language detector is about the source, whether the translation
+// has a plausible length and, when available, the back-translation score.
+func translationConfidence(original, translated string, roundTrip float64, checked bool) float64 {
+	detection := whatlanggo.Detect(original).Confidence
+
+	ratio := float64(len([]rune(translated))) / float64(len([]rune(original)))
+	if ratio > 1 {
+		ratio = 1 / ratio
+	}
+	lengthScore := 1.0
+	if ratio < 0.5 {
+		lengthScore = ratio * 2
+	}
+
+	score := (detection + lengthScore) / 2
+	if checked {
+		score = (score + roundTrip) / 2
+	}
+	return score
+}
+
+// confidenceFooter renders the score as a subtext line shown under the
+// translation.
+func confidenceFooter(score float64) string {
+	label := "high"
+	if score < 0.5 {
+		label = "low"
+	} else if score < 0.75 {
+		label = "medium"
+	}
+	return fmt.Sprintf("\n-# Confidence: %.0f%% (%s)", score*100, label)
+}
+
+func handleConfigConfidenceCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	enabled := i.ApplicationCommandData().Options[0].Options[0].BoolValue()
+
+	value := ""
+	if enabled {
+		value = "on"
+	}
+	err := setGuildSetting(i.GuildID, settingShowConfidence, value)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to update confidence scores: %s", err.Error()),
+		})
+		return
+	}
+
+	responseContent := "Confidence scores will no longer be shown."
+	if enabled {
+		responseContent = "Translations will show an estimated confidence score below them."
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
@@ -0,0 +1,126 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/bwmarrin/discordgo"
+
+	"translate-bot/filter"
+)
+
+// maxContextMessages caps how much history is sent along with a message.
+const maxContextMessages = 10
+
+// Redaction levels for context messages. Strict redaction is the default
+// because context is sent to a third-party LLM provider.
+const (
+	redactionStrict = "strict"
+	redactionBasic  = "basic"
+)
+
+// redactContext removes personal details from a context message.
+func redactContext(text, level string) string {
+	return filter.Redact(text, level != redactionBasic)
+}
+
+// usesLLM reports whether the server's messages are translated by the LLM
+// backend.
+func usesLLM(serverID string) bool {
+	return premiumBackend != nil && guildBackend(serverID) == premiumBackend
+}
+
+// contextLimit returns how many messages of context the server sends along
+// with each message, or 0 when it sends none: context is opt-in, experimental
+// and only used by the LLM backend.
+func contextLimit(serverID string) int {
+	limit, _ := strconv.Atoi(getGuildSetting(serverID, settingContextMessages))
+	if limit <= 0 || !usesLLM(serverID) || !featureEnabled(serverID, featureContext) {
+		return 0
+	}
+	return limit
+}
+
+// channelContext returns the redacted messages preceding beforeID, oldest
+// first, when the server translates with the LLM backend and has context
+// enabled.
+func channelContext(s *discordgo.Session, serverID, channelID, beforeID string) []string {
+	limit := contextLimit(serverID)
+	if limit == 0 {
+		return nil
+	}
+
+	messages, err := s.ChannelMessages(channelID, limit, beforeID, "", "")
+	if err != nil {
+		log.Println("Error fetching context messages,", err)
+		return nil
+	}
+
+	level := getGuildSetting(serverID, settingContextRedaction)
+	speakers := make(map[string]string)
+	var context []string
+	for i := len(messages) - 1; i >= 0; i-- {
+		message := messages[i]
+		if message.Author == nil || message.Author.Bot || message.Content == "" {
+			continue
+		}
+		speaker := message.Author.Username
+		if level != redactionBasic {
+			if speakers[message.Author.ID] == "" {
+				speakers[message.Author.ID] = fmt.Sprintf("Speaker %d", len(speakers)+1)
+			}
+			speaker = speakers[message.Author.ID]
+		}
+		context = append(context, fmt.Sprintf("%s: %s", speaker, redactContext(message.Content, level)))
+	}
+	return context
+}
+
+func handleConfigContextCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var messages int64
+	redaction := redactionStrict
+	for _, option := range i.ApplicationCommandData().Options[0].Options {
+		if option.Name == "messages" {
+			messages = option.IntValue()
+		} else if option.Name == "redaction" {
+			redaction = option.StringValue()
+		}
+	}
+
+	if messages < 0 || messages > maxContextMessages {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Error: The number of messages must be between 0 and %d.", maxContextMessages),
+		})
+		return
+	}
+	if messages > 0 && (!requireFeature(s, i, featureContext) || !requirePremium(s, i, featureLLM)) {
+		return
+	}
+
+	value, redactionValue := "", ""
+	if messages > 0 {
+		value = strconv.FormatInt(messages, 10)
+		if redaction == redactionBasic {
+			redactionValue = redaction
+		}
+	}
+	err := setGuildSetting(i.GuildID, settingContextMessages, value)
+	if err == nil {
+		err = setGuildSetting(i.GuildID, settingContextRedaction, redactionValue)
+	}
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to update translation context: %s", err.Error()),
+		})
+		return
+	}
+
+	responseContent := "Channel history will no longer be sent as context."
+	if messages > 0 {
+		responseContent = fmt.Sprintf("The last %d messages will be sent to the LLM backend as context, with %s redaction.", messages, redaction)
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
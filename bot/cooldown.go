@@ -0,0 +1,121 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// cooldown allows a limited number of events per key within a sliding
+// window. With Redis set up, the count is shared by all instances in fixed
+// windows instead.
+type cooldown struct {
+	name   string
+	limit  int
+	window time.Duration
+
+	mu     sync.Mutex
+	events map[string][]time.Time
+}
+
+func newCooldown(name string, limit int, window time.Duration) *cooldown {
+	return &cooldown{name: name, limit: limit, window: window, events: make(map[string][]time.Time)}
+}
+
+// allow records an event for the key and reports whether it is within the
+// limit. When it isn't, it also returns how long until the next event is
+// allowed.
+func (c *cooldown) allow(key string) (bool, time.Duration) {
+	if sharedRedis != nil {
+		allowed, wait, err := c.allowShared(key)
+		if err == nil {
+			return allowed, wait
+		}
+		log.Println("Error counting shared cooldown,", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	events := c.events[key]
+	for len(events) > 0 && now.Sub(events[0]) >= c.window {
+		events = events[1:]
+	}
+	if len(events) >= c.limit {
+		c.events[key] = events
+		return false, events[0].Add(c.window).Sub(now)
+	}
+	c.events[key] = append(events, now)
+	return true, 0
+}
+
+// allowShared counts the event in Redis, in the window the current time
+// falls into.
+func (c *cooldown) allowShared(key string) (bool, time.Duration, error) {
+	now := time.Now()
+	start := now.Truncate(c.window)
+	count, err := sharedCount(fmt.Sprintf("cooldown:%s:%s:%d", c.name, key, start.Unix()), c.window)
+	if err != nil {
+		return false, 0, err
+	}
+	if count > int64(c.limit) {
+		return false, start.Add(c.window).Sub(now), nil
+	}
+	return true, 0, nil
+}
+
+// prune forgets keys without events in the current window.
+func (c *cooldown) prune() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for key, events := range c.events {
+		if len(events) == 0 || now.Sub(events[len(events)-1]) >= c.window {
+			delete(c.events, key)
+		}
+	}
+}
+
+func pruneCooldowns(s *discordgo.Session) {
+	userCooldown.prune()
+	guildCooldown.prune()
+	liveCooldown.prune()
+}
+
+var (
+	userCooldown  = newCooldown("user", 5, 10*time.Second)
+	guildCooldown = newCooldown("guild", 30, 10*time.Second)
+)
+
+// allowInteraction applies the per-user and per-guild cooldowns, telling the
+// user when to try again if they are over the limit.
+func allowInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) bool {
+	userID := ""
+	if i.Member != nil {
+		userID = i.Member.User.ID
+	} else if i.User != nil {
+		userID = i.User.ID
+	}
+
+	allowed, wait := userCooldown.allow(userID)
+	if allowed && i.GuildID != "" {
+		allowed, wait = guildCooldown.allow(i.GuildID)
+	}
+	if allowed {
+		return true
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("You're going too fast. Try again in %d seconds.", int(wait.Seconds())+1),
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+	return false
+}
@@ -0,0 +1,40 @@
+package bot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCooldownAllow(t *testing.T) {
+	const window = 100 * time.Millisecond
+	c := newCooldown("test", 2, window)
+
+	for n := 0; n < 2; n++ {
+		if allowed, _ := c.allow("user"); !allowed {
+			t.Fatalf("event %d was refused within the limit", n+1)
+		}
+	}
+	allowed, wait := c.allow("user")
+	if allowed {
+		t.Fatal("event over the limit was allowed")
+	}
+	if wait <= 0 || wait > window {
+		t.Errorf("wait = %s, want within the %s window", wait, window)
+	}
+
+	// Keys are counted separately.
+	if allowed, _ := c.allow("other"); !allowed {
+		t.Error("another key was refused")
+	}
+
+	time.Sleep(window)
+	if allowed, _ := c.allow("user"); !allowed {
+		t.Error("event was refused after the window passed")
+	}
+
+	time.Sleep(window)
+	c.prune()
+	if len(c.events) != 0 {
+		t.Errorf("prune() kept %d keys without recent events", len(c.events))
+	}
+}
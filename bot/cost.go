@@ -0,0 +1,57 @@
+package bot
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// backendPricing is the price in USD per million characters for each backend.
+// PRICE_PER_MILLION_CHARACTERS overrides it for hosters on custom plans.
+var backendPricing = map[string]float64{
+	"translate-shell": 0,
+	"deepl":           25,
+	"google":          20,
+}
+
+// costSampleDays is how much recent history the estimate is based on.
+const costSampleDays = 30
+
+func pricePerMillion(backendName string) float64 {
+	if price, err := strconv.ParseFloat(os.Getenv("PRICE_PER_MILLION_CHARACTERS"), 64); err == nil {
+		return price
+	}
+	return backendPricing[backendName]
+}
+
+func handleCostCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	subCommand := i.ApplicationCommandData().Options[0].Name
+
+	switch subCommand {
+	case "estimate":
+		handleCostEstimateCommand(s, i)
+	}
+}
+
+func handleCostEstimateCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	since := guildNow(i.GuildID).AddDate(0, 0, -costSampleDays).Format("2006-01-02")
+	characters, err := usageSince(i.GuildID, since)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to retrieve usage: %s", err.Error()),
+		})
+		return
+	}
+
+	backendName := guildBackend(i.GuildID).Name()
+	price := pricePerMillion(backendName)
+	monthlyCharacters := characters * 30 / costSampleDays
+	monthlyCost := float64(monthlyCharacters) / 1_000_000 * price
+
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: fmt.Sprintf("Based on %d characters translated in the last %d days, this server will use about %d characters per month.\nAt %s pricing ($%.2f per million characters) that is roughly **$%.2f per month**.",
+			characters, costSampleDays, monthlyCharacters, backendName, price, monthlyCost),
+	})
+}
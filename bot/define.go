@@ -0,0 +1,73 @@
+package bot
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+
+	"translate-bot/translation"
+)
+
+// maxDefinition keeps dictionary output within a single Discord message.
+const maxDefinition = 1900
+
+// dictionaryFor returns the dictionary for the server: its translation
+// backend when that has one, the Azure dictionary when configured, or nil.
+func dictionaryFor(serverID string) translation.Dictionary {
+	if d, ok := guildBackend(serverID).(translation.Dictionary); ok {
+		return d
+	}
+	if apiKey := os.Getenv("AZURE_TRANSLATOR_KEY"); apiKey != "" {
+		return translation.NewAzureDictionary(apiKey, os.Getenv("AZURE_TRANSLATOR_REGION"))
+	}
+	return nil
+}
+
+func handleDefineCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var word, sourceLang, targetLang string
+	for _, option := range i.ApplicationCommandData().Options {
+		switch option.Name {
+		case "word":
+			word = strings.TrimSpace(option.StringValue())
+		case "from":
+			sourceLang = strings.ToLower(strings.TrimSpace(option.StringValue()))
+		case "to":
+			targetLang = strings.ToLower(strings.TrimSpace(option.StringValue()))
+		}
+	}
+	if sourceLang == "" {
+		sourceLang = detectLanguage(word)
+	}
+	if targetLang == "" {
+		targetLang = guildTargetLanguage(i.GuildID)
+	}
+
+	d := dictionaryFor(i.GuildID)
+	if d == nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "Dictionary lookups aren't available with this server's translation backend.",
+		})
+		return
+	}
+
+	definition, err := d.Define(word, sourceLang, targetLang)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to look up %q: %s", word, err.Error()),
+		})
+		return
+	}
+
+	responseContent := fmt.Sprintf("No dictionary entry found for %q.", word)
+	if definition != "" {
+		if len([]rune(definition)) > maxDefinition {
+			definition = string([]rune(definition)[:maxDefinition-1]) + "…"
+		}
+		responseContent = fmt.Sprintf("%s **%s** → %s %s\n%s", languageFlag(sourceLang), word, languageFlag(targetLang), targetLang, definition)
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
@@ -0,0 +1,46 @@
+package bot
+
+import (
+	"fmt"
+
+	"github.com/abadojack/whatlanggo"
+	"github.com/bwmarrin/discordgo"
+)
+
+// describeDetection reports the detected language of the text with the
+// detector's confidence.
+func describeDetection(text string) string {
+	info := whatlanggo.Detect(text)
+	code := info.Lang.Iso6391()
+	if code == "" || info.Confidence == 0 {
+		return "The language of this text could not be detected."
+	}
+
+	description := fmt.Sprintf("%s Detected **%s** (`%s`, %s script) with %.0f%% confidence.",
+		languageFlag(code), info.Lang.String(), code, whatlanggo.Scripts[info.Script], info.Confidence*100)
+	if !info.IsReliable() {
+		description += "\nThe text may be too short for a reliable result."
+	}
+	return description
+}
+
+func handleDetectCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	text := i.ApplicationCommandData().Options[0].StringValue()
+
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: describeDetection(text),
+	})
+}
+
+func handleDetectMessageCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+	message := data.Resolved.Messages[data.TargetID]
+
+	responseContent := "This message has no text to detect."
+	if message != nil && message.Content != "" {
+		responseContent = describeDetection(message.Content)
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
@@ -0,0 +1,138 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const digestInterval = 7 * 24 * time.Hour
+
+func recordLanguageUsage(serverID, sourceLang string) error {
+	return store.RecordLanguageUsage(serverID, guildDay(serverID), sourceLang)
+}
+
+func recordError(serverID string) error {
+	return store.RecordError(serverID, guildDay(serverID))
+}
+
+// postWeeklyDigests posts the digest of every guild whose digest is due to
+// its digest channel.
+func postWeeklyDigests(s *discordgo.Session) {
+	for guildID, guild := range settings.Guilds() {
+		channelID := guild[settingDigestChannel]
+		if channelID == "" {
+			continue
+		}
+		lastSent, _ := strconv.ParseInt(guild[settingDigestLastSent], 10, 64)
+		if time.Since(time.Unix(lastSent, 0)) < digestInterval {
+			continue
+		}
+
+		err := postDigest(s, guildID, channelID)
+		if err != nil {
+			log.Println("Error posting weekly digest,", err)
+			continue
+		}
+		err = setGuildSetting(guildID, settingDigestLastSent, strconv.FormatInt(time.Now().Unix(), 10))
+		if err != nil {
+			log.Println("Error saving digest time,", err)
+		}
+	}
+}
+
+func postDigest(s *discordgo.Session, guildID, channelID string) error {
+	digest, err := buildDigest(guildID)
+	if err != nil {
+		return err
+	}
+	_, err = sendQueued(s, channelID, &discordgo.MessageSend{
+		Content:         digest,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	return err
+}
+
+// buildDigest summarizes the last week of translation activity for the guild.
+func buildDigest(guildID string) (string, error) {
+	since := guildNow(guildID).Add(-digestInterval).Format("2006-01-02")
+
+	characters, err := usageSince(guildID, since)
+	if err != nil {
+		return "", err
+	}
+
+	errors, err := store.ErrorsSince(guildID, since)
+	if err != nil {
+		return "", err
+	}
+
+	usage, err := store.LanguageUsageSince(guildID, since)
+	if err != nil {
+		return "", err
+	}
+
+	var translations int
+	var languages []string
+	for _, language := range usage {
+		translations += language.Translations
+		if len(languages) < 5 {
+			languages = append(languages, fmt.Sprintf("%s %s (%d)", languageFlag(language.Language), language.Language, language.Translations))
+		}
+	}
+
+	var digest strings.Builder
+	digest.WriteString("**Weekly translation digest**\n")
+	fmt.Fprintf(&digest, "Translations: %d (%d characters)\n", translations, characters)
+	if len(languages) > 0 {
+		fmt.Fprintf(&digest, "Top languages: %s\n", strings.Join(languages, ", "))
+	}
+	fmt.Fprintf(&digest, "Errors: %d\n", errors)
+
+	if limit := quotaLimit(guildID, settingMonthlyQuota, "MONTHLY_CHARACTER_QUOTA"); limit > 0 {
+		used, err := usageSince(guildID, guildNow(guildID).Format("2006-01")+"-01")
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&digest, "Monthly quota used: %d of %d characters (%d%%)\n", used, limit, used*100/limit)
+	}
+
+	return digest.String(), nil
+}
+
+func handleConfigDigestCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var channel *discordgo.Channel
+	for _, option := range i.ApplicationCommandData().Options[0].Options {
+		if option.Name == "channel" {
+			channel = option.ChannelValue(s)
+		}
+	}
+
+	channelID := ""
+	if channel != nil {
+		channelID = channel.ID
+	}
+
+	err := setGuildSetting(i.GuildID, settingDigestChannel, channelID)
+	if err == nil && channel != nil {
+		err = setGuildSetting(i.GuildID, settingDigestLastSent, strconv.FormatInt(time.Now().Unix(), 10))
+	}
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to update digest channel: %s", err.Error()),
+		})
+		return
+	}
+
+	responseContent := "Weekly digests disabled."
+	if channel != nil {
+		responseContent = fmt.Sprintf("A weekly digest will be posted to %s.", channel.Mention())
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
@@ -0,0 +1,92 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// isDryRun reports whether the server only reports what it would translate
+// instead of posting translations.
+func isDryRun(serverID string) bool {
+	return getGuildSetting(serverID, settingDryRun) != ""
+}
+
+// reportDryRun sends a dry-run report to the server's log channel, or to the
+// process log when it has none.
+func reportDryRun(s *discordgo.Session, serverID, report string) {
+	report = "🧪 Dry run: " + report
+	channelID := getGuildSetting(serverID, settingLogChannel)
+	if channelID == "" {
+		log.Printf("Guild %s: %s", serverID, report)
+		return
+	}
+	queueMessage(s, channelID, report)
+}
+
+// postTranslation posts a translation of the message, or reports it when the
+// server is in dry-run mode.
+func postTranslation(s *discordgo.Session, m *discordgo.MessageCreate, channelID, content string) {
+	postTranslationThen(s, m, channelID, content, nil)
+}
+
+// postTranslationThen posts a translation like postTranslation and, unless
+// sent is nil, calls it with the posted message. For an edited message the
+// existing translation in the channel is edited instead.
+func postTranslationThen(s *discordgo.Session, m *discordgo.MessageCreate, channelID, content string, sent func(message *discordgo.Message)) {
+	if links, ok := editLinks(m.ID); ok {
+		editTranslation(s, m, links, channelID, content)
+		return
+	}
+	if !isDryRun(m.GuildID) {
+		queueMessageThen(s, channelID, content, sent)
+		fireEvent(webhookEvent{
+			Event:     eventTranslation,
+			GuildID:   m.GuildID,
+			ChannelID: m.ChannelID,
+			MessageID: m.ID,
+			UserID:    m.Author.ID,
+			Content:   content,
+		})
+		return
+	}
+	reportDryRun(s, m.GuildID, fmt.Sprintf("would post in <#%s> for %s:\n%s", channelID, messageJumpURL(m.GuildID, m.ChannelID, m.ID), content))
+}
+
+// reportSkipped records that a message wasn't translated and, when the server
+// is in dry-run mode, reports why.
+func reportSkipped(s *discordgo.Session, m *discordgo.MessageCreate, reason string) {
+	recordHistory(m, "")
+	logSkip(s, m, reason)
+	if isDryRun(m.GuildID) {
+		reportDryRun(s, m.GuildID, fmt.Sprintf("skipped %s: %s", messageJumpURL(m.GuildID, m.ChannelID, m.ID), reason))
+	}
+}
+
+func handleConfigDryRunCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	enabled := i.ApplicationCommandData().Options[0].Options[0].BoolValue()
+
+	value := ""
+	if enabled {
+		value = "on"
+	}
+	err := setGuildSetting(i.GuildID, settingDryRun, value)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to update dry-run mode: %s", err.Error()),
+		})
+		return
+	}
+
+	responseContent := "Dry-run mode is off. Translations will be posted again."
+	if enabled {
+		responseContent = "Dry-run mode is on. Translations won't be posted; what would have been posted, and why messages were skipped, is reported to the log channel."
+		if getGuildSetting(i.GuildID, settingLogChannel) == "" {
+			responseContent += " No log channel is set, so reports only go to the bot's own log. Set one with `/config logchannel`."
+		}
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
@@ -0,0 +1,149 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxEditWindow is the longest edit-tracking window a server can configure.
+const maxEditWindow = 24 * 60
+
+// editStages are the pipeline stages edited messages go through. Stages that
+// post something other than the translation, or count the message, are left
+// out so an edit doesn't repeat them.
+var editStages = map[string]bool{
+	"self":      true,
+	"pause":     true,
+	"dedup":     true,
+	"route":     true,
+	"channel":   true,
+	"roles":     true,
+	"filter":    true,
+	"toxicity":  true,
+	"incoming":  true,
+	"detect":    true,
+	"quota":     true,
+	"translate": true,
+	"outgoing":  true,
+	"similar":   true,
+	"hook":      true,
+	"quality":   true,
+	"output":    true,
+}
+
+var (
+	editingMu sync.Mutex
+	// editing holds the translations of messages whose edit is going
+	// through the pipeline, by message ID. Their translations are edited
+	// instead of posted.
+	editing = make(map[string][]messageLink)
+)
+
+// editWindow returns how long after posting a message's edits are still
+// translated. Zero means edits aren't tracked.
+func editWindow(serverID string) time.Duration {
+	minutes, _ := strconv.Atoi(getGuildSetting(serverID, settingEditWindow))
+	return time.Duration(minutes) * time.Minute
+}
+
+// tracksEdits reports whether translations of the message have to be linked
+// to it so an edit can update them.
+func tracksEdits(m *discordgo.MessageCreate) bool {
+	return editWindow(m.GuildID) > 0
+}
+
+func editLinks(messageID string) ([]messageLink, bool) {
+	editingMu.Lock()
+	defer editingMu.Unlock()
+	links, ok := editing[messageID]
+	return links, ok
+}
+
+// messageUpdate re-translates a message edited within the server's edit
+// window and updates its translations.
+func messageUpdate(s *discordgo.Session, e *discordgo.MessageUpdate) {
+	if e.GuildID == "" || e.Author == nil || e.Author.Bot {
+		return
+	}
+	// Discord also reports link previews being added as updates.
+	if e.BeforeUpdate != nil && e.BeforeUpdate.Content == e.Content {
+		return
+	}
+	window := editWindow(e.GuildID)
+	posted, err := discordgo.SnowflakeTimestamp(e.ID)
+	if window == 0 || err != nil || time.Since(posted) > window {
+		return
+	}
+	links := linkedMessages(e.ID)
+	if len(links) == 0 {
+		return
+	}
+
+	editingMu.Lock()
+	editing[e.ID] = links
+	editingMu.Unlock()
+	defer func() {
+		editingMu.Lock()
+		delete(editing, e.ID)
+		editingMu.Unlock()
+	}()
+
+	runPipeline(&pipelineMessage{s: s, m: &discordgo.MessageCreate{Message: e.Message}, edit: true})
+}
+
+// editTranslation replaces the message's translation in the channel with the
+// content, reporting instead in dry-run mode.
+func editTranslation(s *discordgo.Session, m *discordgo.MessageCreate, links []messageLink, channelID, content string) {
+	for _, link := range links {
+		if link.channelID != channelID {
+			continue
+		}
+		if isDryRun(m.GuildID) {
+			reportDryRun(s, m.GuildID, fmt.Sprintf("would edit %s for %s:\n%s", messageJumpURL(m.GuildID, channelID, link.messageID), messageJumpURL(m.GuildID, m.ChannelID, m.ID), content))
+			return
+		}
+
+		edit := discordgo.NewMessageEdit(channelID, link.messageID).SetContent(content)
+		edit.AllowedMentions = translationMentions
+		_, err := s.ChannelMessageEditComplex(edit)
+		if err != nil {
+			log.Println("Error editing translation,", err)
+		}
+		return
+	}
+}
+
+func handleConfigEditWindowCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	minutes := i.ApplicationCommandData().Options[0].Options[0].IntValue()
+	if minutes < 0 || minutes > maxEditWindow {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Error: The window must be between 0 and %d minutes.", maxEditWindow),
+		})
+		return
+	}
+
+	value := ""
+	if minutes > 0 {
+		value = strconv.FormatInt(minutes, 10)
+	}
+	err := setGuildSetting(i.GuildID, settingEditWindow, value)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to update the edit window: %s", err.Error()),
+		})
+		return
+	}
+
+	responseContent := "Edited messages will no longer update their translations."
+	if minutes > 0 {
+		responseContent = fmt.Sprintf("Messages edited within %d minutes of being posted will have their translations updated.", minutes)
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
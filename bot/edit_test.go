@@ -0,0 +1,102 @@
+package bot
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"translate-bot/translation"
+)
+
+// fixedBackend translates every text into the same translation.
+type fixedBackend struct {
+	fakeBackend
+	translation string
+}
+
+func (b *fixedBackend) Translate(text, targetLang string, opts translation.Options) (string, error) {
+	return b.translation, nil
+}
+
+// editRecorder answers Discord API requests, recording the message edits
+// among them.
+type editRecorder struct {
+	mu    sync.Mutex
+	edits map[string]string
+}
+
+func (r *editRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := "{}"
+	if req.Method == http.MethodPatch {
+		var edit discordgo.MessageEdit
+		if err := json.NewDecoder(req.Body).Decode(&edit); err != nil {
+			return nil, err
+		}
+		r.mu.Lock()
+		r.edits[req.URL.Path] = *edit.Content
+		r.mu.Unlock()
+		body = `{"id": "translation", "channel_id": "channel"}`
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestMessageUpdate(t *testing.T) {
+	initTestStore(t)
+	oldBackend := activeBackend
+	activeBackend = &fixedBackend{fakeBackend{"fake"}, "hello everyone, how are you today?"}
+	defer func() { activeBackend = oldBackend }()
+
+	s, err := simulationSession("guild", "channel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := &editRecorder{edits: make(map[string]string)}
+	s.Client = &http.Client{Transport: recorder}
+	translateChannels["guild"] = [3]string{"channel"}
+	translateChannelGuilds["channel"] = "guild"
+	if err := setGuildSetting("guild", settingEditWindow, "60"); err != nil {
+		t.Fatal(err)
+	}
+
+	edit := func(id string) {
+		now := time.Now()
+		messageUpdate(s, &discordgo.MessageUpdate{Message: &discordgo.Message{
+			ID:              id,
+			GuildID:         "guild",
+			ChannelID:       "channel",
+			Content:         "hola a todos, ¿cómo estáis hoy?",
+			Author:          &discordgo.User{ID: "author", Username: "author"},
+			EditedTimestamp: &now,
+		}})
+	}
+	original := func(id string) *discordgo.MessageCreate {
+		return &discordgo.MessageCreate{Message: &discordgo.Message{ID: id, GuildID: "guild", ChannelID: "channel"}}
+	}
+
+	recent := snowflakeAt(time.Now().Add(-time.Minute))
+	linkMessages(original(recent), &discordgo.Message{ID: "translation", ChannelID: "channel"})
+	edit(recent)
+	if got := recorder.edits["/api/v9/channels/channel/messages/translation"]; !strings.Contains(got, "hello everyone") {
+		t.Errorf("translation edited to %q, want the new translation", got)
+	}
+
+	// Edits after the window leave the translation alone.
+	recorder.edits = make(map[string]string)
+	old := snowflakeAt(time.Now().Add(-2 * time.Hour))
+	linkMessages(original(old), &discordgo.Message{ID: "translation", ChannelID: "channel"})
+	edit(old)
+	if len(recorder.edits) != 0 {
+		t.Errorf("edits after the window changed translations: %v", recorder.edits)
+	}
+}
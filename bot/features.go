@@ -0,0 +1,95 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// featureContext is the LLM context feature, see channelContext.
+const featureContext = "context"
+
+// featureDefaults lists the capabilities the host can switch on or off per
+// guild, with whether they are on for guilds without an override.
+// Experimental capabilities start out off and are enabled for test guilds.
+// OCR has no flag because the bot can't read text in images yet; it gets one
+// along with the capability.
+var featureDefaults = map[string]bool{
+	featureVoice:   false,
+	featureContext: false,
+}
+
+// featureSetting is the guild setting that overrides a feature's default.
+func featureSetting(feature string) string {
+	return "feature_" + feature
+}
+
+// featureEnabled reports whether the feature is switched on for the guild.
+func featureEnabled(guildID, feature string) bool {
+	switch getGuildSetting(guildID, featureSetting(feature)) {
+	case "on":
+		return true
+	case "off":
+		return false
+	}
+	return featureDefaults[feature]
+}
+
+// requireFeature responds with an explanation and returns false when the
+// feature is switched off for the guild.
+func requireFeature(s *discordgo.Session, i *discordgo.InteractionCreate, feature string) bool {
+	if featureEnabled(i.GuildID, feature) {
+		return true
+	}
+
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: fmt.Sprintf("The %s feature is not enabled on this server.", feature),
+	})
+	return false
+}
+
+// SetFeature is used by the "feature" subcommand so hosters can switch a
+// feature on or off for a guild, or return it to its default. The change is
+// announced through Redis when REDIS_URL is set; otherwise running instances
+// pick it up on SIGHUP.
+func SetFeature(guildID, feature, state string) error {
+	if _, ok := featureDefaults[feature]; !ok {
+		return fmt.Errorf("unknown feature %q, known features are %s", feature, strings.Join(featureNames(), ", "))
+	}
+	switch state {
+	case "on", "off":
+	case "default":
+		state = ""
+	default:
+		return fmt.Errorf("unknown state %q, use on, off or default", state)
+	}
+	if err := connectRedis(); err != nil {
+		return err
+	}
+	return setGuildSetting(guildID, featureSetting(feature), state)
+}
+
+// PrintFeatures lists whether each feature is switched on for the guild.
+func PrintFeatures(guildID string) {
+	for _, feature := range featureNames() {
+		state := "off"
+		if featureEnabled(guildID, feature) {
+			state = "on"
+		}
+		if getGuildSetting(guildID, featureSetting(feature)) == "" {
+			state += " (default)"
+		}
+		fmt.Printf("%s: %s\n", feature, state)
+	}
+}
+
+func featureNames() []string {
+	var names []string
+	for feature := range featureDefaults {
+		names = append(names, feature)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,88 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+
+	"translate-bot/filter"
+)
+
+// Thread names are limited to 100 characters.
+const maxThreadName = 100
+
+// isTranslateForumPost reports whether the channel is a post in a forum that
+// is configured for translation.
+func isTranslateForumPost(s *discordgo.Session, channelID string) bool {
+	channel, err := lookupChannel(s, channelID)
+	if err != nil || !channel.IsThread() || !isTranslateChannel(channel.ParentID) {
+		return false
+	}
+	parent, err := lookupChannel(s, channel.ParentID)
+	return err == nil && parent.Type == discordgo.ChannelTypeGuildForum
+}
+
+// translateForumTitle translates the title of a new forum post. When the guild
+// has renaming enabled the thread is renamed and an empty string is returned;
+// otherwise the translated title is returned for inclusion in the first reply.
+func translateForumTitle(s *discordgo.Session, m *discordgo.MessageCreate) string {
+	thread, err := lookupChannel(s, m.ChannelID)
+	if err != nil || !thread.IsThread() || containsBannedWord(m.GuildID, m.ChannelID, thread.Name) {
+		return ""
+	}
+
+	characters := len([]rune(thread.Name))
+	if !checkQuota(s, m.GuildID, characters) {
+		return ""
+	}
+	translatedTitle, err := translateText(m.GuildID, thread.Name)
+	if err != nil {
+		log.Println("Error translating forum title,", err)
+		return ""
+	}
+	if err := recordUsage(m.GuildID, characters); err != nil {
+		log.Println("Error recording usage,", err)
+	}
+	if filter.AreTextsSimilar(thread.Name, translatedTitle) {
+		return ""
+	}
+
+	if getGuildSetting(m.GuildID, settingForumRename) != "" && !isDryRun(m.GuildID) {
+		name := []rune(fmt.Sprintf("%s | %s", thread.Name, translatedTitle))
+		if len(name) > maxThreadName {
+			name = name[:maxThreadName]
+		}
+		_, err = s.ChannelEdit(thread.ID, &discordgo.ChannelEdit{Name: string(name)})
+		if err == nil {
+			return ""
+		}
+		log.Println("Error renaming forum post,", err)
+	}
+
+	return fmt.Sprintf("**Title:** %s", translatedTitle)
+}
+
+func handleConfigForumRenameCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	enabled := i.ApplicationCommandData().Options[0].Options[0].BoolValue()
+
+	value := ""
+	if enabled {
+		value = "on"
+	}
+	err := setGuildSetting(i.GuildID, settingForumRename, value)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to update forum title handling: %s", err.Error()),
+		})
+		return
+	}
+
+	responseContent := "Translated forum post titles will be included in the first reply."
+	if enabled {
+		responseContent = "Forum posts will be renamed to include their translated title."
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
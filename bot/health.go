@@ -0,0 +1,95 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// checkedPermissions are the permissions the bot may need in a channel, in
+// the order they are reported.
+var checkedPermissions = []struct {
+	permission int64
+	name       string
+}{
+	{discordgo.PermissionViewChannel, "View Channel"},
+	{discordgo.PermissionSendMessages, "Send Messages"},
+	{discordgo.PermissionEmbedLinks, "Embed Links"},
+	{discordgo.PermissionManageWebhooks, "Manage Webhooks"},
+	{discordgo.PermissionSendMessagesInThreads, "Send Messages in Threads"},
+	{discordgo.PermissionCreatePublicThreads, "Create Public Threads"},
+}
+
+var (
+	unhealthyMu sync.Mutex
+	// unhealthyChannels maps channels the bot can't post in to the
+	// permissions it is missing there.
+	unhealthyChannels = make(map[string]string)
+)
+
+// missingPermissions returns the names of the required permissions the bot
+// lacks in the channel. Permissions that can't be computed from the state
+// cache are assumed to be present.
+func missingPermissions(s *discordgo.Session, channelID string, required int64) []string {
+	permissions, err := s.State.UserChannelPermissions(s.State.User.ID, channelID)
+	if err != nil {
+		return nil
+	}
+
+	var missing []string
+	for _, checked := range checkedPermissions {
+		if required&checked.permission != 0 && permissions&checked.permission == 0 {
+			missing = append(missing, checked.name)
+		}
+	}
+	return missing
+}
+
+// channelWritable reports whether the bot has the required permissions in the
+// channel. A channel that lacks them is marked unhealthy and admins are
+// notified once; it recovers as soon as the permissions are granted.
+func channelWritable(s *discordgo.Session, channelID string, required int64) bool {
+	missing := missingPermissions(s, channelID, required)
+	if len(missing) == 0 {
+		unhealthyMu.Lock()
+		delete(unhealthyChannels, channelID)
+		unhealthyMu.Unlock()
+		return true
+	}
+
+	markUnhealthy(s, channelID, strings.Join(missing, ", "))
+	return false
+}
+
+// markUnhealthy records that the bot can't post in the channel, notifying the
+// guild's admins the first time.
+func markUnhealthy(s *discordgo.Session, channelID, reason string) {
+	unhealthyMu.Lock()
+	_, known := unhealthyChannels[channelID]
+	unhealthyChannels[channelID] = reason
+	unhealthyMu.Unlock()
+	if known {
+		return
+	}
+
+	log.Printf("Channel %s is unhealthy: %s", channelID, reason)
+	channel, err := s.State.Channel(channelID)
+	if err != nil {
+		return
+	}
+	notifyAdmins(s, channel.GuildID, fmt.Sprintf("I can't post in <#%s> because I'm missing permissions: %s. Translations for it are skipped until this is fixed.", channelID, reason))
+}
+
+// isMissingPermissions reports whether Discord rejected a request because
+// the bot lacks access or permissions.
+func isMissingPermissions(err error) bool {
+	var restErr *discordgo.RESTError
+	if !errors.As(err, &restErr) || restErr.Message == nil {
+		return false
+	}
+	return restErr.Message.Code == discordgo.ErrCodeMissingPermissions || restErr.Message.Code == discordgo.ErrCodeMissingAccess
+}
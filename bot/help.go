@@ -0,0 +1,112 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const projectURL = "https://github.com/YoungKru-D/Translate-bot-Discord"
+
+// hiddenSettings are guild settings that are secret or internal bookkeeping
+// and therefore left out of /help.
+var hiddenSettings = map[string]bool{
+	settingAPIKey:         true,
+	settingLicense:        true,
+	settingDigestLastSent: true,
+	settingRulesText:      true,
+	settingRulesMessages:  true,
+	settingOnboarded:      true,
+	settingWebhookURL:     true,
+	settingArchiveJob:     true,
+}
+
+// commandHelp lists every command and subcommand from the command
+// definitions, so the help text can't drift from what is registered.
+func commandHelp() string {
+	var lines []string
+	for _, command := range commandDefinitions() {
+		switch command.Type {
+		case discordgo.UserApplicationCommand:
+			lines = append(lines, fmt.Sprintf("**Apps → %s** (on a member)", command.Name))
+			continue
+		case discordgo.MessageApplicationCommand:
+			lines = append(lines, fmt.Sprintf("**Apps → %s** (on a message)", command.Name))
+			continue
+		}
+
+		var subCommands []string
+		for _, option := range command.Options {
+			switch option.Type {
+			case discordgo.ApplicationCommandOptionSubCommand:
+				subCommands = append(subCommands, option.Name)
+			case discordgo.ApplicationCommandOptionSubCommandGroup:
+				for _, subCommand := range option.Options {
+					subCommands = append(subCommands, option.Name+" "+subCommand.Name)
+				}
+			}
+		}
+		line := fmt.Sprintf("**/%s** — %s", command.Name, command.Description)
+		if len(subCommands) > 0 {
+			line += fmt.Sprintf(" (`%s`)", strings.Join(subCommands, "`, `"))
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// settingsHelp summarizes the server's current configuration.
+func settingsHelp(serverID string) string {
+	var lines []string
+
+	var channels []string
+	for _, channelID := range translateChannels[serverID] {
+		if channelID != "" {
+			channels = append(channels, fmt.Sprintf("<#%s>", channelID))
+		}
+	}
+	if len(channels) == 0 {
+		channels = []string{"none"}
+	}
+	lines = append(lines, "Translated channels: "+strings.Join(channels, ", "))
+	lines = append(lines, fmt.Sprintf("Target language: %s %s", languageFlag(guildTargetLanguage(serverID)), guildTargetLanguage(serverID)))
+	lines = append(lines, "Backend: "+guildBackend(serverID).Name())
+
+	guild := settings.GuildAll(serverID)
+	var keys []string
+	for key := range guild {
+		if !hiddenSettings[key] && key != settingTargetLanguage {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := guild[key]
+		if len([]rune(value)) > 50 {
+			value = string([]rune(value)[:49]) + "…"
+		}
+		lines = append(lines, fmt.Sprintf("`%s`: %s", key, value))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func handleHelpCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	respond(s, i, &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{
+			{
+				Title:       "Commands",
+				Description: commandHelp(),
+			},
+			{
+				Title:       "This server",
+				Description: settingsHelp(i.GuildID),
+			},
+			{
+				Title:       "Links",
+				Description: fmt.Sprintf("[Source code and documentation](%s)\n[Report a problem](%s/issues)", projectURL, projectURL),
+			},
+		},
+	})
+}
@@ -0,0 +1,32 @@
+package bot
+
+import (
+	"log"
+	"os"
+
+	"github.com/bwmarrin/discordgo"
+
+	"translate-bot/storage"
+)
+
+// recordHistory stores the message and what was posted for it, so it can be
+// replayed later. Message contents are only kept when the host opts in with
+// MESSAGE_HISTORY.
+func recordHistory(m *discordgo.MessageCreate, output string) {
+	if os.Getenv("MESSAGE_HISTORY") == "" {
+		return
+	}
+	err := store.RecordMessage(storage.HistoryMessage{
+		MessageID:  m.ID,
+		ServerID:   m.GuildID,
+		ChannelID:  m.ChannelID,
+		AuthorID:   m.Author.ID,
+		AuthorName: m.Author.Username,
+		Content:    m.Content,
+		Output:     output,
+		CreatedAt:  m.Timestamp,
+	})
+	if err != nil {
+		log.Println("Error recording message history,", err)
+	}
+}
@@ -0,0 +1,177 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"go.starlark.net/starlark"
+)
+
+// hookTimeout bounds how long a message hook may take before the translation
+// is posted unchanged.
+const hookTimeout = 5 * time.Second
+
+// hookFunction is the function a message hook script defines. It is called
+// with a dict describing the translation.
+const hookFunction = "on_translation"
+
+// messageHook is the hook function of the MESSAGE_HOOK script, or nil when
+// no hook is set.
+var messageHook starlark.Callable
+
+// hookInput describes a translation to the message hook.
+type hookInput struct {
+	GuildID     string
+	ChannelID   string
+	AuthorID    string
+	Content     string
+	SourceLang  string
+	TargetLang  string
+	Translation string
+}
+
+// hookOutput is what the message hook decided. Empty fields leave the
+// translation as it is.
+type hookOutput struct {
+	Skip        bool
+	Translation string
+	ChannelID   string
+}
+
+// value returns the dict the hook function is called with.
+func (in hookInput) value() *starlark.Dict {
+	dict := starlark.NewDict(7)
+	for key, value := range map[string]string{
+		"guild_id":    in.GuildID,
+		"channel_id":  in.ChannelID,
+		"author_id":   in.AuthorID,
+		"content":     in.Content,
+		"source_lang": in.SourceLang,
+		"target_lang": in.TargetLang,
+		"translation": in.Translation,
+	} {
+		dict.SetKey(starlark.String(key), starlark.String(value))
+	}
+	return dict
+}
+
+// parseHookOutput reads the hook function's result: None, or a dict with any
+// of the skip, translation and channel_id keys.
+func parseHookOutput(result starlark.Value) (hookOutput, error) {
+	var output hookOutput
+	if result == starlark.None {
+		return output, nil
+	}
+	dict, ok := result.(*starlark.Dict)
+	if !ok {
+		return output, fmt.Errorf("%s returned a %s, want a dict or None", hookFunction, result.Type())
+	}
+	for _, item := range dict.Items() {
+		key, _ := starlark.AsString(item[0])
+		switch key {
+		case "skip":
+			output.Skip = bool(item[1].Truth())
+		case "translation", "channel_id":
+			value, ok := starlark.AsString(item[1])
+			if !ok {
+				return output, fmt.Errorf("%s returned a %s for %s, want a string", hookFunction, item[1].Type(), key)
+			}
+			if key == "translation" {
+				output.Translation = value
+			} else {
+				output.ChannelID = value
+			}
+		default:
+			return output, fmt.Errorf("%s returned unknown key %s", hookFunction, item[0])
+		}
+	}
+	return output, nil
+}
+
+// newHookThread returns a thread to run the message hook on, which is
+// canceled once hookTimeout has passed. The returned function stops the
+// timer.
+func newHookThread() (*starlark.Thread, func() bool) {
+	thread := &starlark.Thread{
+		Name: "message hook",
+		Print: func(_ *starlark.Thread, msg string) {
+			log.Println("Message hook:", msg)
+		},
+	}
+	timer := time.AfterFunc(hookTimeout, func() {
+		thread.Cancel(fmt.Sprintf("took longer than %s", hookTimeout))
+	})
+	return thread, timer.Stop
+}
+
+// loadMessageHook loads the Starlark script MESSAGE_HOOK points at. The
+// script is embedded in the bot rather than run as a program of its own, so
+// calling it costs little more than a function call. It is meant for
+// site-specific rules that don't belong in the bot.
+func loadMessageHook() error {
+	path := os.Getenv("MESSAGE_HOOK")
+	if path == "" {
+		messageHook = nil
+		return nil
+	}
+
+	thread, stop := newHookThread()
+	defer stop()
+	globals, err := starlark.ExecFile(thread, path, nil, nil)
+	if err != nil {
+		return fmt.Errorf("loading message hook: %w", err)
+	}
+	hook, ok := globals[hookFunction].(starlark.Callable)
+	if !ok {
+		return fmt.Errorf("message hook %s doesn't define %s(message)", path, hookFunction)
+	}
+	messageHook = hook
+	return nil
+}
+
+// runMessageHook calls the message hook for a translation. A hook that takes
+// longer than hookTimeout is canceled.
+func runMessageHook(input hookInput) (hookOutput, error) {
+	if messageHook == nil {
+		return hookOutput{}, nil
+	}
+
+	thread, stop := newHookThread()
+	defer stop()
+	result, err := starlark.Call(thread, messageHook, starlark.Tuple{input.value()}, nil)
+	if err != nil {
+		return hookOutput{}, err
+	}
+	return parseHookOutput(result)
+}
+
+// hookStage lets the message hook skip, rewrite or reroute a translation.
+func hookStage(p *pipelineMessage) bool {
+	output, err := runMessageHook(hookInput{
+		GuildID:     p.m.GuildID,
+		ChannelID:   p.m.ChannelID,
+		AuthorID:    p.m.Author.ID,
+		Content:     p.m.Content,
+		SourceLang:  p.sourceLang,
+		TargetLang:  guildTargetLanguage(p.m.GuildID),
+		Translation: p.translated,
+	})
+	if err != nil {
+		log.Println("Error running message hook,", err)
+		return true
+	}
+
+	if output.Skip {
+		reportSkipped(p.s, p.m, "skipped by the message hook")
+		return false
+	}
+	if output.Translation != "" {
+		p.translated = output.Translation
+	}
+	if output.ChannelID != "" {
+		p.channelID = output.ChannelID
+	}
+	return true
+}
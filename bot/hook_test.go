@@ -0,0 +1,85 @@
+package bot
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setMessageHook loads the script as the message hook.
+func setMessageHook(t *testing.T, script string) error {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "hook.star")
+	if err := os.WriteFile(path, []byte(script), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("MESSAGE_HOOK", path)
+	t.Cleanup(func() { messageHook = nil })
+	return loadMessageHook()
+}
+
+func TestRunMessageHook(t *testing.T) {
+	err := setMessageHook(t, `
+def on_translation(message):
+    if message["author_id"] == "spammer":
+        return {"skip": True}
+    if message["channel_id"] == "news":
+        return {"channel_id": "news-" + message["target_lang"]}
+    if "colour" in message["translation"]:
+        return {"translation": message["translation"].replace("colour", "color")}
+    return None
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input hookInput
+		want  hookOutput
+	}{
+		{hookInput{AuthorID: "spammer", Translation: "hi"}, hookOutput{Skip: true}},
+		{hookInput{ChannelID: "news", TargetLang: "en"}, hookOutput{ChannelID: "news-en"}},
+		{hookInput{Translation: "the colour red"}, hookOutput{Translation: "the color red"}},
+		{hookInput{Translation: "hello"}, hookOutput{}},
+	}
+	for _, test := range tests {
+		got, err := runMessageHook(test.input)
+		if err != nil || got != test.want {
+			t.Errorf("runMessageHook(%+v) = %+v, %v, want %+v", test.input, got, err, test.want)
+		}
+	}
+}
+
+func TestRunMessageHookErrors(t *testing.T) {
+	tests := []struct {
+		script string
+		want   string
+	}{
+		{"def on_translation(message):\n    return 1\n", "want a dict or None"},
+		{"def on_translation(message):\n    return {\"skipped\": True}\n", "unknown key"},
+		{"def on_translation(message):\n    return {\"translation\": 1}\n", "want a string"},
+		{"def on_translation(message):\n    return message[\"missing\"]\n", "missing"},
+	}
+	for _, test := range tests {
+		if err := setMessageHook(t, test.script); err != nil {
+			t.Fatal(err)
+		}
+		_, err := runMessageHook(hookInput{})
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("runMessageHook() with %q = %v, want an error about %q", test.script, err, test.want)
+		}
+	}
+}
+
+func TestLoadMessageHookErrors(t *testing.T) {
+	for _, script := range []string{
+		"def on_message(message):\n    return None\n",
+		"def on_translation(message)\n",
+		"on_translation = 1\n",
+	} {
+		if err := setMessageHook(t, script); err == nil {
+			t.Errorf("loading %q succeeded, want an error", script)
+		}
+	}
+}
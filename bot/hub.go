@@ -0,0 +1,255 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+
+	"translate-bot/storage"
+)
+
+// hubWebhookName is the name of the webhooks the bot creates to post routed
+// translations under their author's name.
+const hubWebhookName = "Translate Bot"
+
+// maxHubChannels is the number of language channels /route hub accepts at
+// once. Running it again adds more.
+const maxHubChannels = 5
+
+var (
+	channelWebhooksMu sync.Mutex
+	// channelWebhooks caches the bot's webhook for each channel it posts to
+	// as its authors.
+	channelWebhooks = make(map[string]*discordgo.Webhook)
+)
+
+// channelWebhook returns the bot's webhook in the channel, creating it the
+// first time.
+func channelWebhook(s *discordgo.Session, channelID string) (*discordgo.Webhook, error) {
+	channelWebhooksMu.Lock()
+	defer channelWebhooksMu.Unlock()
+
+	if webhook, ok := channelWebhooks[channelID]; ok {
+		return webhook, nil
+	}
+
+	webhooks, err := s.ChannelWebhooks(channelID)
+	if err != nil {
+		return nil, err
+	}
+	for _, webhook := range webhooks {
+		if webhook.User != nil && webhook.User.ID == s.State.User.ID && webhook.Token != "" {
+			channelWebhooks[channelID] = webhook
+			return webhook, nil
+		}
+	}
+
+	webhook, err := s.WebhookCreate(channelID, hubWebhookName, "")
+	if err != nil {
+		return nil, err
+	}
+	channelWebhooks[channelID] = webhook
+	return webhook, nil
+}
+
+func forgetChannelWebhook(channelID string) {
+	channelWebhooksMu.Lock()
+	delete(channelWebhooks, channelID)
+	channelWebhooksMu.Unlock()
+}
+
+// postAsAuthor posts a translation through a webhook that carries the
+// author's name and avatar. Threads are posted to through their parent's
+// webhook. When the webhook can't be used the translation is posted by the
+// bot instead, attributed in the text. Unless sent is nil, it is called with
+// the posted message.
+func postAsAuthor(s *discordgo.Session, m *discordgo.MessageCreate, channelID, content string, sent func(message *discordgo.Message)) {
+	if links, ok := editLinks(m.ID); ok {
+		editAsAuthor(s, m, links, channelID, content)
+		return
+	}
+	if isDryRun(m.GuildID) || simulatedPost != nil {
+		postTranslationThen(s, m, channelID, fmt.Sprintf("**%s**: %s", authorName(m, false), content), sent)
+		return
+	}
+
+	webhookChannelID, threadID := channelID, ""
+	if channel, err := lookupChannel(s, channelID); err == nil && channel.IsThread() {
+		webhookChannelID, threadID = channel.ParentID, channelID
+	}
+
+	params := &discordgo.WebhookParams{
+		Content:         content,
+		Username:        webhookUsername(m),
+		AvatarURL:       m.Author.AvatarURL(""),
+		AllowedMentions: translationMentions,
+	}
+	var message *discordgo.Message
+	webhook, err := channelWebhook(s, webhookChannelID)
+	if err == nil {
+		if threadID != "" {
+			message, err = s.WebhookThreadExecute(webhook.ID, webhook.Token, true, threadID, params)
+		} else {
+			message, err = s.WebhookExecute(webhook.ID, webhook.Token, true, params)
+		}
+		if err != nil {
+			// The webhook may have been deleted; look it up again next time.
+			forgetChannelWebhook(webhookChannelID)
+		}
+	}
+	if err != nil {
+		log.Println("Error posting through webhook,", err)
+		postTranslationThen(s, m, channelID, fmt.Sprintf("**%s**: %s", authorName(m, false), content), sent)
+		return
+	}
+	if sent != nil {
+		sent(message)
+	}
+
+	fireEvent(webhookEvent{
+		Event:     eventTranslation,
+		GuildID:   m.GuildID,
+		ChannelID: m.ChannelID,
+		MessageID: m.ID,
+		UserID:    m.Author.ID,
+		Content:   content,
+	})
+}
+
+// editAsAuthor replaces a translation posted through a webhook. Translations
+// the bot posted itself when the webhook failed are edited directly.
+func editAsAuthor(s *discordgo.Session, m *discordgo.MessageCreate, links []messageLink, channelID, content string) {
+	if isDryRun(m.GuildID) || simulatedPost != nil {
+		editTranslation(s, m, links, channelID, fmt.Sprintf("**%s**: %s", authorName(m, false), content))
+		return
+	}
+
+	webhookChannelID, threadID := channelID, ""
+	if channel, err := lookupChannel(s, channelID); err == nil && channel.IsThread() {
+		webhookChannelID, threadID = channel.ParentID, channelID
+	}
+	webhook, err := channelWebhook(s, webhookChannelID)
+	if err != nil {
+		log.Println("Error editing through webhook,", err)
+		return
+	}
+
+	for _, link := range links {
+		if link.channelID != channelID {
+			continue
+		}
+		var options []discordgo.RequestOption
+		if threadID != "" {
+			options = append(options, withThread(threadID))
+		}
+		_, err := s.WebhookMessageEdit(webhook.ID, webhook.Token, link.messageID, &discordgo.WebhookEdit{
+			Content:         &content,
+			AllowedMentions: translationMentions,
+		}, options...)
+		if err != nil {
+			editTranslation(s, m, links, channelID, fmt.Sprintf("**%s**: %s", authorName(m, false), content))
+		}
+		return
+	}
+}
+
+// withThread addresses a webhook request to a thread of the webhook's
+// channel.
+func withThread(threadID string) discordgo.RequestOption {
+	return func(cfg *discordgo.RequestConfig) {
+		query := cfg.Request.URL.Query()
+		query.Set("thread_id", threadID)
+		cfg.Request.URL.RawQuery = query.Encode()
+	}
+}
+
+// webhookUsername returns the name a webhook post is shown under: the
+// author's server nickname or display name, which Discord limits to 80
+// characters.
+func webhookUsername(m *discordgo.MessageCreate) string {
+	name := m.Author.GlobalName
+	if m.Member != nil && m.Member.Nick != "" {
+		name = m.Member.Nick
+	}
+	if name == "" {
+		name = m.Author.Username
+	}
+	if runes := []rune(name); len(runes) > 80 {
+		name = string(runes[:80])
+	}
+	return name
+}
+
+// handleRouteHubCommand fans a hub channel out into language channels: each
+// message in the hub is translated into every channel's language and posted
+// there under its author's name and avatar.
+func handleRouteHubCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var hubChannelID string
+	channels := make([]string, maxHubChannels)
+	languages := make([]string, maxHubChannels)
+	for _, option := range i.ApplicationCommandData().Options[0].Options {
+		if option.Name == "hub" {
+			hubChannelID = option.ChannelValue(s).ID
+			continue
+		}
+		var index int
+		if _, err := fmt.Sscanf(option.Name, "channel%d", &index); err == nil && index >= 1 && index <= maxHubChannels {
+			channels[index-1] = option.ChannelValue(s).ID
+		} else if _, err := fmt.Sscanf(option.Name, "language%d", &index); err == nil && index >= 1 && index <= maxHubChannels {
+			languages[index-1] = strings.ToLower(strings.TrimSpace(option.StringValue()))
+		}
+	}
+
+	for index, channelID := range channels {
+		if channelID == "" {
+			continue
+		}
+		if languages[index] == "" {
+			respond(s, i, &discordgo.InteractionResponseData{
+				Content: fmt.Sprintf("Error: channel%d needs a language%d.", index+1, index+1),
+			})
+			return
+		}
+		if channelID == hubChannelID {
+			respond(s, i, &discordgo.InteractionResponseData{
+				Content: "Error: The hub can't be one of its own language channels.",
+			})
+			return
+		}
+	}
+
+	var lines []string
+	for index, channelID := range channels {
+		if channelID == "" {
+			continue
+		}
+		err := setRoute(storage.Route{
+			ServerID:             i.GuildID,
+			SourceChannelID:      hubChannelID,
+			DestinationChannelID: channelID,
+			TargetLang:           languages[index],
+			AuthorMode:           routeAuthorWebhook,
+		})
+		if err != nil {
+			respond(s, i, &discordgo.InteractionResponseData{
+				Content: fmt.Sprintf("Failed to add hub channel: %s", err.Error()),
+			})
+			return
+		}
+		lines = append(lines, fmt.Sprintf("→ <#%s> %s %s", channelID, languageFlag(languages[index]), languages[index]))
+	}
+
+	responseContent := fmt.Sprintf("Messages in <#%s> are translated into:\n%s", hubChannelID, strings.Join(lines, "\n"))
+	for _, channelID := range channels {
+		if channelID != "" && len(missingPermissions(s, channelID, discordgo.PermissionManageWebhooks)) > 0 {
+			responseContent += "\nGrant the bot Manage Webhooks in the language channels so translations show their author's name and avatar."
+			break
+		}
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
@@ -0,0 +1,164 @@
+package bot
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"translate-bot/redis"
+	"translate-bot/storage"
+)
+
+// Kinds of configuration that instances cache in memory and reload when
+// another instance changes them.
+const (
+	changeBannedWords    = "banned_words"
+	changeChannels       = "channels"
+	changeRoutes         = "routes"
+	changeSubscriptions  = "subscriptions"
+	changeBotSetting     = "bot_setting"
+	changeGuildSetting   = "guild_setting"
+	changeChannelSetting = "channel_setting"
+)
+
+// changesChannel is the Redis pub/sub channel changes are announced on.
+const changesChannel = "translate-bot:changes"
+
+// reloaders reload a kind of cached configuration from the store.
+var reloaders = map[string]func() error{
+	changeBannedWords:   loadBannedWords,
+	changeChannels:      loadTranslateChannels,
+	changeRoutes:        loadRoutes,
+	changeSubscriptions: loadSubscriptions,
+}
+
+// change announces that an instance changed configuration. Settings carry
+// their new value, so other instances don't have to reload all settings.
+type change struct {
+	Instance  string `json:"instance"`
+	Kind      string `json:"kind"`
+	ServerID  string `json:"server_id,omitempty"`
+	ChannelID string `json:"channel_id,omitempty"`
+	Key       string `json:"key,omitempty"`
+	Value     string `json:"value,omitempty"`
+}
+
+var (
+	// sharedRedis connects the instances of the bot, or is nil when only
+	// one instance runs.
+	sharedRedis *redis.Client
+	// instanceID tells this instance's announcements apart from others'.
+	instanceID string
+)
+
+// initRedis connects to REDIS_URL, when set, and starts listening for
+// changes other instances make.
+func initRedis() error {
+	if err := connectRedis(); err != nil || sharedRedis == nil {
+		return err
+	}
+	go sharedRedis.Subscribe(changesChannel, reloadAll, applyChange)
+	return nil
+}
+
+// connectRedis connects to REDIS_URL, when set, so changes are announced to
+// the running instances.
+func connectRedis() error {
+	redisURL := os.Getenv("REDIS_URL")
+	if redisURL == "" {
+		return nil
+	}
+	client, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return err
+	}
+	id := make([]byte, 8)
+	rand.Read(id)
+	sharedRedis, instanceID = client, hex.EncodeToString(id)
+	return nil
+}
+
+// reloadOnHangup reloads every cache whenever the process receives SIGHUP,
+// so changes made from the command line reach instances without Redis.
+func reloadOnHangup() {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	for range hangup {
+		log.Println("Reloading configuration")
+		reloadAll()
+	}
+}
+
+// reloadShared reloads the kind of configuration from the store and tells
+// the other instances to do the same.
+func reloadShared(kind string) error {
+	if err := reloaders[kind](); err != nil {
+		return err
+	}
+	publishChange(change{Kind: kind})
+	return nil
+}
+
+func publishChange(c change) {
+	if sharedRedis == nil {
+		return
+	}
+	c.Instance = instanceID
+	message, err := json.Marshal(c)
+	if err != nil {
+		log.Println("Error encoding change,", err)
+		return
+	}
+	if err := sharedRedis.Publish(changesChannel, string(message)); err != nil {
+		log.Println("Error announcing change,", err)
+	}
+}
+
+// applyChange updates the cache for a change another instance announced.
+func applyChange(message string) {
+	var c change
+	if err := json.Unmarshal([]byte(message), &c); err != nil {
+		log.Println("Error decoding change,", err)
+		return
+	}
+	if c.Instance == instanceID {
+		return
+	}
+
+	switch c.Kind {
+	case changeBotSetting:
+		settings.CacheBot(c.Key, c.Value)
+	case changeGuildSetting:
+		settings.CacheGuild(c.ServerID, c.Key, c.Value)
+	case changeChannelSetting:
+		settings.CacheChannel(c.ChannelID, c.Key, c.Value)
+	default:
+		reload, ok := reloaders[c.Kind]
+		if !ok {
+			return
+		}
+		if err := reload(); err != nil {
+			log.Printf("Error reloading %s, %s", c.Kind, err)
+		}
+	}
+}
+
+// reloadAll reloads every cache after (re)subscribing, since changes
+// announced while the subscription was down are lost.
+func reloadAll() {
+	loaded, err := storage.LoadSettings(store)
+	if err != nil {
+		log.Println("Error reloading settings,", err)
+	} else {
+		settings = loaded
+	}
+	for kind, reload := range reloaders {
+		if err := reload(); err != nil {
+			log.Printf("Error reloading %s, %s", kind, err)
+		}
+	}
+}
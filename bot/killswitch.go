@@ -0,0 +1,258 @@
+package bot
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// The bot owner can pause translation for all guilds or for one, to stop
+// abuse or runaway backend costs without a restart. Pauses are stored as
+// settings, a guild's with the guild and the pause of all guilds with the
+// bot's, so they reach every instance right away and outlast restarts. They are set with /owner or, with OWNER_TOKEN set, through the
+// metrics server:
+//
+//	curl -X POST -H "Authorization: Bearer $OWNER_TOKEN" "$METRICS_ADDR/pause?guild=<id>&reason=spam"
+//	curl -X DELETE -H "Authorization: Bearer $OWNER_TOKEN" "$METRICS_ADDR/pause?guild=<id>"
+//
+// Leaving out the guild pauses or resumes all guilds. A GET lists the pauses.
+
+// globalPauseID stands for all guilds in the list of pauses. Guild IDs are
+// numeric, so it can't clash with one. Older versions stored the pause of
+// all guilds as the settings of a guild with this ID.
+const globalPauseID = "global"
+
+// errTranslationPaused is returned instead of translating while translation
+// is paused.
+var errTranslationPaused = errors.New("translation is paused by the bot owner")
+
+var (
+	ownersMu sync.Mutex
+	// owners holds the IDs of the users allowed to use /owner.
+	owners = make(map[string]bool)
+)
+
+func init() {
+	http.HandleFunc("/pause", servePause)
+}
+
+// loadOwners reads the bot owners from OWNER_IDS, a comma separated list of
+// user IDs, or else from the application: its owner, or the members of the
+// team owning it.
+func loadOwners(s *discordgo.Session) error {
+	ids := strings.FieldsFunc(os.Getenv("OWNER_IDS"), func(r rune) bool { return r == ',' || r == ' ' })
+	if len(ids) == 0 {
+		application, err := s.Application("@me")
+		if err != nil {
+			return fmt.Errorf("error looking up the bot owner: %w", err)
+		}
+		switch {
+		case application.Team != nil:
+			for _, member := range application.Team.Members {
+				ids = append(ids, member.User.ID)
+			}
+		case application.Owner != nil:
+			ids = append(ids, application.Owner.ID)
+		}
+	}
+
+	ownersMu.Lock()
+	defer ownersMu.Unlock()
+	for _, id := range ids {
+		owners[id] = true
+	}
+	return nil
+}
+
+func isOwner(userID string) bool {
+	ownersMu.Lock()
+	defer ownersMu.Unlock()
+	return owners[userID]
+}
+
+// pauseReason returns why translation is paused for the guild, and whether
+// it is. A pause of all guilds comes first.
+func pauseReason(guildID string) (string, bool) {
+	if reason := getBotSetting(settingPaused); reason != "" {
+		return reason, true
+	}
+	if guildID == "" {
+		return "", false
+	}
+	reason := getGuildSetting(guildID, settingPaused)
+	return reason, reason != ""
+}
+
+// setPaused pauses or resumes translation for the guild, or for all guilds
+// when the guild ID is empty.
+func setPaused(guildID, reason string, paused bool) error {
+	value := ""
+	if paused {
+		value = strings.TrimSpace(reason)
+		if value == "" {
+			value = "no reason given"
+		}
+	}
+	var err error
+	if guildID == "" {
+		err = setBotSetting(settingPaused, value)
+	} else {
+		err = setGuildSetting(guildID, settingPaused, value)
+	}
+	if err != nil {
+		return err
+	}
+	if paused {
+		log.Printf("Translation paused for %s: %s", pauseScope(guildID), value)
+	} else {
+		log.Printf("Translation resumed for %s", pauseScope(guildID))
+	}
+	return nil
+}
+
+// pausedGuilds returns the reason for every pause by guild ID, with
+// globalPauseID standing for all guilds.
+func pausedGuilds() map[string]string {
+	paused := make(map[string]string)
+	if reason := getBotSetting(settingPaused); reason != "" {
+		paused[globalPauseID] = reason
+	}
+	for guildID, guild := range settings.Guilds() {
+		if reason := guild[settingPaused]; reason != "" {
+			paused[guildID] = reason
+		}
+	}
+	return paused
+}
+
+// migrateGlobalPause moves a pause of all guilds stored by an older version
+// as the settings of globalPauseID to the bot's settings, where loops over
+// the guilds don't take it for a guild.
+func migrateGlobalPause() error {
+	reason := getGuildSetting(globalPauseID, settingPaused)
+	if reason == "" {
+		return nil
+	}
+	if getBotSetting(settingPaused) == "" {
+		if err := setBotSetting(settingPaused, reason); err != nil {
+			return err
+		}
+	}
+	return setGuildSetting(globalPauseID, settingPaused, "")
+}
+
+func pauseScope(guildID string) string {
+	if guildID == "" || guildID == globalPauseID {
+		return "all guilds"
+	}
+	return "guild " + guildID
+}
+
+// pauseStage drops messages while translation is paused. They aren't
+// reported as skipped, since a pause against abuse would flood the skip log.
+func pauseStage(p *pipelineMessage) bool {
+	_, paused := pauseReason(p.m.GuildID)
+	return !paused
+}
+
+// servePause lists, sets and lifts pauses for holders of OWNER_TOKEN. It
+// doesn't exist without one.
+func servePause(w http.ResponseWriter, r *http.Request) {
+	token := os.Getenv("OWNER_TOKEN")
+	if token == "" {
+		http.NotFound(w, r)
+		return
+	}
+	given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	guildID := r.URL.Query().Get("guild")
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		err = setPaused(guildID, r.URL.Query().Get("reason"), true)
+	case http.MethodDelete:
+		err = setPaused(guildID, "", false)
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pausedGuilds())
+}
+
+func handleOwnerCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	userID := ""
+	if i.Member != nil {
+		userID = i.Member.User.ID
+	} else if i.User != nil {
+		userID = i.User.ID
+	}
+	if !isOwner(userID) {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "Only the bot owner can use this command.",
+		})
+		return
+	}
+
+	subCommand := i.ApplicationCommandData().Options[0]
+	var guildID, reason string
+	for _, option := range subCommand.Options {
+		switch option.Name {
+		case "guild":
+			guildID = strings.TrimSpace(option.StringValue())
+		case "reason":
+			reason = option.StringValue()
+		}
+	}
+
+	var responseContent string
+	switch subCommand.Name {
+	case "pause":
+		if err := setPaused(guildID, reason, true); err != nil {
+			responseContent = fmt.Sprintf("Failed to pause translation: %s", err.Error())
+			break
+		}
+		responseContent = fmt.Sprintf("⏸️ Translation is paused for %s. Resume it with `/owner resume`.", pauseScope(guildID))
+	case "resume":
+		if err := setPaused(guildID, "", false); err != nil {
+			responseContent = fmt.Sprintf("Failed to resume translation: %s", err.Error())
+			break
+		}
+		responseContent = fmt.Sprintf("▶️ Translation is resumed for %s.", pauseScope(guildID))
+		if reason, paused := pauseReason(guildID); paused && guildID != "" {
+			responseContent += fmt.Sprintf(" All guilds are still paused, though (%s).", reason)
+		}
+	case "status":
+		paused := pausedGuilds()
+		if len(paused) == 0 {
+			responseContent = "Translation isn't paused anywhere."
+			break
+		}
+		var lines []string
+		for _, guildID := range sortedKeys(paused) {
+			lines = append(lines, fmt.Sprintf("• %s: %s", pauseScope(guildID), paused[guildID]))
+		}
+		responseContent = "Translation is paused for:\n" + strings.Join(lines, "\n")
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
@@ -0,0 +1,75 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestPause(t *testing.T) {
+	initTestStore(t)
+
+	if err := setPaused("1", "spam", true); err != nil {
+		t.Fatal(err)
+	}
+	if reason, paused := pauseReason("1"); !paused || reason != "spam" {
+		t.Errorf("pauseReason(1) = %q, %t, want paused for spam", reason, paused)
+	}
+	if _, paused := pauseReason("2"); paused {
+		t.Error("pausing one guild paused another")
+	}
+
+	if err := setPaused("", "", true); err != nil {
+		t.Fatal(err)
+	}
+	if reason, paused := pauseReason("2"); !paused || reason != "no reason given" {
+		t.Errorf("pauseReason(2) = %q, %t, want paused for all guilds", reason, paused)
+	}
+	if _, ok := settings.Guilds()[globalPauseID]; ok {
+		t.Error("the pause of all guilds is stored as a guild's setting")
+	}
+	if paused := pausedGuilds(); len(paused) != 2 || paused[globalPauseID] == "" || paused["1"] != "spam" {
+		t.Errorf("pausedGuilds() = %v, want all guilds and guild 1", paused)
+	}
+
+	if err := setPaused("", "", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, paused := pauseReason("2"); paused {
+		t.Error("guild 2 is still paused after resuming all guilds")
+	}
+	if _, paused := pauseReason("1"); !paused {
+		t.Error("resuming all guilds resumed a guild paused on its own")
+	}
+}
+
+func TestPauseStage(t *testing.T) {
+	initTestStore(t)
+	if err := setPaused("1", "spam", true); err != nil {
+		t.Fatal(err)
+	}
+
+	for guildID, want := range map[string]bool{"1": false, "2": true} {
+		p := &pipelineMessage{m: &discordgo.MessageCreate{Message: &discordgo.Message{GuildID: guildID}}}
+		if got := pauseStage(p); got != want {
+			t.Errorf("pauseStage() in guild %s = %t, want %t", guildID, got, want)
+		}
+	}
+}
+
+func TestMigrateGlobalPause(t *testing.T) {
+	initTestStore(t)
+	if err := setGuildSetting(globalPauseID, settingPaused, "maintenance"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrateGlobalPause(); err != nil {
+		t.Fatal(err)
+	}
+	if reason, paused := pauseReason("1"); !paused || reason != "maintenance" {
+		t.Errorf("pauseReason(1) = %q, %t, want paused for maintenance", reason, paused)
+	}
+	if _, ok := settings.Guilds()[globalPauseID]; ok {
+		t.Error("the old pause of all guilds is still a guild's setting")
+	}
+}
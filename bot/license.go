@@ -0,0 +1,121 @@
+package bot
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Premium features are gated per guild on hosted deployments. A guild unlocks
+// them by activating a license key issued by the hoster for that guild.
+const (
+	featureLLM   = "llm"
+	featureOCR   = "ocr"
+	featureVoice = "voice"
+)
+
+// licenseKey returns the key that unlocks premium features for the guild, or
+// an empty string when no LICENSE_SECRET is configured.
+func licenseKey(guildID string) string {
+	secret := os.Getenv("LICENSE_SECRET")
+	if secret == "" {
+		return ""
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(guildID))
+	return hex.EncodeToString(mac.Sum(nil))[:24]
+}
+
+// hasPremium reports whether the guild may use premium features. Without a
+// LICENSE_SECRET every guild has them, which is the self-hosted case.
+func hasPremium(guildID string) bool {
+	key := licenseKey(guildID)
+	if key == "" {
+		return true
+	}
+	return hmac.Equal([]byte(getGuildSetting(guildID, settingLicense)), []byte(key))
+}
+
+// requirePremium responds with an explanation and returns false when the
+// guild is not licensed for the feature.
+func requirePremium(s *discordgo.Session, i *discordgo.InteractionCreate, feature string) bool {
+	if hasPremium(i.GuildID) {
+		return true
+	}
+
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: fmt.Sprintf("The %s feature requires a license. Use /license activate to unlock it.", feature),
+	})
+	return false
+}
+
+// PrintLicenseKey is used by the "license" subcommand so hosters can issue
+// keys without starting the bot.
+func PrintLicenseKey(guildID string) {
+	key := licenseKey(guildID)
+	if key == "" {
+		fmt.Println("LICENSE_SECRET environment variable is not set.")
+		os.Exit(1)
+	}
+	fmt.Println(key)
+}
+
+func handleLicenseCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	subCommand := i.ApplicationCommandData().Options[0].Name
+
+	switch subCommand {
+	case "activate":
+		handleLicenseActivateCommand(s, i)
+	case "status":
+		handleLicenseStatusCommand(s, i)
+	}
+}
+
+func handleLicenseActivateCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	key := strings.TrimSpace(i.ApplicationCommandData().Options[0].Options[0].StringValue())
+	expected := licenseKey(i.GuildID)
+	if expected == "" {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "This bot does not require a license. All features are available.",
+		})
+		return
+	}
+
+	if !hmac.Equal([]byte(key), []byte(expected)) {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "Error: That license key is not valid for this server.",
+		})
+		return
+	}
+
+	err := setGuildSetting(i.GuildID, settingLicense, key)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to activate license: %s", err.Error()),
+		})
+		return
+	}
+
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: "License activated. Premium features are now available.",
+	})
+}
+
+func handleLicenseStatusCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	responseContent := "This server does not have a license. Premium features (LLM backend, OCR, voice) are unavailable."
+	if licenseKey(i.GuildID) == "" {
+		responseContent = "This bot does not require a license. All features are available."
+	} else if hasPremium(i.GuildID) {
+		responseContent = "This server is licensed. Premium features are available."
+	}
+
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
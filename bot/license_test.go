@@ -0,0 +1,50 @@
+package bot
+
+import "testing"
+
+func TestLicenseKey(t *testing.T) {
+	t.Setenv("LICENSE_SECRET", "")
+	if key := licenseKey("1"); key != "" {
+		t.Errorf("licenseKey() without LICENSE_SECRET = %q, want none", key)
+	}
+
+	t.Setenv("LICENSE_SECRET", "secret")
+	key := licenseKey("1")
+	if len(key) != 24 || key != licenseKey("1") {
+		t.Errorf("licenseKey(1) = %q, want a stable 24 character key", key)
+	}
+	if licenseKey("2") == key {
+		t.Error("two guilds got the same license key")
+	}
+	t.Setenv("LICENSE_SECRET", "other")
+	if licenseKey("1") == key {
+		t.Error("the license key didn't change with the secret")
+	}
+}
+
+func TestHasPremium(t *testing.T) {
+	initTestStore(t)
+
+	t.Setenv("LICENSE_SECRET", "")
+	if !hasPremium("1") {
+		t.Error("hasPremium() without LICENSE_SECRET = false, want every guild to have premium")
+	}
+
+	t.Setenv("LICENSE_SECRET", "secret")
+	if hasPremium("1") {
+		t.Error("hasPremium() without an activated license = true")
+	}
+	// Another guild's key doesn't unlock premium.
+	if err := setGuildSetting("1", settingLicense, licenseKey("2")); err != nil {
+		t.Fatal(err)
+	}
+	if hasPremium("1") {
+		t.Error("hasPremium() with another guild's key = true")
+	}
+	if err := setGuildSetting("1", settingLicense, licenseKey("1")); err != nil {
+		t.Fatal(err)
+	}
+	if !hasPremium("1") {
+		t.Error("hasPremium() with the guild's key = false")
+	}
+}
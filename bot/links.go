@@ -0,0 +1,128 @@
+package bot
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"translate-bot/storage"
+)
+
+// maxMessageLinks bounds how many messages' links are kept in memory. The
+// least recently added are forgotten first and looked up in the store again
+// when needed.
+const maxMessageLinks = 10000
+
+// linkRetention is how long message links are kept at most: no server's edit
+// window is longer, and reactions are mirrored between paired channels for
+// as long.
+const linkRetention = maxEditWindow * time.Minute
+
+// messageLink points from a message to its counterpart: the translation of
+// an original, or the original of a translation.
+type messageLink struct {
+	channelID string
+	messageID string
+}
+
+var (
+	messageLinksMu sync.Mutex
+	// messageLinks caches the links of recently linked or looked up
+	// messages, by message ID.
+	messageLinks = make(map[string][]storage.MessageLink)
+	// messageLinkOrder lists the cached message IDs, oldest first.
+	messageLinkOrder []string
+)
+
+// linkMessages records that the translation was posted for the original.
+func linkMessages(original *discordgo.MessageCreate, translation *discordgo.Message) {
+	link := storage.MessageLink{
+		ServerID:             original.GuildID,
+		OriginalChannelID:    original.ChannelID,
+		OriginalMessageID:    original.ID,
+		TranslationChannelID: translation.ChannelID,
+		TranslationMessageID: translation.ID,
+		CreatedAt:            time.Now(),
+	}
+	if err := store.LinkMessage(link); err != nil {
+		log.Println("Error saving message link,", err)
+	}
+
+	messageLinksMu.Lock()
+	defer messageLinksMu.Unlock()
+	// The original may have been linked before it was cached.
+	if _, ok := messageLinks[original.ID]; ok {
+		messageLinks[original.ID] = append(messageLinks[original.ID], link)
+	}
+	cacheMessageLinks(translation.ID, []storage.MessageLink{link})
+}
+
+// cacheMessageLinks keeps the message's links in memory. The caller holds
+// messageLinksMu.
+func cacheMessageLinks(messageID string, links []storage.MessageLink) {
+	if _, ok := messageLinks[messageID]; !ok {
+		messageLinkOrder = append(messageLinkOrder, messageID)
+	}
+	messageLinks[messageID] = links
+	for len(messageLinkOrder) > maxMessageLinks {
+		delete(messageLinks, messageLinkOrder[0])
+		messageLinkOrder = messageLinkOrder[1:]
+	}
+}
+
+// linkedMessages returns the counterparts of the message.
+func linkedMessages(messageID string) []messageLink {
+	messageLinksMu.Lock()
+	links, ok := messageLinks[messageID]
+	messageLinksMu.Unlock()
+	if !ok {
+		var err error
+		links, err = store.MessageLinks(messageID)
+		if err != nil {
+			log.Println("Error loading message links,", err)
+			return nil
+		}
+		messageLinksMu.Lock()
+		cacheMessageLinks(messageID, links)
+		messageLinksMu.Unlock()
+	}
+
+	var counterparts []messageLink
+	for _, link := range links {
+		if link.OriginalMessageID == messageID {
+			counterparts = append(counterparts, messageLink{link.TranslationChannelID, link.TranslationMessageID})
+		} else {
+			counterparts = append(counterparts, messageLink{link.OriginalChannelID, link.OriginalMessageID})
+		}
+	}
+	return counterparts
+}
+
+// pruneMessageLinks removes the message links nothing needs anymore. Links
+// are kept for the server's edit window, or for linkRetention when the server
+// routes channels and its links mirror reactions.
+func pruneMessageLinks(s *discordgo.Session) {
+	now := time.Now()
+	pruned, err := store.PruneMessageLinks("", now.Add(-linkRetention))
+	if err != nil {
+		log.Println("Error pruning message links,", err)
+		return
+	}
+	for guildID := range settings.Guilds() {
+		window := editWindow(guildID)
+		if window == 0 || window >= linkRetention || hasRoutes(guildID) {
+			continue
+		}
+		n, err := store.PruneMessageLinks(guildID, now.Add(-window))
+		if err != nil {
+			log.Println("Error pruning message links,", err)
+			continue
+		}
+		pruned += n
+	}
+	if pruned > 0 {
+		log.Printf("Pruned %d message links", pruned)
+	}
+}
@@ -0,0 +1,75 @@
+package bot
+
+import (
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+
+	"translate-bot/translation"
+)
+
+// premiumBackend is the LLM backend offered to licensed servers, or nil when
+// the bot has no LLM configured. Servers only translate with it once they
+// switch it on with /backend llm, since it sends their messages to another
+// provider.
+var premiumBackend translation.Backend
+
+func handleBackendLLMCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	enabled := i.ApplicationCommandData().Options[0].Options[0].BoolValue()
+	if enabled && premiumBackend == nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "Error: This bot doesn't have an LLM backend configured.",
+		})
+		return
+	}
+	if enabled && !requirePremium(s, i, featureLLM) {
+		return
+	}
+
+	value := ""
+	if enabled {
+		value = "on"
+	}
+	err := setGuildSetting(i.GuildID, settingUseLLM, value)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to update the backend: %s", err.Error()),
+		})
+		return
+	}
+
+	responseContent := "Messages will be translated with the bot-wide backend."
+	if enabled {
+		responseContent = "Messages will be translated with the LLM backend."
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
+
+func handleConfigPresetCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	preset := i.ApplicationCommandData().Options[0].Options[0].StringValue()
+	if preset != "none" && !requirePremium(s, i, featureLLM) {
+		return
+	}
+
+	value := preset
+	if preset == "none" {
+		value = ""
+	}
+	err := setGuildSetting(i.GuildID, settingLLMPreset, value)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to update style preset: %s", err.Error()),
+		})
+		return
+	}
+
+	responseContent := fmt.Sprintf("Style preset set to %s.", preset)
+	if premiumBackend == nil {
+		responseContent += " Presets only apply to the LLM backend, which this bot doesn't have configured."
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
@@ -0,0 +1,114 @@
+package bot
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// withMedia appends links to the message's attachments, stickers and GIFs to
+// a translation posted in another channel, so readers there see them too.
+// Discord previews the links like the originals. Links that would make the
+// post too long are counted instead.
+func withMedia(content string, m *discordgo.MessageCreate) string {
+	links := mediaLinks(m)
+	var appended strings.Builder
+	length := len([]rune(content))
+	for index, link := range links {
+		if length+1+len([]rune(link)) > maxMessageLength-50 {
+			fmt.Fprintf(&appended, "\n-# +%d more in %s", len(links)-index, messageJumpURL(m.GuildID, m.ChannelID, m.ID))
+			break
+		}
+		appended.WriteString("\n" + link)
+		length += 1 + len([]rune(link))
+	}
+	return content + appended.String()
+}
+
+// mediaLinks returns a line for each attachment, sticker and GIF of the
+// message.
+func mediaLinks(m *discordgo.MessageCreate) []string {
+	var links []string
+	for _, attachment := range m.Attachments {
+		link := attachment.URL
+		// Spoilered attachments stay hidden in the other channel.
+		if strings.HasPrefix(attachment.Filename, "SPOILER_") {
+			link = "||" + link + "||"
+		}
+		links = append(links, link)
+	}
+	for _, sticker := range m.StickerItems {
+		links = append(links, stickerLink(sticker))
+	}
+	// GIFs in the text are kept by the translation, so they only need
+	// passing on when there is nothing else.
+	if isOnlyGIF(m.Content) {
+		links = append(links, strings.Fields(m.Content)...)
+	}
+	return links
+}
+
+// stickerLink shows a sticker by its image, or by name for Lottie stickers,
+// which Discord can't preview from a link.
+func stickerLink(sticker *discordgo.StickerItem) string {
+	switch sticker.FormatType {
+	case discordgo.StickerFormatTypeGIF:
+		return fmt.Sprintf("Sticker: %s\nhttps://media.discordapp.net/stickers/%s.gif", sticker.Name, sticker.ID)
+	case discordgo.StickerFormatTypePNG, discordgo.StickerFormatTypeAPNG:
+		return fmt.Sprintf("Sticker: %s\nhttps://media.discordapp.net/stickers/%s.png", sticker.Name, sticker.ID)
+	}
+	return fmt.Sprintf("Sticker: %s", sticker.Name)
+}
+
+// hasMedia reports whether the message has anything withMedia passes on.
+func hasMedia(m *discordgo.MessageCreate) bool {
+	return len(m.Attachments) > 0 || len(m.StickerItems) > 0 || isOnlyGIF(m.Content)
+}
+
+// hasText reports whether the message has text to translate, as opposed to
+// nothing or only GIF links.
+func hasText(m *discordgo.MessageCreate) bool {
+	return strings.TrimSpace(m.Content) != "" && !isOnlyGIF(m.Content)
+}
+
+// isOnlyGIF reports whether the text consists of GIF links and nothing else.
+func isOnlyGIF(text string) bool {
+	words := strings.Fields(text)
+	for _, word := range words {
+		if !isGIFLink(word) {
+			return false
+		}
+	}
+	return len(words) > 0
+}
+
+func isGIFLink(word string) bool {
+	link, err := url.Parse(word)
+	if err != nil || (link.Scheme != "http" && link.Scheme != "https") {
+		return false
+	}
+	host := strings.TrimPrefix(link.Hostname(), "www.")
+	return host == "tenor.com" || host == "giphy.com" || strings.HasSuffix(host, ".giphy.com") ||
+		strings.HasSuffix(strings.ToLower(link.Path), ".gif")
+}
+
+// mediaStage passes on messages without text to translate, such as images,
+// stickers and GIFs, when translations go to a dedicated channel.
+func mediaStage(p *pipelineMessage) bool {
+	if hasText(p.m) {
+		return true
+	}
+	channelID := getGuildSetting(p.m.GuildID, settingTranslationsChannel)
+	if channelID == "" || channelID == p.m.ChannelID || !hasMedia(p.m) || containsBannedWord(p.m.GuildID, p.m.ChannelID, p.m.Content) {
+		return true
+	}
+	postTranslation(p.s, p.m, channelID, withMedia(mediaHeader(p.m, true), p.m))
+	return false
+}
+
+// mediaHeader attributes media passed on without a translation.
+func mediaHeader(m *discordgo.MessageCreate, relayed bool) string {
+	return fmt.Sprintf("**%s** in <#%s>:\n-# %s", authorName(m, relayed), m.ChannelID, messageJumpURL(m.GuildID, m.ChannelID, m.ID))
+}
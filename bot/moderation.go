@@ -0,0 +1,200 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+
+	"translate-bot/filter"
+)
+
+// toxicityScorer rates messages for the toxicity check, or is nil when no
+// scorer is configured.
+var toxicityScorer filter.ToxicityScorer
+
+// maxAlertQuote is how many characters of the offending message a moderator
+// alert quotes.
+const maxAlertQuote = 1000
+
+// alertModerators tells the server's moderators what happened to the message,
+// quoting it with the banned words masked. The alert goes to the mod channel,
+// or the admin channel when only a moderator role is set. With ping set the
+// moderator role, if there is one, is mentioned.
+func alertModerators(s *discordgo.Session, m *discordgo.MessageCreate, what string, ping bool) {
+	roleID := getGuildSetting(m.GuildID, settingModRole)
+	channelID := getGuildSetting(m.GuildID, settingModChannel)
+	if roleID == "" && channelID == "" {
+		return
+	}
+	// Dry-run mode reports the message as skipped instead.
+	if isDryRun(m.GuildID) {
+		return
+	}
+	channelID = moderatorChannel(s, m.GuildID)
+	if channelID == "" {
+		log.Printf("No channel to alert moderators of guild %s", m.GuildID)
+		return
+	}
+
+	_, words := bannedWordSeverity(m.GuildID, m.ChannelID, m.Content)
+	quote := filter.MaskBannedWords(m.Content, words)
+	if runes := []rune(quote); len(runes) > maxAlertQuote {
+		quote = string(runes[:maxAlertQuote]) + "…"
+	}
+	content := fmt.Sprintf("🚨 %s by %s %s\n> %s", messageJumpURL(m.GuildID, m.ChannelID, m.ID), m.Author.Mention(), what, strings.ReplaceAll(quote, "\n", "\n> "))
+	mentions := &discordgo.MessageAllowedMentions{}
+	if roleID != "" && ping {
+		content = fmt.Sprintf("<@&%s> %s", roleID, content)
+		mentions.Roles = []string{roleID}
+	}
+
+	queueSend(s, channelID, &discordgo.MessageSend{
+		Content:         content,
+		AllowedMentions: mentions,
+	})
+}
+
+// scoreToxicity rates the text's toxicity in percent. Personal details are
+// redacted first, since the scorer may be a third party.
+func scoreToxicity(text string) (int, error) {
+	score, err := toxicityScorer.Score(filter.Redact(text, false))
+	return int(math.Round(score * 100)), err
+}
+
+// toxicityStage holds back messages that score at or above the server's
+// toxicity threshold. It runs ahead of the announce, route and subscribe
+// stages, so only messages in channels the bot handles at all are scored.
+// Messages are let through when the scorer fails.
+func toxicityStage(p *pipelineMessage) bool {
+	threshold, _ := strconv.Atoi(getGuildSetting(p.m.GuildID, settingToxicity))
+	if threshold <= 0 || toxicityScorer == nil || !hasText(p.m) || !handledChannel(p.s, p.m.ChannelID) {
+		return true
+	}
+
+	percent, err := scoreToxicity(p.m.Content)
+	if err != nil {
+		log.Printf("Error checking toxicity with %s, %s", toxicityScorer.Name(), err)
+		return true
+	}
+	if percent < threshold {
+		return true
+	}
+
+	reportSkipped(p.s, p.m, fmt.Sprintf("the message scored %d%% for toxicity", percent))
+	alertModerators(p.s, p.m, fmt.Sprintf("was held back for scoring %d%% for toxicity", percent), true)
+	recordWarning(p.s, p.m, fmt.Sprintf("scored %d%% for toxicity", percent))
+	fireEvent(webhookEvent{
+		Event:     eventToxicity,
+		GuildID:   p.m.GuildID,
+		ChannelID: p.m.ChannelID,
+		MessageID: p.m.ID,
+		UserID:    p.m.Author.ID,
+	})
+	return false
+}
+
+// moderatorChannel returns the server's mod channel, or its admin channel
+// when it has none.
+func moderatorChannel(s *discordgo.Session, guildID string) string {
+	if channelID := getGuildSetting(guildID, settingModChannel); channelID != "" {
+		return channelID
+	}
+	return adminChannel(s, guildID)
+}
+
+func handleModerationCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	switch i.ApplicationCommandData().Options[0].Name {
+	case "alerts":
+		handleModerationAlertsCommand(s, i)
+	case "toxicity":
+		handleModerationToxicityCommand(s, i)
+	case "timeout":
+		handleModerationTimeoutCommand(s, i)
+	}
+}
+
+func handleModerationAlertsCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var role *discordgo.Role
+	var channel *discordgo.Channel
+	for _, option := range i.ApplicationCommandData().Options[0].Options {
+		switch option.Name {
+		case "role":
+			role = option.RoleValue(s, i.GuildID)
+		case "channel":
+			channel = option.ChannelValue(s)
+		}
+	}
+
+	roleID, channelID := "", ""
+	if role != nil {
+		roleID = role.ID
+	}
+	if channel != nil {
+		channelID = channel.ID
+	}
+	err := setGuildSetting(i.GuildID, settingModRole, roleID)
+	if err == nil {
+		err = setGuildSetting(i.GuildID, settingModChannel, channelID)
+	}
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to update moderator alerts: %s", err.Error()),
+		})
+		return
+	}
+
+	var responseContent string
+	switch {
+	case role != nil && channel != nil:
+		responseContent = fmt.Sprintf("Messages held back for moderation will be posted to %s, pinging %s.", channel.Mention(), role.Mention())
+	case channel != nil:
+		responseContent = fmt.Sprintf("Messages held back for moderation will be posted to %s.", channel.Mention())
+	case role != nil:
+		responseContent = fmt.Sprintf("Messages held back for moderation will be posted to the log channel, pinging %s.", role.Mention())
+	default:
+		responseContent = "Moderators will no longer be alerted of messages held back for moderation."
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
+
+func handleModerationToxicityCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	threshold := i.ApplicationCommandData().Options[0].Options[0].IntValue()
+	if threshold < 0 || threshold > 100 {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "Error: The threshold must be between 0 and 100.",
+		})
+		return
+	}
+	if threshold > 0 && toxicityScorer == nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "Toxicity checks aren't available: the bot has no toxicity scorer configured.",
+		})
+		return
+	}
+
+	value := ""
+	if threshold > 0 {
+		value = strconv.FormatInt(threshold, 10)
+	}
+	err := setGuildSetting(i.GuildID, settingToxicity, value)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to update the toxicity check: %s", err.Error()),
+		})
+		return
+	}
+
+	responseContent := "Messages will no longer be checked for toxicity."
+	if threshold > 0 {
+		responseContent = fmt.Sprintf("Messages scoring %d%% or more for toxicity won't be translated.", threshold)
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
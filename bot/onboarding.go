@@ -0,0 +1,73 @@
+package bot
+
+import (
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// onboardingWindow is how recently the bot must have joined a guild for a
+// GuildCreate to count as a new guild rather than a reconnect.
+const onboardingWindow = 10 * time.Minute
+
+const setupMessage = "👋 Thanks for adding me! To get started:\n" +
+	"• `/translate set` picks the channels whose messages are translated\n" +
+	"• `/config translations` mirrors translations to a dedicated channel\n" +
+	"• `/config logchannel` chooses where I post notices (this channel for now)\n" +
+	"• `/banword add` keeps unwanted words from being translated"
+
+var ensureCommandsMu sync.Mutex
+
+// guildCreate onboards guilds the bot has just joined. GuildCreate also fires
+// for every guild on startup, so only guilds joined moments ago that were
+// never onboarded are handled.
+func guildCreate(s *discordgo.Session, g *discordgo.GuildCreate) {
+	if g.Unavailable || time.Since(g.JoinedAt) > onboardingWindow || getGuildSetting(g.ID, settingOnboarded) != "" {
+		return
+	}
+
+	ensureCommands(s)
+
+	if g.SystemChannelID != "" && getGuildSetting(g.ID, settingLogChannel) == "" {
+		if err := setGuildSetting(g.ID, settingLogChannel, g.SystemChannelID); err != nil {
+			log.Println("Error seeding log channel,", err)
+		}
+	}
+	if err := setGuildSetting(g.ID, settingOnboarded, strconv.FormatInt(time.Now().Unix(), 10)); err != nil {
+		log.Println("Error saving onboarding time,", err)
+	}
+
+	if g.SystemChannelID != "" {
+		queueMessage(s, g.SystemChannelID, setupMessage)
+	}
+}
+
+// ensureCommands registers any of the bot's commands that are missing, for
+// example because registration failed or was interrupted.
+func ensureCommands(s *discordgo.Session) {
+	ensureCommandsMu.Lock()
+	defer ensureCommandsMu.Unlock()
+
+	registered, err := s.ApplicationCommands(s.State.User.ID, "")
+	if err != nil {
+		log.Println("Error listing commands,", err)
+		return
+	}
+	present := make(map[string]bool)
+	for _, command := range registered {
+		present[command.Name] = true
+	}
+
+	for _, command := range commandDefinitions() {
+		if present[command.Name] {
+			continue
+		}
+		_, err := s.ApplicationCommandCreate(s.State.User.ID, "", command)
+		if err != nil {
+			log.Printf("Cannot create command %s: %v", command.Name, err)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/abadojack/whatlanggo"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	targetLanguage = "en"
+
+	defaultTemplate          = "Translated: {translation}"
+	defaultDedicatedTemplate = "{author} in {channel}: {translation}\n{jump_url}"
+
+	// maxTranslationLength bounds a formatted translation, leaving room in
+	// the message for the title, reply quote, notes, footer and media links
+	// posted with it.
+	maxTranslationLength = maxMessageLength - 400
+)
+
+// languageCountries maps language codes to the country whose flag represents
+// them in compact output.
+var languageCountries = map[string]string{
+	"ar": "SA",
+	"cs": "CZ",
+	"da": "DK",
+	"de": "DE",
+	"el": "GR",
+	"en": "GB",
+	"es": "ES",
+	"fa": "IR",
+	"fi": "FI",
+	"fr": "FR",
+	"he": "IL",
+	"hi": "IN",
+	"hu": "HU",
+	"id": "ID",
+	"it": "IT",
+	"ja": "JP",
+	"ko": "KR",
+	"nl": "NL",
+	"no": "NO",
+	"pl": "PL",
+	"pt": "PT",
+	"ro": "RO",
+	"ru": "RU",
+	"sv": "SE",
+	"th": "TH",
+	"tr": "TR",
+	"uk": "UA",
+	"vi": "VN",
+	"zh": "CN",
+}
+
+// formatTranslation renders the message posted for a translation according to
+// the source channel's style, adding a jump link back to the original in
+// dedicated-channel mode or when the guild has jump links enabled. The result
+// is cut short to fit in a message along with what is posted around it.
+func formatTranslation(m *discordgo.MessageCreate, translatedText string, dedicated bool) string {
+	style := getChannelSetting(m.ChannelID, settingStyle)
+	jumpURL := messageJumpURL(m.GuildID, m.ChannelID, m.ID)
+
+	withLink := dedicated || (getGuildSetting(m.GuildID, settingJumpLinks) != "" && style != styleMinimal)
+	room := maxTranslationLength
+	if withLink {
+		room -= 1 + utf8.RuneCountInString(jumpURL)
+	}
+	content := formatTranslationBody(m, translatedText, style, dedicated, room)
+	if withLink && !strings.Contains(content, jumpURL) {
+		content += "\n" + jumpURL
+	}
+	return content
+}
+
+// formatTranslationBody renders the translation in the given style, in at
+// most room characters. The default style uses the guild's template, falling
+// back to the built-in format when none is set.
+func formatTranslationBody(m *discordgo.MessageCreate, translatedText, style string, dedicated bool, room int) string {
+	switch style {
+	case styleMinimal:
+		return shorten(translatedText, room)
+	case styleSpoiler:
+		// The translation comes first; the original gets what room is left,
+		// counting the backslash each of its pipes is escaped with.
+		translatedText = shorten(translatedText, room-len("\n||||"))
+		left := room - len("\n||||") - utf8.RuneCountInString(translatedText)
+		original := shorten(m.Content, left-strings.Count(m.Content, "|"))
+		return fmt.Sprintf("%s\n||%s||", translatedText, escapeSpoiler(original))
+	case styleCompact:
+		prefix := fmt.Sprintf("%s→%s ", languageFlag(detectLanguage(m.Content)), languageFlag(guildTargetLanguage(m.GuildID)))
+		suffix := fmt.Sprintf(" (from @%s)", authorName(m, dedicated))
+		return prefix + shorten(translatedText, room-utf8.RuneCountInString(prefix+suffix)) + suffix
+	}
+
+	template := getGuildSetting(m.GuildID, settingTemplate)
+	if template == "" {
+		template = defaultTemplate
+		if dedicated {
+			template = defaultDedicatedTemplate
+		}
+	}
+
+	render := func(original, translation string) string {
+		return strings.NewReplacer(
+			"{author}", authorName(m, dedicated),
+			"{channel}", fmt.Sprintf("<#%s>", m.ChannelID),
+			"{source_lang}", detectLanguage(m.Content),
+			"{target_lang}", guildTargetLanguage(m.GuildID),
+			"{original}", original,
+			"{translation}", translation,
+			"{jump_url}", messageJumpURL(m.GuildID, m.ChannelID, m.ID),
+		).Replace(template)
+	}
+
+	// The translation gets the room the rest of the template leaves, and
+	// the original whatever is left after that.
+	left := room - utf8.RuneCountInString(render("", ""))
+	if count := strings.Count(template, "{translation}"); count > 0 {
+		translatedText = shorten(translatedText, left/count)
+		left -= count * utf8.RuneCountInString(translatedText)
+	}
+	original := m.Content
+	if count := strings.Count(template, "{original}"); count > 0 {
+		original = shorten(original, left/count)
+	}
+	return shorten(render(original, translatedText), room)
+}
+
+// shorten cuts text to at most max characters, marking the cut with an
+// ellipsis.
+func shorten(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	if max <= 0 {
+		return ""
+	}
+	return string(runes[:max-1]) + "…"
+}
+
+// guildTargetLanguage returns the language the server's messages are
+// translated into.
+func guildTargetLanguage(serverID string) string {
+	if language := getGuildSetting(serverID, settingTargetLanguage); language != "" {
+		return language
+	}
+	return targetLanguage
+}
+
+// authorName returns the name translations are attributed to. Messages relayed
+// to another channel are attributed to a generic "Member" when the guild has
+// anonymized mirroring enabled.
+func authorName(m *discordgo.MessageCreate, relayed bool) string {
+	if relayed && getGuildSetting(m.GuildID, settingAnonymize) != "" {
+		return "Member"
+	}
+	return m.Author.Username
+}
+
+// languageFlag returns the flag emoji for a language code, or the code itself
+// when there is no flag for it.
+func languageFlag(code string) string {
+	country, ok := languageCountries[code]
+	if !ok {
+		return fmt.Sprintf("[%s]", code)
+	}
+
+	var flag strings.Builder
+	for _, r := range country {
+		flag.WriteRune(0x1F1E6 + r - 'A')
+	}
+	return flag.String()
+}
+
+// escapeSpoiler keeps pipes in the text from closing the spoiler early.
+func escapeSpoiler(text string) string {
+	return strings.ReplaceAll(text, "|", "\\|")
+}
+
+// detectLanguage returns the ISO 639-1 code of the text's language, or "?" when
+// it can't be determined.
+func detectLanguage(text string) string {
+	code := whatlanggo.DetectLang(text).Iso6391()
+	if code == "" {
+		return "?"
+	}
+	return code
+}
+
+// notifyAdmins posts a notice to the guild's log channel, falling back to the
+// system channel when no log channel is configured.
+func notifyAdmins(s *discordgo.Session, guildID, content string) {
+	channelID := adminChannel(s, guildID)
+	if channelID == "" {
+		log.Printf("No channel to notify admins of guild %s: %s", guildID, content)
+		return
+	}
+
+	queueMessage(s, channelID, content)
+}
+
+// adminChannel returns the guild's log channel, or its system channel when no
+// log channel is set.
+func adminChannel(s *discordgo.Session, guildID string) string {
+	channelID := getGuildSetting(guildID, settingLogChannel)
+	if channelID == "" {
+		guild, err := s.State.Guild(guildID)
+		if err == nil {
+			channelID = guild.SystemChannelID
+		}
+	}
+	return channelID
+}
+
+func messageJumpURL(guildID, channelID, messageID string) string {
+	return fmt.Sprintf("https://discord.com/channels/%s/%s/%s", guildID, channelID, messageID)
+}
@@ -0,0 +1,96 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestShorten(t *testing.T) {
+	tests := []struct {
+		text string
+		max  int
+		want string
+	}{
+		{"hello", 5, "hello"},
+		{"hello", 4, "hel…"},
+		{"héllo", 2, "h…"},
+		{"hello", 0, ""},
+	}
+	for _, test := range tests {
+		if got := shorten(test.text, test.max); got != test.want {
+			t.Errorf("shorten(%q, %d) = %q, want %q", test.text, test.max, got, test.want)
+		}
+	}
+}
+
+func TestFormatTranslationSpoilerNearLimit(t *testing.T) {
+	initTestStore(t)
+	if err := setChannelSetting("guild", "channel", settingStyle, styleSpoiler); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		original, translated string
+	}{
+		{strings.Repeat("a", maxMessageLength-10), strings.Repeat("b", maxMessageLength-10)},
+		{strings.Repeat("|", maxMessageLength-10), strings.Repeat("b", 1000)},
+		{strings.Repeat("a", 1000), strings.Repeat("é", 900)},
+	}
+	for _, test := range tests {
+		m := &discordgo.MessageCreate{Message: &discordgo.Message{
+			ID:        "message",
+			GuildID:   "guild",
+			ChannelID: "channel",
+			Content:   test.original,
+			Author:    &discordgo.User{Username: "user"},
+		}}
+		for _, dedicated := range []bool{false, true} {
+			content := formatTranslation(m, test.translated, dedicated)
+			if length := utf8.RuneCountInString(content); length > maxTranslationLength {
+				t.Errorf("formatTranslation() is %d characters long, want at most %d", length, maxTranslationLength)
+			}
+			if !strings.HasSuffix(strings.Split(content, "\n")[1], "||") {
+				t.Errorf("formatTranslation() = %q, want the original in a closed spoiler", content)
+			}
+			if utf8.RuneCountInString(test.translated) < 1000 && !strings.HasPrefix(content, test.translated+"\n") {
+				t.Errorf("formatTranslation() cut a translation that fits")
+			}
+		}
+	}
+}
+
+func TestFormatTranslationTemplateNearLimit(t *testing.T) {
+	initTestStore(t)
+	if err := setGuildSetting("guild", settingTemplate, "{author}: {translation}\n> {original}\n> {original}"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		original, translated string
+	}{
+		{strings.Repeat("a", maxMessageLength-10), strings.Repeat("b", maxMessageLength-10)},
+		{strings.Repeat("a", maxMessageLength-10), "short"},
+		{"short", strings.Repeat("é", maxMessageLength-10)},
+	}
+	for _, test := range tests {
+		m := &discordgo.MessageCreate{Message: &discordgo.Message{
+			ID:        "message",
+			GuildID:   "guild",
+			ChannelID: "channel",
+			Content:   test.original,
+			Author:    &discordgo.User{Username: "user"},
+		}}
+		for _, dedicated := range []bool{false, true} {
+			content := formatTranslation(m, test.translated, dedicated)
+			if length := utf8.RuneCountInString(content); length > maxTranslationLength {
+				t.Errorf("formatTranslation() is %d characters long, want at most %d", length, maxTranslationLength)
+			}
+			if test.translated == "short" && !strings.HasPrefix(content, "user: short\n> ") {
+				t.Errorf("formatTranslation() = %q, want the translation in full", content)
+			}
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Discord allows at most 10 embeds, with 6000 characters between them, in a
+// single message.
+const (
+	maxEmbedsPerMessage = 10
+	maxEmbedCharacters  = 6000
+)
+
+// handleTranslatePinsCommand translates the channel's pinned messages and
+// posts them as a series of embeds, oldest first, so new members can catch up
+// on what the channel considers important.
+func handleTranslatePinsCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	language := guildTargetLanguage(i.GuildID)
+	for _, option := range i.ApplicationCommandData().Options[0].Options {
+		if option.Name == "language" {
+			language = strings.ToLower(strings.TrimSpace(option.StringValue()))
+		}
+	}
+
+	if missing := missingPermissions(s, i.ChannelID, discordgo.PermissionViewChannel|discordgo.PermissionSendMessages|discordgo.PermissionEmbedLinks); len(missing) > 0 {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Error: I'm missing permissions in <#%s>: %s.", i.ChannelID, strings.Join(missing, ", ")),
+		})
+		return
+	}
+
+	pinned, err := s.ChannelMessagesPinned(i.ChannelID)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to fetch pinned messages: %s", err.Error()),
+		})
+		return
+	}
+	slices.Reverse(pinned)
+
+	var embeds []*discordgo.MessageEmbed
+	for _, message := range pinned {
+		if strings.TrimSpace(message.Content) == "" {
+			continue
+		}
+		translated := message.Content
+		if detectLanguage(message.Content) != language {
+			characters := len([]rune(message.Content))
+			if !checkQuota(s, i.GuildID, characters) {
+				respond(s, i, &discordgo.InteractionResponseData{
+					Content: "Error: This server's translation quota has been reached.",
+				})
+				return
+			}
+			translated, err = translateTo(i.GuildID, message.Content, language)
+			if err != nil {
+				respond(s, i, &discordgo.InteractionResponseData{
+					Content: fmt.Sprintf("Failed to translate pinned message: %s", err.Error()),
+				})
+				return
+			}
+			if err := recordUsage(i.GuildID, characters); err != nil {
+				log.Println("Error recording usage,", err)
+			}
+		}
+		embeds = append(embeds, pinEmbed(i.GuildID, message, translated))
+	}
+
+	if len(embeds) == 0 {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "Error: This channel has no pinned messages with text to translate.",
+		})
+		return
+	}
+
+	for _, batch := range embedBatches(embeds) {
+		_, err := sendQueued(s, i.ChannelID, &discordgo.MessageSend{
+			Embeds:          batch,
+			AllowedMentions: translationMentions,
+		})
+		if err != nil {
+			respond(s, i, &discordgo.InteractionResponseData{
+				Content: fmt.Sprintf("Failed to post pinned messages: %s", err.Error()),
+			})
+			return
+		}
+	}
+
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: fmt.Sprintf("Translated %d pinned messages into %s %s.", len(embeds), languageFlag(language), language),
+	})
+}
+
+func pinEmbed(guildID string, message *discordgo.Message, translated string) *discordgo.MessageEmbed {
+	if len([]rune(translated)) > maxEmbedDescription {
+		translated = string([]rune(translated)[:maxEmbedDescription-1]) + "…"
+	}
+	return &discordgo.MessageEmbed{
+		Author:      &discordgo.MessageEmbedAuthor{Name: message.Author.Username, IconURL: message.Author.AvatarURL("")},
+		Description: translated,
+		URL:         messageJumpURL(guildID, message.ChannelID, message.ID),
+		Title:       "📌",
+		Timestamp:   message.Timestamp.Format(time.RFC3339),
+	}
+}
+
+// embedBatches groups the embeds into as few messages as Discord's limits
+// allow, keeping their order.
+func embedBatches(embeds []*discordgo.MessageEmbed) [][]*discordgo.MessageEmbed {
+	var batches [][]*discordgo.MessageEmbed
+	var batch []*discordgo.MessageEmbed
+	characters := 0
+	for _, embed := range embeds {
+		size := len([]rune(embed.Description)) + len([]rune(embed.Author.Name)) + len([]rune(embed.Title))
+		if len(batch) == maxEmbedsPerMessage || (len(batch) > 0 && characters+size > maxEmbedCharacters) {
+			batches = append(batches, batch)
+			batch, characters = nil, 0
+		}
+		batch = append(batch, embed)
+		characters += size
+	}
+	return append(batches, batch)
+}
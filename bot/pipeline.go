@@ -0,0 +1,448 @@
+package bot
+
+import (
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+
+	"translate-bot/filter"
+	"translate-bot/translation"
+)
+
+// pipelineMessage carries a message through the pipeline stages, collecting
+// what each stage works out for the ones after it.
+type pipelineMessage struct {
+	s *discordgo.Session
+	m *discordgo.MessageCreate
+
+	// text is what gets translated, after incoming processors ran.
+	text       string
+	characters int
+	sourceLang string
+	titleLine  string
+	ref        *discordgo.Message
+	translated string
+	roundTrip  float64
+	checked    bool
+	footer     string
+	// channelID overrides where the translation is posted.
+	channelID string
+	// edit is set when the message is an edit of one already translated.
+	edit bool
+	// catchUp is set when the message was missed while the bot was offline.
+	catchUp bool
+	// delayed is set when the message waited for the backend to recover.
+	delayed bool
+	// streamed is the preview posted while the translation was streamed.
+	streamed *discordgo.Message
+	// poll is the message's poll, which only new messages from the gateway
+	// carry.
+	poll *messagePoll
+}
+
+// stage is one step of message handling. Returning false stops the pipeline
+// for the message.
+type stage struct {
+	name string
+	run  func(p *pipelineMessage) bool
+}
+
+// pipeline is the ordered list of stages every message goes through.
+var pipeline = []stage{
+	{"self", selfStage},
+	{"pause", pauseStage},
+	{"dedup", dedupStage},
+	{"roles", roleStage},
+	{"toxicity", toxicityStage},
+	{"announce", announceStage},
+	{"route", routeStage},
+	{"subscribe", subscribeStage},
+	{"channel", channelStage},
+	{"seen", seenStage},
+	{"forum", forumStage},
+	{"poll", pollStage},
+	{"media", mediaStage},
+	{"filter", filterStage},
+	{"incoming", incomingStage},
+	{"detect", detectStage},
+	{"quiet", quietStage},
+	{"quota", quotaStage},
+	{"translate", translateStage},
+	{"outgoing", outgoingStage},
+	{"similar", similarStage},
+	{"hook", hookStage},
+	{"stats", statsStage},
+	{"quality", qualityStage},
+	{"output", outputStage},
+}
+
+// insertStage adds a stage to the pipeline in front of the named one, or at
+// the end when there is no stage with that name.
+func insertStage(before string, st stage) {
+	for n, existing := range pipeline {
+		if existing.name == before {
+			pipeline = append(pipeline[:n], append([]stage{st}, pipeline[n:]...)...)
+			return
+		}
+	}
+	pipeline = append(pipeline, st)
+}
+
+// stageMetrics counts how often a stage ran, how often a message stopped
+// there and how long the stage took in total.
+type stageMetrics struct {
+	Runs     int64
+	Stops    int64
+	Duration time.Duration
+}
+
+var (
+	pipelineMetricsMu sync.Mutex
+	pipelineMetrics   = make(map[string]*stageMetrics)
+)
+
+func init() {
+	expvar.Publish("pipeline", expvar.Func(func() any {
+		pipelineMetricsMu.Lock()
+		defer pipelineMetricsMu.Unlock()
+		snapshot := make(map[string]stageMetrics, len(pipelineMetrics))
+		for name, metrics := range pipelineMetrics {
+			snapshot[name] = *metrics
+		}
+		return snapshot
+	}))
+}
+
+func recordStage(name string, stopped bool, duration time.Duration) {
+	pipelineMetricsMu.Lock()
+	defer pipelineMetricsMu.Unlock()
+	metrics := pipelineMetrics[name]
+	if metrics == nil {
+		metrics = &stageMetrics{}
+		pipelineMetrics[name] = metrics
+	}
+	metrics.Runs++
+	if stopped {
+		metrics.Stops++
+	}
+	metrics.Duration += duration
+}
+
+func messageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
+	runPipeline(&pipelineMessage{s: s, m: m})
+}
+
+// gatewayMessageCreate runs new messages from the gateway through the
+// pipeline. It handles the raw event rather than MessageCreate, because
+// discordgo drops the poll a message may carry.
+func gatewayMessageCreate(s *discordgo.Session, e *discordgo.Event) {
+	m, ok := e.Struct.(*discordgo.MessageCreate)
+	if !ok {
+		return
+	}
+	runPipeline(&pipelineMessage{s: s, m: m, poll: decodePoll(e.RawData)})
+}
+
+// runPipeline runs the message through the pipeline stages, only the ones in
+// editStages for edits.
+func runPipeline(p *pipelineMessage) {
+	runStages(p, pipeline)
+}
+
+// resumePipeline runs the message through the stages from the named one on.
+func resumePipeline(p *pipelineMessage, from string) {
+	for n, st := range pipeline {
+		if st.name == from {
+			runStages(p, pipeline[n:])
+			return
+		}
+	}
+}
+
+func runStages(p *pipelineMessage, stages []stage) {
+	for _, st := range stages {
+		if p.edit && !editStages[st.name] {
+			continue
+		}
+		start := time.Now()
+		ok := st.run(p)
+		recordStage(st.name, !ok, time.Since(start))
+		if !ok {
+			discardStream(p)
+			return
+		}
+	}
+}
+
+func selfStage(p *pipelineMessage) bool {
+	return p.m.Author.ID != p.s.State.User.ID
+}
+
+// announceStage translates announcements, except during the server's quiet
+// hours. Announcement channels can be translated channels too, so the
+// pipeline carries on either way.
+func announceStage(p *pipelineMessage) bool {
+	if isQuiet(p.m.GuildID) {
+		return true
+	}
+	if languages := getChannelSetting(p.m.ChannelID, settingAnnounceLanguages); languages != "" {
+		translateAnnouncement(p.s, p.m, parseLanguages(languages))
+	}
+	return true
+}
+
+// handledChannel reports whether messages in the channel are translated, or
+// passed on by the announce, route or subscribe stages.
+func handledChannel(s *discordgo.Session, channelID string) bool {
+	return isTranslateChannel(channelID) ||
+		getChannelSetting(channelID, settingAnnounceLanguages) != "" ||
+		len(routes[channelID]) > 0 ||
+		len(subscriptions[channelID]) > 0 ||
+		isTranslateForumPost(s, channelID)
+}
+
+func channelStage(p *pipelineMessage) bool {
+	if isTranslateChannel(p.m.ChannelID) || isTranslateForumPost(p.s, p.m.ChannelID) {
+		return true
+	}
+	logSkip(p.s, p.m, reasonNotConfigured)
+	return false
+}
+
+func forumStage(p *pipelineMessage) bool {
+	// The first message of a forum post shares its ID with the thread.
+	if p.m.ID == p.m.ChannelID {
+		p.titleLine = translateForumTitle(p.s, p.m)
+	}
+	return true
+}
+
+func pollStage(p *pipelineMessage) bool {
+	if p.poll == nil {
+		return true
+	}
+	translatePoll(p.s, p.m, p.poll)
+	return false
+}
+
+func filterStage(p *pipelineMessage) bool {
+	if filter.IsOnlyEmoji(p.m.Content) {
+		reportSkipped(p.s, p.m, "the message contains only emoji")
+		return false
+	}
+	matches := matchBannedWords(p.m.GuildID, p.m.ChannelID, p.m.Content)
+	severity, words := strongestMatch(matches, false)
+	if severity == "" {
+		return true
+	}
+	recordBanwordHits(p.m.GuildID, words)
+	// Only the server's own words count towards a timeout, not those other
+	// servers put on the shared ban list.
+	if localSeverity, localWords := strongestMatch(matches, true); localSeverity != "" {
+		recordWarning(p.s, p.m, fmt.Sprintf("%s: %s", localSeverity, strings.Join(localWords, ", ")))
+	}
+	fireEvent(webhookEvent{
+		Event:     eventBanword,
+		GuildID:   p.m.GuildID,
+		ChannelID: p.m.ChannelID,
+		MessageID: p.m.ID,
+		UserID:    p.m.Author.ID,
+	})
+	switch severity {
+	case severityWarn:
+		alertModerators(p.s, p.m, "was translated but contains a flagged word", false)
+		return true
+	case severityEscalate:
+		reportSkipped(p.s, p.m, "the message contains a banned word")
+		alertModerators(p.s, p.m, "was deleted for containing a banned word", true)
+		deleteOriginal(p.s, p.m)
+		return false
+	default:
+		reportSkipped(p.s, p.m, "the message contains a banned word")
+		alertModerators(p.s, p.m, "was held back for containing a banned word", true)
+		return false
+	}
+}
+
+func incomingStage(p *pipelineMessage) bool {
+	text, processor, ok := processIncoming(p.m.GuildID, p.m.ChannelID, p.m.Content)
+	if !ok {
+		reportSkipped(p.s, p.m, "skipped by the "+processor+" processor")
+		return false
+	}
+	p.text = text
+	p.characters = utf8.RuneCountInString(text)
+	return true
+}
+
+func detectStage(p *pipelineMessage) bool {
+	p.sourceLang = detectLanguage(p.m.Content)
+	return true
+}
+
+func quotaStage(p *pipelineMessage) bool {
+	if !checkQuota(p.s, p.m.GuildID, p.characters) {
+		reportSkipped(p.s, p.m, "the translation quota is used up")
+		return false
+	}
+	return true
+}
+
+func translateStage(p *pipelineMessage) bool {
+	s, m := p.s, p.m
+	opts := channelOptions(m.GuildID, m.ChannelID)
+	opts.Context = channelContext(s, m.GuildID, m.ChannelID, m.ID)
+	p.ref = referencedMessage(s, m)
+	if p.ref != nil && p.ref.Content != "" && contextLimit(m.GuildID) > 0 {
+		opts.Context = append(opts.Context, replyContext(m.GuildID, p.ref))
+	}
+	// A racing request may still be streaming after it lost, so only one of
+	// the two applies.
+	translate := translateWith
+	if getChannelSetting(m.ChannelID, settingRaceBackends) != "" {
+		translate = translateRacing
+	} else if streamsTranslation(p) {
+		opts.Partial = newTranslationStream(p).update
+	}
+	translated, err := translate(m.GuildID, p.text, guildTargetLanguage(m.GuildID), opts)
+	if err != nil {
+		if queueUntilRecovered(p) {
+			return false
+		}
+		log.Println("Error translating message,", err)
+		reportSkipped(s, m, "translation failed: "+err.Error())
+		fireEvent(webhookEvent{
+			Event:     eventBackendError,
+			GuildID:   m.GuildID,
+			ChannelID: m.ChannelID,
+			MessageID: m.ID,
+			Error:     err.Error(),
+		})
+		if err := recordError(m.GuildID); err != nil {
+			log.Println("Error recording error count,", err)
+		}
+		if errors.Is(err, translation.ErrQuotaExceeded) {
+			warnBackendQuotaExhausted(s, m.GuildID)
+		}
+		return false
+	}
+	p.translated = translated
+
+	err = recordUsage(m.GuildID, p.characters)
+	if err != nil {
+		log.Println("Error recording usage,", err)
+	}
+	warnQuotaUsage(s, m.GuildID)
+	return true
+}
+
+func outgoingStage(p *pipelineMessage) bool {
+	translated, processor, ok := processOutgoing(p.m.GuildID, p.m.ChannelID, p.translated)
+	if !ok {
+		reportSkipped(p.s, p.m, "dropped by the "+processor+" processor")
+		return false
+	}
+	p.translated = translated
+	return true
+}
+
+func similarStage(p *pipelineMessage) bool {
+	if !filter.AreTextsSimilar(p.m.Content, p.translated) {
+		return true
+	}
+	reportSkipped(p.s, p.m, "the message is already in the target language")
+	if p.titleLine != "" {
+		postTranslation(p.s, p.m, p.m.ChannelID, p.titleLine)
+	}
+	return false
+}
+
+func statsStage(p *pipelineMessage) bool {
+	err := recordStats(p.m, p.sourceLang, p.characters)
+	if err != nil {
+		log.Println("Error recording statistics,", err)
+	}
+	return true
+}
+
+// qualityStage adds the inaccuracy warning and confidence footer the server
+// asked for.
+func qualityStage(p *pipelineMessage) bool {
+	if p.titleLine != "" {
+		p.titleLine += "\n"
+	}
+
+	p.roundTrip, p.checked = backTranslationScore(p.s, p.m.GuildID, p.m.Content, p.translated)
+	if p.checked && p.roundTrip < backTranslationThreshold {
+		p.titleLine += "⚠️ This translation may be inaccurate.\n"
+	}
+
+	if getGuildSetting(p.m.GuildID, settingShowConfidence) != "" {
+		p.footer = confidenceFooter(translationConfidence(p.m.Content, p.translated, p.roundTrip, p.checked))
+	}
+	return true
+}
+
+// outputChannel returns the channel the message's translation goes to.
+func outputChannel(p *pipelineMessage) string {
+	if p.channelID != "" {
+		return p.channelID
+	}
+	if channelID := getGuildSetting(p.m.GuildID, settingTranslationsChannel); channelID != "" {
+		return channelID
+	}
+	return p.m.ChannelID
+}
+
+func outputStage(p *pipelineMessage) bool {
+	m := p.m
+	channelID := outputChannel(p)
+	if channelID != m.ChannelID {
+		// Replies lose their context in the translations channel, so quote
+		// the message being replied to.
+		titleLine := p.titleLine
+		if p.ref != nil && p.ref.Author != nil && p.ref.Content != "" {
+			titleLine = replyQuote(m, p.ref) + titleLine
+		}
+		titleLine = lateNote(p) + titleLine
+		content := withMedia(titleLine+formatTranslation(m, p.translated, true)+p.footer, m)
+		recordHistory(m, content)
+		postOutput(p, channelID, content)
+		return true
+	}
+
+	content := p.titleLine + formatTranslation(m, p.translated, false) + p.footer
+	content = lateNote(p) + content
+	recordHistory(m, content)
+	postOutput(p, m.ChannelID, content)
+	return true
+}
+
+// lateNote marks translations posted well after their message, or returns
+// an empty string.
+func lateNote(p *pipelineMessage) string {
+	switch {
+	case p.catchUp:
+		return "-# ⏪ Catch-up\n"
+	case p.delayed:
+		return fmt.Sprintf("-# ⏳ Delayed, sent <t:%d:R>\n", p.m.Timestamp.Unix())
+	}
+	return ""
+}
+
+// linkIfTracked returns a callback linking the translation to the message
+// when the server tracks edits, and nil otherwise so translations can still
+// be coalesced.
+func linkIfTracked(m *discordgo.MessageCreate) func(message *discordgo.Message) {
+	if !tracksEdits(m) {
+		return nil
+	}
+	return func(message *discordgo.Message) { linkMessages(m, message) }
+}
@@ -0,0 +1,110 @@
+package bot
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// stageIndex returns the position of the named stage in the pipeline.
+func stageIndex(t *testing.T, name string) int {
+	t.Helper()
+	n := slices.IndexFunc(pipeline, func(st stage) bool { return st.name == name })
+	if n < 0 {
+		t.Fatalf("no %s stage in the pipeline", name)
+	}
+	return n
+}
+
+func TestRoleStageBeforeFanOut(t *testing.T) {
+	for _, name := range []string{"announce", "route", "subscribe"} {
+		if stageIndex(t, "roles") > stageIndex(t, name) {
+			t.Errorf("roles stage runs after the %s stage", name)
+		}
+	}
+}
+
+func TestRoleStage(t *testing.T) {
+	initTestStore(t)
+	if err := setChannelSetting("guild", "channel", settingTranslateRoles, "role1,role2"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		channelID string
+		roles     []string
+		want      bool
+	}{
+		{"channel", []string{"role2"}, true},
+		{"channel", []string{"role3"}, false},
+		{"channel", nil, false},
+		{"other", nil, true},
+	}
+	for _, test := range tests {
+		p := &pipelineMessage{m: &discordgo.MessageCreate{Message: &discordgo.Message{
+			GuildID:   "guild",
+			ChannelID: test.channelID,
+			Author:    &discordgo.User{ID: "author"},
+			Member:    &discordgo.Member{Roles: test.roles},
+		}}}
+		if got := roleStage(p); got != test.want {
+			t.Errorf("roleStage() in %s with roles %v = %v, want %v", test.channelID, test.roles, got, test.want)
+		}
+	}
+}
+
+// fakeScorer rates every text the same.
+type fakeScorer struct {
+	score  float64
+	scored int
+}
+
+func (s *fakeScorer) Name() string { return "fake" }
+
+func (s *fakeScorer) Score(text string) (float64, error) {
+	s.scored++
+	return s.score, nil
+}
+
+func TestToxicityStageBeforeFanOut(t *testing.T) {
+	for _, name := range []string{"announce", "route", "subscribe"} {
+		if stageIndex(t, "toxicity") > stageIndex(t, name) {
+			t.Errorf("toxicity stage runs after the %s stage", name)
+		}
+	}
+}
+
+func TestToxicityStage(t *testing.T) {
+	initTestStore(t)
+	scorer := &fakeScorer{score: 0.9}
+	oldScorer := toxicityScorer
+	toxicityScorer = scorer
+	defer func() { toxicityScorer = oldScorer }()
+	if err := setGuildSetting("guild", settingToxicity, "80"); err != nil {
+		t.Fatal(err)
+	}
+	if err := setChannelSetting("guild", "announcements", settingAnnounceLanguages, "ES"); err != nil {
+		t.Fatal(err)
+	}
+	s, err := simulationSession("guild", "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message := func(channelID string) *pipelineMessage {
+		return &pipelineMessage{s: s, m: &discordgo.MessageCreate{Message: &discordgo.Message{
+			ID:        "1",
+			GuildID:   "guild",
+			ChannelID: channelID,
+			Content:   "you are awful",
+			Author:    &discordgo.User{ID: "author"},
+		}}}
+	}
+	if !toxicityStage(message("other")) || scorer.scored != 0 {
+		t.Errorf("message in a channel the bot doesn't handle was scored %d times", scorer.scored)
+	}
+	if toxicityStage(message("announcements")) {
+		t.Error("toxic announcement was let through")
+	}
+}
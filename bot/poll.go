@@ -0,0 +1,101 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+
+	"translate-bot/filter"
+)
+
+// messagePoll is the poll attached to a message. discordgo doesn't model
+// polls yet, so they are decoded from the raw gateway event.
+type messagePoll struct {
+	Question struct {
+		Text string `json:"text"`
+	} `json:"question"`
+	Answers []struct {
+		PollMedia struct {
+			Text string `json:"text"`
+		} `json:"poll_media"`
+	} `json:"answers"`
+}
+
+// decodePoll returns the poll in a raw message payload, or nil when the
+// message has none.
+func decodePoll(raw json.RawMessage) *messagePoll {
+	var message struct {
+		Poll *messagePoll `json:"poll"`
+	}
+	if err := json.Unmarshal(raw, &message); err != nil {
+		log.Println("Error decoding poll,", err)
+		return nil
+	}
+	return message.Poll
+}
+
+// translatePoll posts a translation of the poll's question and answers, since
+// poll content isn't part of the message text.
+func translatePoll(s *discordgo.Session, m *discordgo.MessageCreate, poll *messagePoll) {
+	texts := []string{poll.Question.Text}
+	for _, answer := range poll.Answers {
+		texts = append(texts, answer.PollMedia.Text)
+	}
+
+	characters := 0
+	for _, text := range texts {
+		if containsBannedWord(m.GuildID, m.ChannelID, text) {
+			return
+		}
+		characters += len([]rune(text))
+	}
+	if !checkQuota(s, m.GuildID, characters) {
+		return
+	}
+
+	translated := make([]string, len(texts))
+	changed := false
+	for i, text := range texts {
+		if strings.TrimSpace(text) == "" || filter.IsOnlyEmoji(text) {
+			translated[i] = text
+			continue
+		}
+		var err error
+		translated[i], err = translateText(m.GuildID, text)
+		if err != nil {
+			log.Println("Error translating poll,", err)
+			if err := recordError(m.GuildID); err != nil {
+				log.Println("Error recording error count,", err)
+			}
+			return
+		}
+		if !filter.AreTextsSimilar(text, translated[i]) {
+			changed = true
+		}
+	}
+
+	if err := recordUsage(m.GuildID, characters); err != nil {
+		log.Println("Error recording usage,", err)
+	}
+	warnQuotaUsage(s, m.GuildID)
+
+	if !changed {
+		return
+	}
+
+	var content strings.Builder
+	fmt.Fprintf(&content, "📊 **Poll:** %s", translated[0])
+	for i, answer := range translated[1:] {
+		fmt.Fprintf(&content, "\n%d. %s", i+1, answer)
+	}
+
+	channelID := m.ChannelID
+	if dedicatedChannelID := getGuildSetting(m.GuildID, settingTranslationsChannel); dedicatedChannelID != "" {
+		channelID = dedicatedChannelID
+		fmt.Fprintf(&content, "\n%s", messageJumpURL(m.GuildID, m.ChannelID, m.ID))
+	}
+	postTranslation(s, m, channelID, content.String())
+}
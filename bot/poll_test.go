@@ -0,0 +1,20 @@
+package bot
+
+import "testing"
+
+func TestDecodePoll(t *testing.T) {
+	poll := decodePoll([]byte(`{"id":"1","content":"","poll":{"question":{"text":"Lunch?"},` +
+		`"answers":[{"answer_id":1,"poll_media":{"text":"Pizza"}},{"answer_id":2,"poll_media":{"text":"Sushi"}}]}}`))
+	if poll == nil {
+		t.Fatal("decodePoll() = nil, want the poll")
+	}
+	if poll.Question.Text != "Lunch?" || len(poll.Answers) != 2 || poll.Answers[1].PollMedia.Text != "Sushi" {
+		t.Errorf("decodePoll() = %+v, want the question and both answers", poll)
+	}
+
+	// System messages and messages read without the MessageContent intent
+	// are empty too, but carry no poll.
+	if poll := decodePoll([]byte(`{"id":"2","content":"","type":7}`)); poll != nil {
+		t.Errorf("decodePoll() = %+v for a message without a poll, want nil", poll)
+	}
+}
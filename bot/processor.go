@@ -0,0 +1,59 @@
+package bot
+
+import "sync"
+
+// Processor transforms message text on its way through the pipeline, for
+// things like masking, glossaries or censoring. Processors run in the order
+// they were registered.
+type Processor interface {
+	// Name identifies the processor in logs and dry-run reports.
+	Name() string
+	// ProcessIncoming returns the text to translate. Returning false skips
+	// the message.
+	ProcessIncoming(serverID, channelID, text string) (string, bool)
+	// ProcessOutgoing returns the translation to post. Returning false drops
+	// the translation.
+	ProcessOutgoing(serverID, channelID, text string) (string, bool)
+}
+
+var (
+	processorsMu sync.RWMutex
+	processors   = []Processor{profanityProcessor{}}
+)
+
+// RegisterProcessor adds the processor to the end of the chain.
+func RegisterProcessor(p Processor) {
+	processorsMu.Lock()
+	defer processorsMu.Unlock()
+	processors = append(processors, p)
+}
+
+// processIncoming runs the message text through every processor. When one
+// skips the message its name is returned with false.
+func processIncoming(serverID, channelID, text string) (string, string, bool) {
+	processorsMu.RLock()
+	defer processorsMu.RUnlock()
+	for _, p := range processors {
+		var ok bool
+		text, ok = p.ProcessIncoming(serverID, channelID, text)
+		if !ok {
+			return "", p.Name(), false
+		}
+	}
+	return text, "", true
+}
+
+// processOutgoing runs a translation through every processor. When one drops
+// the translation its name is returned with false.
+func processOutgoing(serverID, channelID, text string) (string, string, bool) {
+	processorsMu.RLock()
+	defer processorsMu.RUnlock()
+	for _, p := range processors {
+		var ok bool
+		text, ok = p.ProcessOutgoing(serverID, channelID, text)
+		if !ok {
+			return "", p.Name(), false
+		}
+	}
+	return text, "", true
+}
@@ -0,0 +1,64 @@
+package bot
+
+import (
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+
+	"translate-bot/filter"
+)
+
+// Profanity modes decide what happens when a translation contains profanity
+// the translator produced. This is separate from the banword filter, which
+// only looks at the original message.
+const (
+	profanityKeep = "keep"
+	profanityMask = "mask"
+	profanityDrop = "drop"
+)
+
+// filterProfanity applies the server's profanity mode to a translation. It
+// returns false when the translation should not be posted at all.
+func filterProfanity(serverID, text string) (string, bool) {
+	switch getGuildSetting(serverID, settingProfanity) {
+	case profanityMask:
+		return filter.MaskProfanity(text), true
+	case profanityDrop:
+		return text, !filter.ContainsProfanity(text)
+	default:
+		return text, true
+	}
+}
+
+// profanityProcessor applies the server's profanity mode to translations.
+type profanityProcessor struct{}
+
+func (profanityProcessor) Name() string { return "profanity" }
+
+func (profanityProcessor) ProcessIncoming(serverID, channelID, text string) (string, bool) {
+	return text, true
+}
+
+func (profanityProcessor) ProcessOutgoing(serverID, channelID, text string) (string, bool) {
+	return filterProfanity(serverID, text)
+}
+
+func handleConfigProfanityCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	mode := i.ApplicationCommandData().Options[0].Options[0].StringValue()
+
+	value := mode
+	if mode == profanityKeep {
+		value = ""
+	}
+	err := setGuildSetting(i.GuildID, settingProfanity, value)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to update profanity handling: %s", err.Error()),
+		})
+		return
+	}
+
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: fmt.Sprintf("Profanity in translations will now be handled with mode %s.", mode),
+	})
+}
@@ -0,0 +1,106 @@
+package bot
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	banwordHitsMu sync.Mutex
+	// banwordHitCounts counts banned word matches since startup by guild
+	// and word.
+	banwordHitCounts = make(map[[2]string]int64)
+)
+
+func init() {
+	http.HandleFunc("/metrics", servePrometheusMetrics)
+}
+
+// servePrometheusMetrics publishes the pipeline, job, banword and shard
+// metrics in the Prometheus text format.
+func servePrometheusMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	pipelineMetricsMu.Lock()
+	writeMetricHeader(w, "translatebot_stage_runs_total", "counter", "Messages that reached a pipeline stage.")
+	for _, name := range sortedKeys(pipelineMetrics) {
+		fmt.Fprintf(w, "translatebot_stage_runs_total{stage=%s} %d\n", quoteLabel(name), pipelineMetrics[name].Runs)
+	}
+	writeMetricHeader(w, "translatebot_stage_stops_total", "counter", "Messages a pipeline stage stopped.")
+	for _, name := range sortedKeys(pipelineMetrics) {
+		fmt.Fprintf(w, "translatebot_stage_stops_total{stage=%s} %d\n", quoteLabel(name), pipelineMetrics[name].Stops)
+	}
+	writeMetricHeader(w, "translatebot_stage_seconds_total", "counter", "Time spent in a pipeline stage.")
+	for _, name := range sortedKeys(pipelineMetrics) {
+		fmt.Fprintf(w, "translatebot_stage_seconds_total{stage=%s} %g\n", quoteLabel(name), pipelineMetrics[name].Duration.Seconds())
+	}
+	pipelineMetricsMu.Unlock()
+
+	jobMetricsMu.Lock()
+	writeMetricHeader(w, "translatebot_job_runs_total", "counter", "Runs of a scheduled job.")
+	for _, name := range sortedKeys(jobStats) {
+		fmt.Fprintf(w, "translatebot_job_runs_total{job=%s} %d\n", quoteLabel(name), jobStats[name].Runs)
+	}
+	writeMetricHeader(w, "translatebot_job_failures_total", "counter", "Runs of a scheduled job that panicked.")
+	for _, name := range sortedKeys(jobStats) {
+		fmt.Fprintf(w, "translatebot_job_failures_total{job=%s} %d\n", quoteLabel(name), jobStats[name].Failures)
+	}
+	jobMetricsMu.Unlock()
+
+	banwordHitsMu.Lock()
+	writeMetricHeader(w, "translatebot_banword_hits_total", "counter", "Messages a banned word matched.")
+	keys := make([][2]string, 0, len(banwordHitCounts))
+	for key := range banwordHitCounts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(a, b int) bool {
+		return keys[a][0] < keys[b][0] || keys[a][0] == keys[b][0] && keys[a][1] < keys[b][1]
+	})
+	for _, key := range keys {
+		fmt.Fprintf(w, "translatebot_banword_hits_total{guild=%s,word=%s} %d\n", quoteLabel(key[0]), quoteLabel(key[1]), banwordHitCounts[key])
+	}
+	banwordHitsMu.Unlock()
+
+	localHealthMu.Lock()
+	if health := localHealth; health != nil {
+		shard := quoteLabel(strconv.Itoa(health.Shard))
+		writeMetricHeader(w, "translatebot_shard_guilds", "gauge", "Guilds the shard serves.")
+		fmt.Fprintf(w, "translatebot_shard_guilds{shard=%s} %d\n", shard, health.Guilds)
+		writeMetricHeader(w, "translatebot_shard_latency_seconds", "gauge", "Gateway heartbeat latency of the shard.")
+		fmt.Fprintf(w, "translatebot_shard_latency_seconds{shard=%s} %g\n", shard, health.Latency)
+		writeMetricHeader(w, "translatebot_shard_connected", "gauge", "Whether the shard is connected to the gateway.")
+		fmt.Fprintf(w, "translatebot_shard_connected{shard=%s} %d\n", shard, boolMetric(health.Connected))
+	}
+	localHealthMu.Unlock()
+}
+
+func boolMetric(value bool) int {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+func writeMetricHeader(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quoteLabel(value string) string {
+	return `"` + labelEscaper.Replace(value) + `"`
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
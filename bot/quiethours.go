@@ -0,0 +1,205 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// quietHoursInterval is how often the scheduler checks whether quiet hours
+// started or ended.
+const quietHoursInterval = time.Minute
+
+var (
+	quietMu sync.Mutex
+	// quietGuilds holds the guilds in their quiet hours.
+	quietGuilds = make(map[string]bool)
+	// quietChecked is set once quiet hours were first checked, so the
+	// guilds already in them at startup aren't told they just started.
+	quietChecked bool
+)
+
+// parseQuietHours parses quiet hours written as "22:00-07:00" into minutes
+// since midnight. The end may come before the start, for quiet hours that
+// span midnight.
+func parseQuietHours(value string) (start, end int, err error) {
+	from, to, ok := strings.Cut(value, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("%q isn't a range like 22:00-07:00", value)
+	}
+	start, err = parseClock(from)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err = parseClock(to)
+	if err != nil {
+		return 0, 0, err
+	}
+	if start == end {
+		return 0, 0, fmt.Errorf("quiet hours can't start and end at the same time")
+	}
+	return start, end, nil
+}
+
+// parseClock parses a time of day such as 7:30 or 22:00 into minutes since
+// midnight.
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("%q isn't a time like 22:00", strings.TrimSpace(value))
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// inQuietHours reports whether the time falls within the quiet hours.
+func inQuietHours(value string, now time.Time) bool {
+	start, end, err := parseQuietHours(value)
+	if err != nil {
+		return false
+	}
+	minute := now.Hour()*60 + now.Minute()
+	if start < end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+// isQuiet reports whether the guild is in its quiet hours.
+func isQuiet(guildID string) bool {
+	quietMu.Lock()
+	defer quietMu.Unlock()
+	return quietGuilds[guildID]
+}
+
+// updateQuietHours starts and ends the quiet hours of every guild that has
+// them, telling the admins when they do.
+func updateQuietHours(s *discordgo.Session) {
+	for guildID, guild := range settings.Guilds() {
+		quiet := guild[settingQuietHours] != "" && inQuietHours(guild[settingQuietHours], guildNow(guildID))
+
+		quietMu.Lock()
+		changed := quietGuilds[guildID] != quiet
+		if quiet {
+			quietGuilds[guildID] = true
+		} else {
+			delete(quietGuilds, guildID)
+		}
+		notify := changed && quietChecked
+		quietMu.Unlock()
+
+		// Every instance runs this job, but only one tells the admins.
+		if notify && sharedClaim(fmt.Sprintf("quiet:%s:%t", guildID, quiet), 10*quietHoursInterval) {
+			notifyAdmins(s, guildID, quietHoursNotice(guild, quiet))
+		}
+	}
+
+	quietMu.Lock()
+	quietChecked = true
+	quietMu.Unlock()
+}
+
+// refreshQuietHours starts or ends the guild's quiet hours right away after
+// its settings changed, without telling the admins.
+func refreshQuietHours(guildID string) {
+	value := getGuildSetting(guildID, settingQuietHours)
+	quiet := value != "" && inQuietHours(value, guildNow(guildID))
+
+	quietMu.Lock()
+	defer quietMu.Unlock()
+	if quiet {
+		quietGuilds[guildID] = true
+	} else {
+		delete(quietGuilds, guildID)
+	}
+}
+
+func quietHoursNotice(guild map[string]string, quiet bool) string {
+	if !quiet {
+		return "☀️ Quiet hours are over. Translations are posted as usual again."
+	}
+	_, end, _ := parseQuietHours(guild[settingQuietHours])
+	until := fmt.Sprintf("%02d:%02d", end/60, end%60)
+	if channelID := guild[settingQuietChannel]; channelID != "" {
+		return fmt.Sprintf("🌙 Quiet hours started. Translations go to <#%s> until %s.", channelID, until)
+	}
+	return fmt.Sprintf("🌙 Quiet hours started. Messages won't be translated until %s.", until)
+}
+
+// quietStage holds back translations during the guild's quiet hours, or
+// sends them to the channel set aside for them. The announce, route and
+// subscribe stages before it check quiet hours themselves.
+func quietStage(p *pipelineMessage) bool {
+	if !isQuiet(p.m.GuildID) {
+		return true
+	}
+	if channelID := getGuildSetting(p.m.GuildID, settingQuietChannel); channelID != "" {
+		p.channelID = channelID
+		return true
+	}
+	reportSkipped(p.s, p.m, "it's the server's quiet hours")
+	return false
+}
+
+func handleConfigQuietHoursCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var start, end string
+	var channel *discordgo.Channel
+	for _, option := range i.ApplicationCommandData().Options[0].Options {
+		switch option.Name {
+		case "start":
+			start = option.StringValue()
+		case "end":
+			end = option.StringValue()
+		case "channel":
+			channel = option.ChannelValue(s)
+		}
+	}
+
+	if (start == "") != (end == "") {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "Give both a start and an end, or neither to turn quiet hours off.",
+		})
+		return
+	}
+	value := ""
+	if start != "" {
+		value = strings.TrimSpace(start) + "-" + strings.TrimSpace(end)
+		if _, _, err := parseQuietHours(value); err != nil {
+			respond(s, i, &discordgo.InteractionResponseData{
+				Content: fmt.Sprintf("Invalid quiet hours: %s", err.Error()),
+			})
+			return
+		}
+	}
+	channelID := ""
+	if channel != nil && value != "" {
+		channelID = channel.ID
+	}
+
+	err := setGuildSetting(i.GuildID, settingQuietHours, value)
+	if err == nil {
+		err = setGuildSetting(i.GuildID, settingQuietChannel, channelID)
+	}
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to update quiet hours: %s", err.Error()),
+		})
+		return
+	}
+
+	refreshQuietHours(i.GuildID)
+
+	responseContent := "Quiet hours are off. Messages are translated at any time."
+	switch {
+	case value == "":
+	case channelID != "":
+		responseContent = fmt.Sprintf("From %s to %s every day (%s), translations will go to %s instead.", strings.TrimSpace(start), strings.TrimSpace(end), guildLocation(i.GuildID), channel.Mention())
+	default:
+		responseContent = fmt.Sprintf("From %s to %s every day (%s), messages won't be translated.", strings.TrimSpace(start), strings.TrimSpace(end), guildLocation(i.GuildID))
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
@@ -0,0 +1,124 @@
+package bot
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"translate-bot/storage"
+)
+
+func TestParseQuietHours(t *testing.T) {
+	tests := []struct {
+		value      string
+		start, end int
+		wantErr    bool
+	}{
+		{"22:00-07:00", 22 * 60, 7 * 60, false},
+		{"9:30-17:00", 9*60 + 30, 17 * 60, false},
+		{" 08:00 - 12:15 ", 8 * 60, 12*60 + 15, false},
+		{"22:00", 0, 0, true},
+		{"22:00-", 0, 0, true},
+		{"25:00-07:00", 0, 0, true},
+		{"10:00-10:00", 0, 0, true},
+	}
+	for _, test := range tests {
+		start, end, err := parseQuietHours(test.value)
+		if (err != nil) != test.wantErr {
+			t.Errorf("parseQuietHours(%q) error = %v, want error %t", test.value, err, test.wantErr)
+			continue
+		}
+		if err == nil && (start != test.start || end != test.end) {
+			t.Errorf("parseQuietHours(%q) = %d, %d, want %d, %d", test.value, start, end, test.start, test.end)
+		}
+	}
+}
+
+func TestInQuietHours(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2026, 1, 1, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		value string
+		now   time.Time
+		want  bool
+	}{
+		{"22:00-07:00", at(23, 0), true},
+		{"22:00-07:00", at(0, 0), true},
+		{"22:00-07:00", at(6, 59), true},
+		{"22:00-07:00", at(7, 0), false},
+		{"22:00-07:00", at(21, 59), false},
+		{"09:00-17:30", at(12, 0), true},
+		{"09:00-17:30", at(17, 30), false},
+		{"09:00-17:30", at(8, 0), false},
+		{"invalid", at(12, 0), false},
+	}
+	for _, test := range tests {
+		if got := inQuietHours(test.value, test.now); got != test.want {
+			t.Errorf("inQuietHours(%q, %s) = %t, want %t", test.value, test.now.Format("15:04"), got, test.want)
+		}
+	}
+}
+
+// TestQuietHoursRedirect runs a message through the whole pipeline during
+// quiet hours and checks that its translation lands in the quiet channel and
+// isn't routed elsewhere.
+func TestQuietHoursRedirect(t *testing.T) {
+	dir := t.TempDir()
+	translator := filepath.Join(dir, "trans")
+	err := os.WriteFile(translator, []byte("#!/bin/sh\ncat >/dev/null\necho 'hola a todos'\n"), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TRANSLATE_PATH", translator)
+
+	st, err := storage.Open(filepath.Join(dir, "channels.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Init(st); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().UTC()
+	start, end := now.Add(-time.Hour), now.Add(time.Hour)
+	quietHours := fmt.Sprintf("%s-%s", start.Format("15:04"), end.Format("15:04"))
+	if err := setGuildSetting(simulationGuildID, settingQuietHours, quietHours); err != nil {
+		t.Fatal(err)
+	}
+	if err := setGuildSetting(simulationGuildID, settingQuietChannel, "quiet"); err != nil {
+		t.Fatal(err)
+	}
+	err = st.SetRoute(storage.Route{
+		ServerID:             simulationGuildID,
+		SourceChannelID:      simulationChannelID,
+		DestinationChannelID: "routed",
+		TargetLang:           "ES",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := loadRoutes(); err != nil {
+		t.Fatal(err)
+	}
+	refreshQuietHours(simulationGuildID)
+	defer func() {
+		quietMu.Lock()
+		delete(quietGuilds, simulationGuildID)
+		quietMu.Unlock()
+	}()
+
+	var out strings.Builder
+	if err := Simulate("", strings.NewReader("hello everyone, how are you today?\n"), &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "[#quiet]\n") || !strings.Contains(out.String(), "hola a todos") {
+		t.Errorf("translation posted as %q, want it in #quiet", out.String())
+	}
+	if strings.Contains(out.String(), "[#routed]") {
+		t.Errorf("translation routed during quiet hours: %q", out.String())
+	}
+}
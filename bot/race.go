@@ -0,0 +1,100 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+
+	"translate-bot/translation"
+)
+
+// raceBackend returns the backend to race the server's own against: the next
+// usable one in its fallback chain whose breaker is closed, or nil.
+func raceBackend(serverID string, primary translation.Backend) translation.Backend {
+	key := breakerKey(primary)
+	for _, entry := range backendChain(serverID) {
+		if entry.err != nil || entry.backend == nil {
+			continue
+		}
+		other := breakerKey(entry.backend)
+		if other != key && !backendDown(other) {
+			return entry.backend
+		}
+	}
+	return nil
+}
+
+// translateRacing sends the text to the server's backend and the one after
+// it in the fallback chain at once, returning whichever translation comes
+// back first and canceling the other request. It only fails when both do.
+func translateRacing(serverID, text, targetLang string, opts translation.Options) (string, error) {
+	primary := guildBackend(serverID)
+	secondary := raceBackend(serverID, primary)
+	if secondary == nil {
+		return translateWith(serverID, text, targetLang, opts)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	type result struct {
+		translated string
+		err        error
+	}
+	results := make(chan result, 2)
+	for _, b := range []translation.Backend{primary, secondary} {
+		go func(b translation.Backend) {
+			translated, err := translateUsing(ctx, serverID, b, text, targetLang, opts)
+			results <- result{translated, err}
+		}(b)
+	}
+
+	var err error
+	for n := 0; n < 2; n++ {
+		r := <-results
+		if r.err == nil {
+			return r.translated, nil
+		}
+		err = r.err
+	}
+	return "", err
+}
+
+func handleBackendRaceCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var channel *discordgo.Channel
+	var enabled bool
+	for _, option := range i.ApplicationCommandData().Options[0].Options {
+		switch option.Name {
+		case "channel":
+			channel = option.ChannelValue(s)
+		case "enabled":
+			enabled = option.BoolValue()
+		}
+	}
+
+	value := ""
+	if enabled {
+		value = "on"
+	}
+	err := setChannelSetting(i.GuildID, channel.ID, settingRaceBackends, value)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to update backend racing: %s", err.Error()),
+		})
+		return
+	}
+
+	responseContent := fmt.Sprintf("Messages in %s will be translated by one backend again.", channel.Mention())
+	if enabled {
+		primary := guildBackend(i.GuildID)
+		if secondary := raceBackend(i.GuildID, primary); secondary != nil {
+			responseContent = fmt.Sprintf("Messages in %s will be sent to %s and %s at once, and whichever translation comes back first is posted.", channel.Mention(), primary.Name(), secondary.Name())
+		} else {
+			responseContent = fmt.Sprintf("Racing is on for %s, but this server has no second backend to race %s against, so it has no effect for now.", channel.Mention(), primary.Name())
+		}
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
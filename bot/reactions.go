@@ -0,0 +1,80 @@
+package bot
+
+import (
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// isPaired reports whether translations are routed both ways between the
+// channels.
+func isPaired(channelA, channelB string) bool {
+	return hasRoute(channelA, channelB) && hasRoute(channelB, channelA)
+}
+
+func hasRoute(sourceChannelID, destinationChannelID string) bool {
+	for _, route := range routes[sourceChannelID] {
+		if route.DestinationChannelID == destinationChannelID {
+			return true
+		}
+	}
+	return false
+}
+
+// messageReactionAdd mirrors a reaction onto the counterparts of the message
+// in paired channels, so each side sees the other's engagement. The bot's
+// own reactions are never mirrored, which keeps the two sides from echoing.
+func messageReactionAdd(s *discordgo.Session, r *discordgo.MessageReactionAdd) {
+	if r.GuildID == "" || r.UserID == s.State.User.ID {
+		return
+	}
+	for _, link := range linkedMessages(r.MessageID) {
+		if !isPaired(r.ChannelID, link.channelID) {
+			continue
+		}
+		err := s.MessageReactionAdd(link.channelID, link.messageID, r.Emoji.APIName())
+		if err != nil {
+			log.Println("Error mirroring reaction,", err)
+		}
+	}
+}
+
+// messageReactionRemove takes back a mirrored reaction once nobody but the
+// bot reacts with that emoji on the message anymore.
+func messageReactionRemove(s *discordgo.Session, r *discordgo.MessageReactionRemove) {
+	if r.GuildID == "" || r.UserID == s.State.User.ID {
+		return
+	}
+	links := linkedMessages(r.MessageID)
+	if len(links) == 0 {
+		return
+	}
+
+	message, err := s.ChannelMessage(r.ChannelID, r.MessageID)
+	if err != nil {
+		log.Println("Error fetching message for reaction,", err)
+		return
+	}
+	for _, reaction := range message.Reactions {
+		if reaction.Emoji.APIName() != r.Emoji.APIName() {
+			continue
+		}
+		others := reaction.Count
+		if reaction.Me {
+			others--
+		}
+		if others > 0 {
+			return
+		}
+	}
+
+	for _, link := range links {
+		if !isPaired(r.ChannelID, link.channelID) {
+			continue
+		}
+		err := s.MessageReactionRemove(link.channelID, link.messageID, r.Emoji.APIName(), "@me")
+		if err != nil {
+			log.Println("Error removing mirrored reaction,", err)
+		}
+	}
+}
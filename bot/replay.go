@@ -0,0 +1,69 @@
+package bot
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Replay runs the server's most recent stored messages, or those of all
+// servers when the server ID is empty, through the current pipeline without
+// connecting to Discord, and writes the messages whose output changed along
+// with what was posted before and what would be posted now.
+func Replay(serverID string, limit int, w io.Writer) error {
+	if err := initBackends(); err != nil {
+		return err
+	}
+
+	messages, err := store.Messages(serverID, limit)
+	if err != nil {
+		return err
+	}
+
+	s, err := simulationSession(simulationGuildID, simulationChannelID)
+	if err != nil {
+		return err
+	}
+	var posts []string
+	simulatedPost = func(channelID, content string) {
+		posts = append(posts, content)
+	}
+	defer func() { simulatedPost = nil }()
+
+	changed := 0
+	for _, message := range messages {
+		s.State.GuildAdd(&discordgo.Guild{ID: message.ServerID})
+		s.State.ChannelAdd(&discordgo.Channel{ID: message.ChannelID, GuildID: message.ServerID, Type: discordgo.ChannelTypeGuildText})
+
+		posts = nil
+		messageCreate(s, &discordgo.MessageCreate{Message: &discordgo.Message{
+			ID:        message.MessageID,
+			ChannelID: message.ChannelID,
+			GuildID:   message.ServerID,
+			Content:   message.Content,
+			Author:    &discordgo.User{ID: message.AuthorID, Username: message.AuthorName},
+			Timestamp: message.CreatedAt,
+		}})
+
+		output := strings.Join(posts, "\n")
+		if output == message.Output {
+			continue
+		}
+		changed++
+		fmt.Fprintf(w, "%s\nOriginal: %s\nBefore: %s\nAfter: %s\n\n",
+			messageJumpURL(message.ServerID, message.ChannelID, message.MessageID),
+			message.Content, replayOutput(message.Output), replayOutput(output))
+	}
+
+	fmt.Fprintf(w, "%d messages replayed, %d changed.\n", len(messages), changed)
+	return nil
+}
+
+func replayOutput(output string) string {
+	if output == "" {
+		return "(skipped)"
+	}
+	return output
+}
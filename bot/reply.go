@@ -0,0 +1,51 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxReplyQuote keeps the quoted message short enough not to drown out the
+// translation itself.
+const maxReplyQuote = 100
+
+// referencedMessage returns the message the given message replies to, or nil
+// when it isn't a reply.
+func referencedMessage(s *discordgo.Session, m *discordgo.MessageCreate) *discordgo.Message {
+	if m.Type != discordgo.MessageTypeReply || m.MessageReference == nil {
+		return nil
+	}
+	if m.ReferencedMessage != nil {
+		return m.ReferencedMessage
+	}
+
+	ref, err := s.ChannelMessage(m.MessageReference.ChannelID, m.MessageReference.MessageID)
+	if err != nil {
+		log.Println("Error fetching referenced message,", err)
+		return nil
+	}
+	return ref
+}
+
+// replyContext describes the replied-to message for the LLM backend. It is
+// only sent when the server has context enabled, and is redacted like the
+// rest of the context.
+func replyContext(serverID string, ref *discordgo.Message) string {
+	return "The message being translated is a reply to: " + redactContext(ref.Content, getGuildSetting(serverID, settingContextRedaction))
+}
+
+// replyQuote renders a short quote of the replied-to message for translations
+// posted away from the original conversation.
+func replyQuote(m *discordgo.MessageCreate, ref *discordgo.Message) string {
+	author := ref.Author.Username
+	if getGuildSetting(m.GuildID, settingAnonymize) != "" {
+		author = "Member"
+	}
+	quote := ref.Content
+	if len([]rune(quote)) > maxReplyQuote {
+		quote = string([]rune(quote)[:maxReplyQuote-1]) + "…"
+	}
+	return fmt.Sprintf("> ↪️ **%s**: %s\n", author, quote)
+}
@@ -0,0 +1,87 @@
+package bot
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// roleStage skips messages in channels restricted to some roles when the
+// author holds none of them. It runs ahead of the announce, route and
+// subscribe stages so such messages aren't passed on elsewhere either.
+func roleStage(p *pipelineMessage) bool {
+	value := getChannelSetting(p.m.ChannelID, settingTranslateRoles)
+	if value == "" {
+		return true
+	}
+	roleIDs := strings.Split(value, ",")
+	for _, roleID := range memberRoles(p.s, p.m) {
+		if slices.Contains(roleIDs, roleID) {
+			return true
+		}
+	}
+	reportSkipped(p.s, p.m, "the author has none of the roles translated in this channel")
+	return false
+}
+
+// memberRoles returns the roles of the message's author. Messages fetched
+// after the fact, such as for catch-up, don't carry the member, so it is
+// looked up.
+func memberRoles(s *discordgo.Session, m *discordgo.MessageCreate) []string {
+	if m.Member != nil {
+		return m.Member.Roles
+	}
+	if member, err := s.State.Member(m.GuildID, m.Author.ID); err == nil {
+		return member.Roles
+	}
+	member, err := s.GuildMember(m.GuildID, m.Author.ID)
+	if err != nil {
+		return nil
+	}
+	return member.Roles
+}
+
+func handleTranslateRolesCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Member == nil || i.Member.Permissions&discordgo.PermissionManageServer == 0 {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "Error: Restricting translation to roles requires the Manage Server permission.",
+		})
+		return
+	}
+
+	var channel *discordgo.Channel
+	var roles []*discordgo.Role
+	for _, option := range i.ApplicationCommandData().Options[0].Options {
+		switch option.Type {
+		case discordgo.ApplicationCommandOptionChannel:
+			channel = option.ChannelValue(s)
+		case discordgo.ApplicationCommandOptionRole:
+			roles = append(roles, option.RoleValue(s, i.GuildID))
+		}
+	}
+
+	var roleIDs, mentions []string
+	for _, role := range roles {
+		if !slices.Contains(roleIDs, role.ID) {
+			roleIDs = append(roleIDs, role.ID)
+			mentions = append(mentions, role.Mention())
+		}
+	}
+	err := setChannelSetting(i.GuildID, channel.ID, settingTranslateRoles, strings.Join(roleIDs, ","))
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to update the channel's roles: %s", err.Error()),
+		})
+		return
+	}
+
+	responseContent := fmt.Sprintf("Messages from everyone in %s will be translated.", channel.Mention())
+	if len(roleIDs) > 0 {
+		responseContent = fmt.Sprintf("Only messages from members with %s will be translated in %s.", strings.Join(mentions, " or "), channel.Mention())
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
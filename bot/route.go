@@ -0,0 +1,306 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+
+	"translate-bot/filter"
+	"translate-bot/storage"
+)
+
+// Author modes decide how the author of a routed message is shown.
+const (
+	routeAuthorName      = "name"
+	routeAuthorAnonymous = "anonymous"
+	// routeAuthorWebhook posts through a webhook under the author's name
+	// and avatar.
+	routeAuthorWebhook = "webhook"
+)
+
+// routes holds the translation routes of every server by source channel.
+var routes map[string][]storage.Route
+
+func loadRoutes() error {
+	all, err := store.Routes()
+	if err != nil {
+		return err
+	}
+	bySource := make(map[string][]storage.Route)
+	for _, route := range all {
+		bySource[route.SourceChannelID] = append(bySource[route.SourceChannelID], route)
+	}
+	routes = bySource
+	return nil
+}
+
+// routeStage sends translations of the message along the routes leaving its
+// channel, except during the server's quiet hours. The channel may be a
+// translated channel too, so the pipeline carries on either way. Messages
+// the bot posted are dropped by the self stage and webhook messages are
+// never routed, so paired channels don't bounce translations back and forth.
+func routeStage(p *pipelineMessage) bool {
+	channelRoutes := routes[p.m.ChannelID]
+	if len(channelRoutes) == 0 || p.m.WebhookID != "" || isQuiet(p.m.GuildID) {
+		return true
+	}
+
+	m := p.m
+	if !hasText(m) {
+		// Media is passed on even without text to translate.
+		if hasMedia(m) {
+			for _, route := range channelRoutes {
+				postRouted(p.s, m, route, "")
+			}
+		}
+		return true
+	}
+	if filter.IsOnlyEmoji(m.Content) || containsBannedWord(m.GuildID, m.ChannelID, m.Content) {
+		return true
+	}
+	text, _, ok := processIncoming(m.GuildID, m.ChannelID, m.Content)
+	if !ok {
+		return true
+	}
+
+	sourceLang := detectLanguage(text)
+	characters := utf8.RuneCountInString(text)
+	for _, route := range channelRoutes {
+		translated := text
+		if sourceLang != route.TargetLang {
+			if !checkQuota(p.s, m.GuildID, characters) {
+				return true
+			}
+			var err error
+			translated, err = translateWith(m.GuildID, text, route.TargetLang, channelOptions(m.GuildID, m.ChannelID))
+			if err != nil {
+				log.Println("Error translating routed message,", err)
+				if err := recordError(m.GuildID); err != nil {
+					log.Println("Error recording error count,", err)
+				}
+				continue
+			}
+			if err := recordUsage(m.GuildID, characters); err != nil {
+				log.Println("Error recording usage,", err)
+			}
+		}
+
+		translated, _, ok = processOutgoing(m.GuildID, m.ChannelID, translated)
+		if !ok {
+			continue
+		}
+		postRouted(p.s, m, route, translated)
+	}
+	return true
+}
+
+// postRouted posts a routed translation, with the original's attachments,
+// the way the route shows its author. The translation is linked to the
+// original so reactions can be mirrored between them.
+func postRouted(s *discordgo.Session, m *discordgo.MessageCreate, route storage.Route, translated string) {
+	link := func(message *discordgo.Message) { linkMessages(m, message) }
+	if route.AuthorMode == routeAuthorWebhook {
+		postAsAuthor(s, m, route.DestinationChannelID, withMedia(fmt.Sprintf("%s\n-# %s", translated, messageJumpURL(m.GuildID, m.ChannelID, m.ID)), m), link)
+		return
+	}
+	postTranslationThen(s, m, route.DestinationChannelID, withMedia(formatRouted(m, route, translated), m), link)
+}
+
+// formatRouted renders a routed translation with the author shown as the
+// route asks and a link back to the original.
+func formatRouted(m *discordgo.MessageCreate, route storage.Route, translated string) string {
+	author := m.Author.Username
+	if route.AuthorMode == routeAuthorAnonymous {
+		author = "Member"
+	}
+	return fmt.Sprintf("**%s** in <#%s>: %s\n-# %s", author, m.ChannelID, translated, messageJumpURL(m.GuildID, m.ChannelID, m.ID))
+}
+
+func handleRouteCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	subCommand := i.ApplicationCommandData().Options[0].Name
+
+	switch subCommand {
+	case "add":
+		handleRouteAddCommand(s, i)
+	case "remove":
+		handleRouteRemoveCommand(s, i)
+	case "list":
+		handleRouteListCommand(s, i)
+	case "hub":
+		handleRouteHubCommand(s, i)
+	}
+}
+
+func handleRouteAddCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	route := storage.Route{ServerID: i.GuildID, AuthorMode: routeAuthorName}
+	for _, option := range i.ApplicationCommandData().Options[0].Options {
+		switch option.Name {
+		case "from":
+			route.SourceChannelID = option.ChannelValue(s).ID
+		case "to":
+			route.DestinationChannelID = option.ChannelValue(s).ID
+		case "language":
+			route.TargetLang = strings.ToLower(strings.TrimSpace(option.StringValue()))
+		case "author":
+			route.AuthorMode = option.StringValue()
+		}
+	}
+
+	if route.SourceChannelID == route.DestinationChannelID {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "Error: A route needs two different channels.",
+		})
+		return
+	}
+
+	err := setRoute(route)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to add route: %s", err.Error()),
+		})
+		return
+	}
+
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: fmt.Sprintf("Messages in <#%s> will be translated into %s %s and posted to <#%s>.",
+			route.SourceChannelID, languageFlag(route.TargetLang), route.TargetLang, route.DestinationChannelID),
+	})
+}
+
+func handleRouteRemoveCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var sourceChannelID, destinationChannelID string
+	for _, option := range i.ApplicationCommandData().Options[0].Options {
+		switch option.Name {
+		case "from":
+			sourceChannelID = channelIDValue(option.StringValue())
+		case "to":
+			destinationChannelID = channelIDValue(option.StringValue())
+		}
+	}
+
+	if sourceChannelID == "" || destinationChannelID == "" {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "Error: Pick both channels from the suggestions.",
+		})
+		return
+	}
+
+	removed, err := removeRoute(i.GuildID, sourceChannelID, destinationChannelID)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to remove route: %s", err.Error()),
+		})
+		return
+	}
+
+	responseContent := fmt.Sprintf("There is no route from <#%s> to <#%s>.", sourceChannelID, destinationChannelID)
+	if removed {
+		responseContent = fmt.Sprintf("Removed the route from <#%s> to <#%s>.", sourceChannelID, destinationChannelID)
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
+
+func handleRouteListCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var lines []string
+	for _, channelRoutes := range routes {
+		for _, route := range channelRoutes {
+			if route.ServerID == i.GuildID {
+				lines = append(lines, fmt.Sprintf("<#%s> → <#%s> in %s %s (author: %s)",
+					route.SourceChannelID, route.DestinationChannelID, languageFlag(route.TargetLang), route.TargetLang, route.AuthorMode))
+			}
+		}
+	}
+
+	responseContent := "This server has no routes."
+	if len(lines) > 0 {
+		sort.Strings(lines)
+		responseContent = "Routes:\n" + strings.Join(lines, "\n")
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
+
+// handleTranslatePairCommand sets up a route in each direction between two
+// channels so each channel reads the other in its own language.
+func handleTranslatePairCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Member == nil || i.Member.Permissions&discordgo.PermissionManageServer == 0 {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "Error: Pairing channels requires the Manage Server permission.",
+		})
+		return
+	}
+
+	var channelA, channelB, languageA, languageB string
+	for _, option := range i.ApplicationCommandData().Options[0].Options {
+		switch option.Name {
+		case "channel_a":
+			channelA = option.ChannelValue(s).ID
+		case "channel_b":
+			channelB = option.ChannelValue(s).ID
+		case "language_a":
+			languageA = strings.ToLower(strings.TrimSpace(option.StringValue()))
+		case "language_b":
+			languageB = strings.ToLower(strings.TrimSpace(option.StringValue()))
+		}
+	}
+
+	if channelA == channelB {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "Error: A pair needs two different channels.",
+		})
+		return
+	}
+
+	for _, route := range []storage.Route{
+		{ServerID: i.GuildID, SourceChannelID: channelA, DestinationChannelID: channelB, TargetLang: languageB, AuthorMode: routeAuthorName},
+		{ServerID: i.GuildID, SourceChannelID: channelB, DestinationChannelID: channelA, TargetLang: languageA, AuthorMode: routeAuthorName},
+	} {
+		err := setRoute(route)
+		if err != nil {
+			respond(s, i, &discordgo.InteractionResponseData{
+				Content: fmt.Sprintf("Failed to pair channels: %s", err.Error()),
+			})
+			return
+		}
+	}
+
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: fmt.Sprintf("Paired <#%s> (%s %s) with <#%s> (%s %s). Messages in either channel are translated into the other. Use /route remove to undo either direction.",
+			channelA, languageFlag(languageA), languageA, channelB, languageFlag(languageB), languageB),
+	})
+}
+
+// hasRoutes reports whether the server has any routes.
+func hasRoutes(serverID string) bool {
+	for _, channelRoutes := range routes {
+		for _, route := range channelRoutes {
+			if route.ServerID == serverID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func setRoute(route storage.Route) error {
+	err := store.SetRoute(route)
+	if err == nil {
+		err = reloadShared(changeRoutes)
+	}
+	return err
+}
+
+func removeRoute(serverID, sourceChannelID, destinationChannelID string) (bool, error) {
+	removed, err := store.RemoveRoute(serverID, sourceChannelID, destinationChannelID)
+	if err == nil {
+		err = reloadShared(changeRoutes)
+	}
+	return removed, err
+}
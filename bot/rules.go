@@ -0,0 +1,137 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Embed descriptions are limited to 4096 characters.
+const maxEmbedDescription = 4096
+
+func handleRulesCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	subCommand := i.ApplicationCommandData().Options[0].Name
+
+	switch subCommand {
+	case "set":
+		handleRulesSetCommand(s, i)
+	case "publish":
+		handleRulesPublishCommand(s, i)
+	}
+}
+
+func handleRulesSetCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	rules := strings.ReplaceAll(i.ApplicationCommandData().Options[0].Options[0].StringValue(), "\\n", "\n")
+
+	err := setGuildSetting(i.GuildID, settingRulesText, rules)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to save rules: %s", err.Error()),
+		})
+		return
+	}
+
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: "Rules saved. Use /rules publish to post them.",
+	})
+}
+
+func handleRulesPublishCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	rules := getGuildSetting(i.GuildID, settingRulesText)
+	if rules == "" {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "Error: No rules have been saved. Use /rules set first.",
+		})
+		return
+	}
+
+	languagesValue := getGuildSetting(i.GuildID, settingRulesLanguages)
+	channelID := i.ChannelID
+	for _, option := range i.ApplicationCommandData().Options[0].Options {
+		if option.Name == "languages" {
+			languagesValue = option.StringValue()
+		} else if option.Name == "channel" {
+			channelID = option.ChannelValue(s).ID
+		}
+	}
+
+	languages := parseLanguages(languagesValue)
+
+	if missing := missingPermissions(s, channelID, discordgo.PermissionViewChannel|discordgo.PermissionSendMessages|discordgo.PermissionEmbedLinks); len(missing) > 0 {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Error: I'm missing permissions in <#%s>: %s.", channelID, strings.Join(missing, ", ")),
+		})
+		return
+	}
+
+	if !checkQuota(s, i.GuildID, len([]rune(rules))*len(languages)) {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "Error: This server's translation quota has been reached.",
+		})
+		return
+	}
+
+	embeds := []*discordgo.MessageEmbed{rulesEmbed(detectLanguage(rules), rules)}
+	for _, language := range languages {
+		translated, err := translateTo(i.GuildID, rules, language)
+		if err != nil {
+			respond(s, i, &discordgo.InteractionResponseData{
+				Content: fmt.Sprintf("Failed to translate rules into %s: %s", language, err.Error()),
+			})
+			return
+		}
+		if err := recordUsage(i.GuildID, len([]rune(rules))); err != nil {
+			log.Println("Error recording usage,", err)
+		}
+		embeds = append(embeds, rulesEmbed(language, translated))
+	}
+
+	// Replace the previously published series so the rules channel only ever
+	// shows the current version.
+	if previous := getGuildSetting(i.GuildID, settingRulesMessages); previous != "" {
+		previousChannelID, messageIDs, _ := strings.Cut(previous, ":")
+		for _, messageID := range strings.Split(messageIDs, ",") {
+			s.ChannelMessageDelete(previousChannelID, messageID)
+		}
+	}
+
+	var messageIDs []string
+	for _, embed := range embeds {
+		message, err := sendQueued(s, channelID, &discordgo.MessageSend{
+			Embeds:          []*discordgo.MessageEmbed{embed},
+			AllowedMentions: translationMentions,
+		})
+		if err != nil {
+			respond(s, i, &discordgo.InteractionResponseData{
+				Content: fmt.Sprintf("Failed to publish rules: %s", err.Error()),
+			})
+			return
+		}
+		messageIDs = append(messageIDs, message.ID)
+	}
+
+	err := setGuildSetting(i.GuildID, settingRulesMessages, channelID+":"+strings.Join(messageIDs, ","))
+	if err == nil {
+		err = setGuildSetting(i.GuildID, settingRulesLanguages, strings.Join(languages, ","))
+	}
+	if err != nil {
+		log.Println("Error saving published rules,", err)
+	}
+
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: fmt.Sprintf("Published the rules in %d languages to <#%s>.", len(embeds), channelID),
+	})
+}
+
+func rulesEmbed(language, text string) *discordgo.MessageEmbed {
+	if len([]rune(text)) > maxEmbedDescription {
+		text = string([]rune(text)[:maxEmbedDescription-1]) + "…"
+	}
+	return &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("%s Rules", languageFlag(language)),
+		Description: text,
+		Footer:      &discordgo.MessageEmbedFooter{Text: language},
+	}
+}
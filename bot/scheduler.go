@@ -0,0 +1,142 @@
+package bot
+
+import (
+	"expvar"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// job is background work the scheduler runs at a fixed interval. Shared jobs
+// work on the store for all guilds, so when several instances run only one of
+// them runs the job each interval.
+type job struct {
+	name   string
+	every  time.Duration
+	run    func(s *discordgo.Session)
+	shared bool
+}
+
+// jobs is the list of everything the bot does periodically.
+var jobs = []job{
+	{"weekly digests", time.Hour, postWeeklyDigests, true},
+	{"subscription digests", 5 * time.Minute, sendSubscriptionDigests, false},
+	{"message links", time.Hour, pruneMessageLinks, true},
+	{"cooldowns", 10 * time.Minute, pruneCooldowns, false},
+	{"skip log", time.Hour, pruneSkipLog, true},
+	{"banned words", 10 * time.Minute, pruneBannedWords, true},
+	{"warnings", time.Hour, pruneWarnings, true},
+	{"shard health", shardHealthInterval, reportShardHealth, false},
+	{"backend queue", 30 * time.Second, drainBackendQueues, false},
+	{"quiet hours", quietHoursInterval, updateQuietHours, false},
+}
+
+// jobMetrics counts how often a job ran, how often it panicked and how long
+// it took in total.
+type jobMetrics struct {
+	Runs     int64
+	Failures int64
+	Duration time.Duration
+	LastRun  time.Time
+}
+
+var (
+	jobMetricsMu sync.Mutex
+	jobStats     = make(map[string]*jobMetrics)
+)
+
+func init() {
+	expvar.Publish("jobs", expvar.Func(func() any {
+		jobMetricsMu.Lock()
+		defer jobMetricsMu.Unlock()
+		snapshot := make(map[string]jobMetrics, len(jobStats))
+		for name, metrics := range jobStats {
+			snapshot[name] = *metrics
+		}
+		return snapshot
+	}))
+}
+
+// runScheduler runs every job once per interval, first one interval after
+// startup. A job still running when it is due again is skipped that time.
+func runScheduler(s *discordgo.Session) {
+	now := time.Now()
+	next := make([]time.Time, len(jobs))
+	running := make([]bool, len(jobs))
+	var runningMu sync.Mutex
+	for n, j := range jobs {
+		next[n] = now.Add(j.every)
+	}
+
+	for {
+		soonest := next[0]
+		for _, t := range next[1:] {
+			if t.Before(soonest) {
+				soonest = t
+			}
+		}
+		time.Sleep(time.Until(soonest))
+
+		now := time.Now()
+		for n, j := range jobs {
+			if now.Before(next[n]) {
+				continue
+			}
+			next[n] = now.Add(j.every)
+
+			runningMu.Lock()
+			if running[n] {
+				runningMu.Unlock()
+				continue
+			}
+			running[n] = true
+			runningMu.Unlock()
+
+			// The lock runs out a little early so that the instance holding
+			// it can take it again next time despite timer jitter.
+			if j.shared && !sharedClaim("job:"+j.name, j.every*9/10) {
+				runningMu.Lock()
+				running[n] = false
+				runningMu.Unlock()
+				continue
+			}
+
+			go func(n int, j job) {
+				runJob(s, j)
+				runningMu.Lock()
+				running[n] = false
+				runningMu.Unlock()
+			}(n, j)
+		}
+	}
+}
+
+// runJob runs the job once, recording its metrics. A panicking job is logged
+// and runs again at its next interval.
+func runJob(s *discordgo.Session, j job) {
+	start := time.Now()
+	failed := true
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Job %q panicked: %v", j.name, r)
+		}
+		jobMetricsMu.Lock()
+		defer jobMetricsMu.Unlock()
+		metrics := jobStats[j.name]
+		if metrics == nil {
+			metrics = &jobMetrics{}
+			jobStats[j.name] = metrics
+		}
+		metrics.Runs++
+		if failed {
+			metrics.Failures++
+		}
+		metrics.Duration += time.Since(start)
+		metrics.LastRun = start
+	}()
+
+	j.run(s)
+	failed = false
+}
@@ -0,0 +1,125 @@
+package bot
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Encrypted secrets are stored with this prefix so plaintext values written
+// before encryption was enabled can still be told apart and migrated.
+const encryptedSecretPrefix = "enc:v1:"
+
+var errNoMasterKey = errors.New("API_KEY_MASTER_KEY environment variable is not set")
+
+func masterCipher(passphrase string) (cipher.AEAD, error) {
+	if passphrase == "" {
+		return nil, errNoMasterKey
+	}
+	key := sha256.Sum256([]byte(passphrase))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encryptSecretWith(passphrase, plaintext string) (string, error) {
+	aead, err := masterCipher(passphrase)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedSecretPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func decryptSecretWith(passphrase, stored string) (string, error) {
+	if !strings.HasPrefix(stored, encryptedSecretPrefix) {
+		return stored, nil
+	}
+
+	aead, err := masterCipher(passphrase)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(stored, encryptedSecretPrefix))
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("encrypted secret is too short")
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypting secret: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// encryptSecret encrypts a value for storage with the master key from the
+// environment.
+func encryptSecret(plaintext string) (string, error) {
+	return encryptSecretWith(os.Getenv("API_KEY_MASTER_KEY"), plaintext)
+}
+
+// decryptSecret reverses encryptSecret. Values stored before encryption was
+// enabled are returned unchanged.
+func decryptSecret(stored string) (string, error) {
+	return decryptSecretWith(os.Getenv("API_KEY_MASTER_KEY"), stored)
+}
+
+// RotateSecrets re-encrypts every stored guild API key with the current master
+// key. Keys are decrypted with API_KEY_MASTER_KEY_OLD, and plaintext keys from
+// before encryption was enabled are encrypted for the first time. Keys already
+// encrypted with the current master key are left alone, so a rotation that
+// stopped partway can be run again.
+func RotateSecrets() error {
+	oldPassphrase := os.Getenv("API_KEY_MASTER_KEY_OLD")
+	newPassphrase := os.Getenv("API_KEY_MASTER_KEY")
+	if newPassphrase == "" {
+		return errNoMasterKey
+	}
+
+	rotated, current := 0, 0
+	for serverID, guild := range settings.Guilds() {
+		stored := guild[settingAPIKey]
+		if stored == "" {
+			continue
+		}
+		if strings.HasPrefix(stored, encryptedSecretPrefix) {
+			if _, err := decryptSecretWith(newPassphrase, stored); err == nil {
+				current++
+				continue
+			}
+		}
+
+		plaintext, err := decryptSecretWith(oldPassphrase, stored)
+		if err != nil {
+			return fmt.Errorf("server %s: %w", serverID, err)
+		}
+		encrypted, err := encryptSecretWith(newPassphrase, plaintext)
+		if err != nil {
+			return fmt.Errorf("server %s: %w", serverID, err)
+		}
+		err = setGuildSetting(serverID, settingAPIKey, encrypted)
+		if err != nil {
+			return fmt.Errorf("server %s: %w", serverID, err)
+		}
+		rotated++
+	}
+
+	fmt.Printf("Re-encrypted %d API keys, %d already used the current master key.\n", rotated, current)
+	return nil
+}
@@ -0,0 +1,74 @@
+package bot
+
+import (
+	"errors"
+	"testing"
+)
+
+// setAPIKey stores the server's API key encrypted with the passphrase, or in
+// plaintext without one.
+func setAPIKey(t *testing.T, serverID, passphrase, key string) {
+	t.Helper()
+	stored := key
+	if passphrase != "" {
+		var err error
+		if stored, err = encryptSecretWith(passphrase, key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := setGuildSetting(serverID, settingAPIKey, stored); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// checkAPIKey checks that the server's API key decrypts with the passphrase.
+func checkAPIKey(t *testing.T, serverID, passphrase, want string) {
+	t.Helper()
+	stored := getGuildSetting(serverID, settingAPIKey)
+	got, err := decryptSecretWith(passphrase, stored)
+	if err != nil || got != want || stored == want {
+		t.Errorf("API key of server %s = %q, %v, want %q encrypted with the new key", serverID, got, err, want)
+	}
+}
+
+func TestRotateSecrets(t *testing.T) {
+	initTestStore(t)
+	t.Setenv("API_KEY_MASTER_KEY_OLD", "old")
+	t.Setenv("API_KEY_MASTER_KEY", "new")
+	setAPIKey(t, "encrypted", "old", "key1")
+	setAPIKey(t, "plaintext", "", "key2")
+
+	if err := RotateSecrets(); err != nil {
+		t.Fatal(err)
+	}
+	checkAPIKey(t, "encrypted", "new", "key1")
+	checkAPIKey(t, "plaintext", "new", "key2")
+}
+
+func TestRotateSecretsRerun(t *testing.T) {
+	initTestStore(t)
+	t.Setenv("API_KEY_MASTER_KEY_OLD", "old")
+	t.Setenv("API_KEY_MASTER_KEY", "new")
+	// A rotation that stopped partway left one key rotated and one not.
+	setAPIKey(t, "rotated", "new", "key1")
+	setAPIKey(t, "pending", "old", "key2")
+
+	if err := RotateSecrets(); err != nil {
+		t.Fatal(err)
+	}
+	checkAPIKey(t, "rotated", "new", "key1")
+	checkAPIKey(t, "pending", "new", "key2")
+	if err := RotateSecrets(); err != nil {
+		t.Errorf("running the rotation again failed: %v", err)
+	}
+}
+
+func TestRotateSecretsNoMasterKey(t *testing.T) {
+	initTestStore(t)
+	t.Setenv("API_KEY_MASTER_KEY_OLD", "old")
+	t.Setenv("API_KEY_MASTER_KEY", "")
+
+	if err := RotateSecrets(); !errors.Is(err, errNoMasterKey) {
+		t.Errorf("RotateSecrets() = %v, want %v", err, errNoMasterKey)
+	}
+}
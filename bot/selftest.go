@@ -0,0 +1,72 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"translate-bot/translation"
+)
+
+// selfTestTimeout bounds how long the startup self-test waits for a backend.
+const selfTestTimeout = 30 * time.Second
+
+// selfTestBackends runs a test translation through each configured backend
+// before the bot connects. BACKEND_SELF_TEST picks what happens when one
+// fails: "strict" refuses to start, "off" skips the test, and by default the
+// bot starts degraded with the backend's breaker open, so messages wait for
+// it to recover instead of each failing in turn.
+func selfTestBackends() error {
+	mode := os.Getenv("BACKEND_SELF_TEST")
+	if mode == "off" {
+		return nil
+	}
+
+	backends := []translation.Backend{activeBackend}
+	if premiumBackend != nil {
+		backends = append(backends, premiumBackend)
+	}
+	for _, b := range backends {
+		start := time.Now()
+		err := selfTest(b)
+		if err == nil {
+			log.Printf("Translation backend %s passed its self-test in %s", b.Name(), time.Since(start).Round(time.Millisecond))
+			continue
+		}
+		if mode == "strict" {
+			return fmt.Errorf("translation backend %s failed its self-test: %w", b.Name(), err)
+		}
+		log.Printf("WARNING: translation backend %s failed its self-test, starting degraded: %s", b.Name(), err)
+		breakerTrip(breakerKey(b))
+	}
+	return nil
+}
+
+// selfTest translates a short phrase with the backend, giving up after
+// selfTestTimeout.
+func selfTest(b translation.Backend) error {
+	type result struct {
+		translated string
+		err        error
+	}
+	done := make(chan result, 1)
+	go func() {
+		translated, err := translation.Translate(b, "Good morning, everyone!", "es", translation.Options{})
+		done <- result{translated, err}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			return r.err
+		}
+		if r.translated == "" {
+			return errors.New("the translation came back empty")
+		}
+		return nil
+	case <-time.After(selfTestTimeout):
+		return fmt.Errorf("no answer within %s", selfTestTimeout)
+	}
+}
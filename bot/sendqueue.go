@@ -0,0 +1,314 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// Discord allows five messages per channel every five seconds.
+	channelRateLimit  = 5
+	channelRateWindow = 5 * time.Second
+
+	// maxMessageLength is the most content a single message may carry, which
+	// bounds how many queued messages are coalesced into one.
+	maxMessageLength = 2000
+
+	// sendQueueIdle is how long a channel's queue waits for new messages
+	// before its worker exits.
+	sendQueueIdle = time.Minute
+
+	// sendRetries is how often a failed send is retried, doubling the delay
+	// from sendRetryDelay each time, before it is dead-lettered.
+	sendRetries    = 5
+	sendRetryDelay = 2 * time.Second
+)
+
+// translationMentions lets translations mention users, as the original
+// did, but never @everyone, @here or roles: those show as text without
+// pinging anyone a second time.
+var translationMentions = &discordgo.MessageAllowedMentions{
+	Parse: []discordgo.AllowedMentionType{discordgo.AllowedMentionTypeUsers},
+}
+
+// sendMessage posts the content to the channel with translationMentions.
+func sendMessage(s *discordgo.Session, channelID, content string) (*discordgo.Message, error) {
+	return s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Content:         content,
+		AllowedMentions: translationMentions,
+	})
+}
+
+var errChannelNotWritable = errors.New("missing permissions to post in the channel")
+
+// queuedMessage is a message waiting in a channel's send queue. Messages
+// with a sent callback are never coalesced, so the callback gets the message
+// its content was posted as, and neither are those with a send of their own.
+type queuedMessage struct {
+	content string
+	// send, when set, is posted instead of content, for messages with
+	// embeds, files or allowed mentions of their own.
+	send   *discordgo.MessageSend
+	sent   func(message *discordgo.Message)
+	failed func(err error)
+}
+
+// coalescable reports whether the message may be merged with others.
+func (m queuedMessage) coalescable() bool {
+	return m.sent == nil && m.send == nil
+}
+
+// deliver posts the message. Files are read from the start again on retries.
+func (m queuedMessage) deliver(s *discordgo.Session, channelID string) (*discordgo.Message, error) {
+	if m.send == nil {
+		return sendMessage(s, channelID, m.content)
+	}
+	for _, file := range m.send.Files {
+		if seeker, ok := file.Reader.(io.Seeker); ok {
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return s.ChannelMessageSendComplex(channelID, m.send)
+}
+
+// fail reports the error to the message's failed callback, if it has one.
+func (m queuedMessage) fail(err error) {
+	if m.failed != nil {
+		m.failed(err)
+	}
+}
+
+// sendQueue holds the messages waiting to be sent to a channel. pending
+// counts those enqueued but not yet taken off by the worker, so the worker
+// doesn't exit while a message is on its way in.
+type sendQueue struct {
+	messages chan queuedMessage
+	pending  int
+}
+
+var (
+	sendQueuesMu sync.Mutex
+	// sendQueues holds the queue of every channel with a running worker.
+	// pending is guarded by sendQueuesMu too.
+	sendQueues = make(map[string]*sendQueue)
+)
+
+// queueMessage posts the content to the channel in the background. Messages
+// to the same channel are sent in order, paced to stay within Discord's rate
+// limit, and bursts are coalesced into fewer messages.
+func queueMessage(s *discordgo.Session, channelID, content string) {
+	enqueue(s, channelID, queuedMessage{content: content})
+}
+
+// queueMessageThen queues the content like queueMessage and calls sent with
+// the posted message once it has been delivered. A nil sent makes it the
+// same as queueMessage.
+func queueMessageThen(s *discordgo.Session, channelID, content string, sent func(message *discordgo.Message)) {
+	enqueue(s, channelID, queuedMessage{content: content, sent: sent})
+}
+
+// queueSend posts the message through the channel's send queue in the
+// background, like queueMessage, for messages that need more than content.
+func queueSend(s *discordgo.Session, channelID string, send *discordgo.MessageSend) {
+	enqueue(s, channelID, queuedMessage{content: send.Content, send: send})
+}
+
+// sendQueued posts the message through the channel's send queue and waits
+// until it has been delivered, for callers that need the posted message or
+// the error.
+func sendQueued(s *discordgo.Session, channelID string, send *discordgo.MessageSend) (*discordgo.Message, error) {
+	if simulatedPost != nil {
+		simulatedPost(channelID, send.Content)
+		return &discordgo.Message{ChannelID: channelID, Content: send.Content}, nil
+	}
+
+	type result struct {
+		message *discordgo.Message
+		err     error
+	}
+	done := make(chan result, 1)
+	enqueue(s, channelID, queuedMessage{
+		content: send.Content,
+		send:    send,
+		sent:    func(message *discordgo.Message) { done <- result{message: message} },
+		failed:  func(err error) { done <- result{err: err} },
+	})
+	r := <-done
+	return r.message, r.err
+}
+
+func enqueue(s *discordgo.Session, channelID string, message queuedMessage) {
+	if simulatedPost != nil {
+		simulatedPost(channelID, message.content)
+		return
+	}
+
+	sendQueuesMu.Lock()
+	queue, exists := sendQueues[channelID]
+	if !exists {
+		queue = &sendQueue{messages: make(chan queuedMessage, 100)}
+		sendQueues[channelID] = queue
+		go runSendQueue(s, channelID, queue)
+	}
+	queue.pending++
+	sendQueuesMu.Unlock()
+
+	queue.messages <- message
+}
+
+// take removes the next message from the queue, if one is waiting.
+func (q *sendQueue) take() (queuedMessage, bool) {
+	select {
+	case message := <-q.messages:
+		sendQueuesMu.Lock()
+		q.pending--
+		sendQueuesMu.Unlock()
+		return message, true
+	default:
+		return queuedMessage{}, false
+	}
+}
+
+func runSendQueue(s *discordgo.Session, channelID string, queue *sendQueue) {
+	var sent []time.Time
+	// pace waits until the oldest send in the window has expired, so every
+	// send, retries included, stays within the rate limit.
+	pace := func() {
+		if len(sent) == channelRateLimit {
+			time.Sleep(time.Until(sent[0].Add(channelRateWindow)))
+			sent = sent[1:]
+		}
+		sent = append(sent, time.Now())
+	}
+
+	var leftover *queuedMessage
+	for {
+		var next queuedMessage
+		if leftover != nil {
+			next, leftover = *leftover, nil
+		} else {
+			select {
+			case next = <-queue.messages:
+				sendQueuesMu.Lock()
+				queue.pending--
+				sendQueuesMu.Unlock()
+			case <-time.After(sendQueueIdle):
+				sendQueuesMu.Lock()
+				if queue.pending > 0 {
+					sendQueuesMu.Unlock()
+					continue
+				}
+				delete(sendQueues, channelID)
+				sendQueuesMu.Unlock()
+				return
+			}
+		}
+
+		pace()
+		if next.coalescable() {
+			next.content, leftover = coalesce(next.content, queue)
+		}
+		if !channelWritable(s, channelID, discordgo.PermissionViewChannel|discordgo.PermissionSendMessages) {
+			next.fail(errChannelNotWritable)
+			continue
+		}
+
+		// Failed sends are retried here rather than requeued, so later
+		// messages wait for them and the channel stays in order.
+		message, err := next.deliver(s, channelID)
+		delay := sendRetryDelay
+		for attempt := 0; err != nil && isRetryable(err) && attempt < sendRetries; attempt++ {
+			log.Println("Error sending message, retrying,", err)
+			time.Sleep(delay)
+			delay *= 2
+			pace()
+			message, err = next.deliver(s, channelID)
+		}
+		switch {
+		case err == nil:
+			if next.sent != nil {
+				next.sent(message)
+			}
+		case isMissingPermissions(err):
+			log.Println("Error sending message,", err)
+			markUnhealthy(s, channelID, "access denied by Discord")
+			next.fail(err)
+		case isRetryable(err):
+			log.Println("Giving up sending message,", err)
+			deadLetter(s, channelID, next.content, err)
+			next.fail(err)
+		default:
+			log.Println("Error sending message,", err)
+			next.fail(err)
+		}
+	}
+}
+
+// coalesce appends messages already waiting in the queue to the content for
+// as long as the result fits in a single message. The first message that
+// doesn't fit, or that can't be coalesced, is returned separately so it can
+// be sent next.
+func coalesce(content string, queue *sendQueue) (string, *queuedMessage) {
+	length := len([]rune(content))
+	var combined strings.Builder
+	combined.WriteString(content)
+	for {
+		next, ok := queue.take()
+		if !ok {
+			return combined.String(), nil
+		}
+		nextLength := len([]rune(next.content))
+		if !next.coalescable() || length+1+nextLength > maxMessageLength {
+			return combined.String(), &next
+		}
+		combined.WriteString("\n")
+		combined.WriteString(next.content)
+		length += 1 + nextLength
+	}
+}
+
+// isRetryable reports whether a failed send may succeed later: network errors
+// and server-side failures are, rejected requests are not.
+func isRetryable(err error) bool {
+	var restErr *discordgo.RESTError
+	if errors.As(err, &restErr) {
+		return restErr.Response != nil && restErr.Response.StatusCode >= http.StatusInternalServerError
+	}
+	return true
+}
+
+// deadLetter posts a message that couldn't be delivered to the guild's log
+// channel, so the translation isn't lost silently.
+func deadLetter(s *discordgo.Session, channelID, content string, err error) {
+	channel, stateErr := s.State.Channel(channelID)
+	if stateErr != nil || channelID == adminChannel(s, channel.GuildID) {
+		return
+	}
+	notifyAdmins(s, channel.GuildID, deadLetterNotice(channelID, content, err))
+}
+
+// deadLetterNotice reports the failed delivery with its content, cutting the
+// error and content short to fit in one message.
+func deadLetterNotice(channelID, content string, err error) string {
+	reason := err.Error()
+	if runes := []rune(reason); len(runes) > 200 {
+		reason = string(runes[:200]) + "…"
+	}
+	notice := fmt.Sprintf("Failed to deliver a message to <#%s> (%s):\n", channelID, reason)
+	room := maxMessageLength - utf8.RuneCountInString(notice)
+	if runes := []rune(content); len(runes) > room {
+		content = string(runes[:room-1]) + "…"
+	}
+	return notice + content
+}
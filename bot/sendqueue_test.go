@@ -0,0 +1,128 @@
+package bot
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestDeadLetterNotice(t *testing.T) {
+	tests := []struct {
+		content string
+		err     error
+	}{
+		{"short translation", errors.New("HTTP 502 Bad Gateway")},
+		{strings.Repeat("ü", maxMessageLength), errors.New("HTTP 503 Service Unavailable")},
+		{strings.Repeat("a", maxMessageLength), errors.New(strings.Repeat("x", 5000))},
+	}
+	for _, test := range tests {
+		notice := deadLetterNotice("123", test.content, test.err)
+		if length := utf8.RuneCountInString(notice); length > maxMessageLength {
+			t.Errorf("notice for %d characters is %d characters long", len(test.content), length)
+		}
+		if !strings.HasPrefix(notice, "Failed to deliver a message to <#123>") {
+			t.Errorf("notice = %q", notice)
+		}
+	}
+	if notice := deadLetterNotice("123", "hola", errors.New("boom")); !strings.HasSuffix(notice, "\nhola") {
+		t.Errorf("short content was changed: %q", notice)
+	}
+}
+
+func TestCoalesce(t *testing.T) {
+	newQueue := func(messages ...queuedMessage) *sendQueue {
+		queue := &sendQueue{messages: make(chan queuedMessage, len(messages)+1), pending: len(messages)}
+		for _, message := range messages {
+			queue.messages <- message
+		}
+		return queue
+	}
+
+	queue := newQueue()
+	if content, next := coalesce("one", queue); content != "one" || next != nil {
+		t.Errorf("coalesce() on an empty queue = %q, %v", content, next)
+	}
+
+	queue = newQueue(queuedMessage{content: "two"}, queuedMessage{content: "three"})
+	if content, next := coalesce("one", queue); content != "one\ntwo\nthree" || next != nil {
+		t.Errorf("coalesce() = %q, %v, want all three joined", content, next)
+	}
+	if queue.pending != 0 {
+		t.Errorf("pending = %d after taking every message, want 0", queue.pending)
+	}
+
+	// Messages whose sent message is needed are sent on their own.
+	tracked := queuedMessage{content: "tracked", sent: func(*discordgo.Message) {}}
+	queue = newQueue(queuedMessage{content: "two"}, tracked, queuedMessage{content: "four"})
+	content, next := coalesce("one", queue)
+	if content != "one\ntwo" || next == nil || next.content != "tracked" {
+		t.Errorf("coalesce() = %q, %v, want to stop at the tracked message", content, next)
+	}
+	if len(queue.messages) != 1 || queue.pending != 1 {
+		t.Errorf("%d messages left with %d pending, want 1 and 1", len(queue.messages), queue.pending)
+	}
+
+	// So are messages with a send of their own.
+	embed := queuedMessage{send: &discordgo.MessageSend{Embeds: []*discordgo.MessageEmbed{{Title: "embed"}}}}
+	queue = newQueue(queuedMessage{content: "two"}, embed)
+	content, next = coalesce("one", queue)
+	if content != "one\ntwo" || next == nil || next.send == nil {
+		t.Errorf("coalesce() = %q, %v, want to stop at the embed", content, next)
+	}
+
+	// The result stays within one message.
+	long := strings.Repeat("a", maxMessageLength-5)
+	queue = newQueue(queuedMessage{content: "short"}, queuedMessage{content: long})
+	content, next = coalesce("one", queue)
+	if content != "one\nshort" || next == nil || next.content != long {
+		t.Errorf("coalesce() = %q, %v, want to stop before the message that doesn't fit", content, next)
+	}
+	if length := utf8.RuneCountInString(content); length > maxMessageLength {
+		t.Errorf("coalesced content is %d characters long", length)
+	}
+}
+
+// forbiddenTransport answers every request as Discord does when the bot
+// lacks permissions.
+type forbiddenTransport struct{}
+
+func (forbiddenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusForbidden,
+		Status:     "403 Forbidden",
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(`{"message": "Missing Permissions", "code": 50013}`)),
+		Request:    req,
+	}, nil
+}
+
+// TestSendQueuedFailure checks that a message the queue can't deliver
+// reports the error rather than leaving the sender waiting.
+func TestSendQueuedFailure(t *testing.T) {
+	initTestStore(t)
+	s, err := simulationSession("guild", "channel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Client = &http.Client{Transport: forbiddenTransport{}}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := sendQueued(s, "channel", &discordgo.MessageSend{Content: "hello"})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("sendQueued() succeeded without a connection to Discord")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("sendQueued() didn't return")
+	}
+}
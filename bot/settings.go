@@ -0,0 +1,366 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	settingTranslationsChannel = "translations_channel"
+	settingTemplate            = "template"
+	settingJumpLinks           = "jump_links"
+	settingAnonymize           = "anonymize"
+	settingLogChannel          = "log_channel"
+	settingDailyQuota          = "daily_quota"
+	settingMonthlyQuota        = "monthly_quota"
+	settingLicense             = "license"
+	settingDigestChannel       = "digest_channel"
+	settingDigestLastSent      = "digest_last_sent"
+	settingAPIProvider         = "api_provider"
+	settingAPIKey              = "api_key"
+	settingWelcomeChannel      = "welcome_channel"
+	settingWelcomeMessage      = "welcome_message"
+	settingRulesText           = "rules_text"
+	settingRulesLanguages      = "rules_languages"
+	settingRulesMessages       = "rules_messages"
+	settingForumRename         = "forum_rename"
+	settingNickSuggest         = "nick_suggest"
+	settingBackTranslate       = "back_translate"
+	settingShowConfidence      = "show_confidence"
+	settingProfanity           = "profanity"
+	settingLLMPreset           = "llm_preset"
+	settingUseLLM              = "use_llm"
+	settingContextMessages     = "context_messages"
+	settingContextRedaction    = "context_redaction"
+	settingOnboarded           = "onboarded"
+	settingTargetLanguage      = "target_language"
+	settingDryRun              = "dry_run"
+	settingWebhookURL          = "webhook_url"
+	settingWebhookEvents       = "webhook_events"
+	settingEditWindow          = "edit_window"
+	settingArchiveJob          = "archive_job"
+	settingCatchUp             = "catch_up"
+	settingBackendQueue        = "backend_queue"
+	settingRaceBackends        = "race_backends"
+	settingStreamTranslations  = "stream_translations"
+	settingTranslateRoles      = "translate_roles"
+	settingQuietHours          = "quiet_hours"
+	settingQuietChannel        = "quiet_channel"
+	settingTimezone            = "timezone"
+	settingPaused              = "paused"
+	settingSkipLog             = "skip_log"
+	settingModRole             = "mod_role"
+	settingModChannel          = "mod_channel"
+	settingToxicity            = "toxicity_threshold"
+	settingBanwordPacks        = "banword_packs"
+	settingBanwordExempt       = "banword_exempt"
+	settingTimeoutHits         = "timeout_warnings"
+	settingTimeoutMinutes      = "timeout_minutes"
+
+	settingStyle             = "style"
+	settingAnnounceLanguages = "announce_languages"
+	settingFormality         = "formality"
+	settingLastMessage       = "last_message"
+)
+
+const (
+	styleDefault = "default"
+	styleMinimal = "minimal"
+	styleSpoiler = "spoiler"
+	styleCompact = "compact"
+)
+
+func getBotSetting(key string) string {
+	return settings.Bot(key)
+}
+
+// setBotSetting stores a setting of the bot as a whole. An empty value
+// removes it.
+func setBotSetting(key, value string) error {
+	if err := settings.SetBot(key, value); err != nil {
+		return err
+	}
+	publishChange(change{Kind: changeBotSetting, Key: key, Value: value})
+	return nil
+}
+
+func getGuildSetting(serverID, key string) string {
+	return settings.Guild(serverID, key)
+}
+
+// setGuildSetting stores a setting for the server. An empty value removes it.
+func setGuildSetting(serverID, key, value string) error {
+	if err := settings.SetGuild(serverID, key, value); err != nil {
+		return err
+	}
+	publishChange(change{Kind: changeGuildSetting, ServerID: serverID, Key: key, Value: value})
+	return nil
+}
+
+func getChannelSetting(channelID, key string) string {
+	return settings.Channel(channelID, key)
+}
+
+// setChannelSetting stores a setting for the channel. An empty value removes it.
+func setChannelSetting(serverID, channelID, key, value string) error {
+	if err := settings.SetChannel(serverID, channelID, key, value); err != nil {
+		return err
+	}
+	publishChange(change{Kind: changeChannelSetting, ServerID: serverID, ChannelID: channelID, Key: key, Value: value})
+	return nil
+}
+
+func handleConfigCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	subCommand := i.ApplicationCommandData().Options[0].Name
+
+	switch subCommand {
+	case "translations":
+		handleConfigTranslationsCommand(s, i)
+	case "template":
+		handleConfigTemplateCommand(s, i)
+	case "style":
+		handleConfigStyleCommand(s, i)
+	case "jumplinks":
+		handleConfigJumpLinksCommand(s, i)
+	case "anonymize":
+		handleConfigAnonymizeCommand(s, i)
+	case "logchannel":
+		handleConfigLogChannelCommand(s, i)
+	case "quota":
+		handleConfigQuotaCommand(s, i)
+	case "digest":
+		handleConfigDigestCommand(s, i)
+	case "announce":
+		handleConfigAnnounceCommand(s, i)
+	case "forumrename":
+		handleConfigForumRenameCommand(s, i)
+	case "nicksuggest":
+		handleConfigNickSuggestCommand(s, i)
+	case "backtranslate":
+		handleConfigBackTranslateCommand(s, i)
+	case "confidence":
+		handleConfigConfidenceCommand(s, i)
+	case "formality":
+		handleConfigFormalityCommand(s, i)
+	case "profanity":
+		handleConfigProfanityCommand(s, i)
+	case "preset":
+		handleConfigPresetCommand(s, i)
+	case "context":
+		handleConfigContextCommand(s, i)
+	case "dryrun":
+		handleConfigDryRunCommand(s, i)
+	case "webhook":
+		handleConfigWebhookCommand(s, i)
+	case "editwindow":
+		handleConfigEditWindowCommand(s, i)
+	case "catchup":
+		handleConfigCatchUpCommand(s, i)
+	case "backendqueue":
+		handleConfigBackendQueueCommand(s, i)
+	case "quiethours":
+		handleConfigQuietHoursCommand(s, i)
+	case "timezone":
+		handleConfigTimezoneCommand(s, i)
+	case "skiplog":
+		handleConfigSkipLogCommand(s, i)
+	}
+}
+
+func handleConfigTranslationsCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var channel *discordgo.Channel
+	for _, option := range i.ApplicationCommandData().Options[0].Options {
+		if option.Name == "channel" {
+			channel = option.ChannelValue(s)
+		}
+	}
+
+	channelID := ""
+	if channel != nil {
+		channelID = channel.ID
+	}
+
+	err := setGuildSetting(i.GuildID, settingTranslationsChannel, channelID)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to update translations channel: %s", err.Error()),
+		})
+		return
+	}
+
+	responseContent := "Translations will be posted in the channel they were written in."
+	if channel != nil {
+		responseContent = fmt.Sprintf("All translations will be posted to %s.", channel.Mention())
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
+
+func handleConfigTemplateCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	template := ""
+	for _, option := range i.ApplicationCommandData().Options[0].Options {
+		if option.Name == "text" {
+			template = strings.ReplaceAll(option.StringValue(), "\\n", "\n")
+		}
+	}
+
+	if template != "" && !strings.Contains(template, "{translation}") {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "Error: The template must contain the {translation} placeholder.",
+		})
+		return
+	}
+
+	err := setGuildSetting(i.GuildID, settingTemplate, template)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to update template: %s", err.Error()),
+		})
+		return
+	}
+
+	responseContent := "Translation template reset to the default."
+	if template != "" {
+		responseContent = fmt.Sprintf("Translation template set to: %s", template)
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
+
+func handleConfigStyleCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var channel *discordgo.Channel
+	var style string
+	for _, option := range i.ApplicationCommandData().Options[0].Options {
+		if option.Name == "channel" {
+			channel = option.ChannelValue(s)
+		} else if option.Name == "mode" {
+			style = option.StringValue()
+		}
+	}
+
+	value := style
+	if style == styleDefault {
+		value = ""
+	}
+	err := setChannelSetting(i.GuildID, channel.ID, settingStyle, value)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to update output style: %s", err.Error()),
+		})
+		return
+	}
+
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: fmt.Sprintf("Output style for %s set to %s.", channel.Mention(), style),
+	})
+}
+
+func handleConfigFormalityCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var channel *discordgo.Channel
+	var tone string
+	for _, option := range i.ApplicationCommandData().Options[0].Options {
+		if option.Name == "channel" {
+			channel = option.ChannelValue(s)
+		} else if option.Name == "tone" {
+			tone = option.StringValue()
+		}
+	}
+
+	value := tone
+	if tone == "default" {
+		value = ""
+	}
+	err := setChannelSetting(i.GuildID, channel.ID, settingFormality, value)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to update formality: %s", err.Error()),
+		})
+		return
+	}
+
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: fmt.Sprintf("Formality for %s set to %s.", channel.Mention(), tone),
+	})
+}
+
+func handleConfigJumpLinksCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	enabled := i.ApplicationCommandData().Options[0].Options[0].BoolValue()
+
+	value := ""
+	if enabled {
+		value = "on"
+	}
+	err := setGuildSetting(i.GuildID, settingJumpLinks, value)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to update jump links: %s", err.Error()),
+		})
+		return
+	}
+
+	responseContent := "Jump links will only be added in the dedicated translations channel."
+	if enabled {
+		responseContent = "Jump links to the original message will be added to all translations."
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
+
+func handleConfigAnonymizeCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	enabled := i.ApplicationCommandData().Options[0].Options[0].BoolValue()
+
+	value := ""
+	if enabled {
+		value = "on"
+	}
+	err := setGuildSetting(i.GuildID, settingAnonymize, value)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to update anonymized mirroring: %s", err.Error()),
+		})
+		return
+	}
+
+	responseContent := "Mirrored translations will show the author's name."
+	if enabled {
+		responseContent = "Mirrored translations will be posted as \"Member\" without the author's name."
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
+
+func handleConfigLogChannelCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var channel *discordgo.Channel
+	for _, option := range i.ApplicationCommandData().Options[0].Options {
+		if option.Name == "channel" {
+			channel = option.ChannelValue(s)
+		}
+	}
+
+	channelID := ""
+	if channel != nil {
+		channelID = channel.ID
+	}
+
+	err := setGuildSetting(i.GuildID, settingLogChannel, channelID)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to update log channel: %s", err.Error()),
+		})
+		return
+	}
+
+	responseContent := "Admin notifications will be posted in the server's system channel."
+	if channel != nil {
+		responseContent = fmt.Sprintf("Admin notifications will be posted to %s.", channel.Mention())
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
@@ -0,0 +1,223 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxSelectOptions is the most options Discord allows in a select menu.
+const maxSelectOptions = 25
+
+// setupState holds an admin's answers while they go through /setup.
+type setupState struct {
+	channelIDs []string
+	language   string
+	style      string
+}
+
+var (
+	setupStatesMu sync.Mutex
+	setupStates   = make(map[string]*setupState)
+)
+
+func setupKey(i *discordgo.InteractionCreate) string {
+	return i.GuildID + ":" + i.Member.User.ID
+}
+
+func handleSetupCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	setupStatesMu.Lock()
+	setupStates[setupKey(i)] = &setupState{}
+	setupStatesMu.Unlock()
+
+	respond(s, i, setupChannelsStep())
+}
+
+func setupChannelsStep() *discordgo.InteractionResponseData {
+	minValues := 1
+	return &discordgo.InteractionResponseData{
+		Content: "**Setup 1/3:** Which channels should be translated?",
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				discordgo.SelectMenu{
+					MenuType:     discordgo.ChannelSelectMenu,
+					CustomID:     "setup:channels",
+					Placeholder:  "Choose up to 3 channels",
+					MinValues:    &minValues,
+					MaxValues:    3,
+					ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText, discordgo.ChannelTypeGuildNews, discordgo.ChannelTypeGuildForum},
+				},
+			}},
+		},
+	}
+}
+
+func setupLanguageStep() *discordgo.InteractionResponseData {
+	codes := make([]string, 0, len(languageCountries))
+	for code := range languageCountries {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	// A select menu holds at most maxSelectOptions, so the languages are
+	// spread over as many menus as they need, one per row.
+	var rows []discordgo.MessageComponent
+	for page := 0; page*maxSelectOptions < len(codes); page++ {
+		pageCodes := codes[page*maxSelectOptions : min((page+1)*maxSelectOptions, len(codes))]
+		var options []discordgo.SelectMenuOption
+		for _, code := range pageCodes {
+			options = append(options, discordgo.SelectMenuOption{
+				Label:   fmt.Sprintf("%s %s", languageFlag(code), code),
+				Value:   code,
+				Default: code == targetLanguage,
+			})
+		}
+		rows = append(rows, discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.SelectMenu{
+				CustomID:    fmt.Sprintf("setup:language:%d", page+1),
+				Placeholder: fmt.Sprintf("Target language (%s–%s)", pageCodes[0], pageCodes[len(pageCodes)-1]),
+				Options:     options,
+			},
+		}})
+	}
+	return &discordgo.InteractionResponseData{
+		Content:    "**Setup 2/3:** Which language should messages be translated into?",
+		Components: rows,
+	}
+}
+
+func setupStyleStep() *discordgo.InteractionResponseData {
+	return &discordgo.InteractionResponseData{
+		Content: "**Setup 3/3:** How should translations look?",
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				discordgo.SelectMenu{
+					CustomID:    "setup:style",
+					Placeholder: "Output style",
+					Options: []discordgo.SelectMenuOption{
+						{Label: "Default (server template)", Value: styleDefault},
+						{Label: "Minimal (translation only)", Value: styleMinimal},
+						{Label: "Spoiler (original hidden below)", Value: styleSpoiler},
+						{Label: "Compact (flags and author on one line)", Value: styleCompact},
+					},
+				},
+			}},
+		},
+	}
+}
+
+func setupSummaryStep(state *setupState) *discordgo.InteractionResponseData {
+	var channels []string
+	for _, channelID := range state.channelIDs {
+		channels = append(channels, fmt.Sprintf("<#%s>", channelID))
+	}
+	return &discordgo.InteractionResponseData{
+		Content: fmt.Sprintf("**Review:**\nChannels: %s\nTarget language: %s %s\nStyle: %s",
+			strings.Join(channels, ", "), languageFlag(state.language), state.language, state.style),
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				discordgo.Button{Label: "Save", Style: discordgo.SuccessButton, CustomID: "setup:save"},
+				discordgo.Button{Label: "Cancel", Style: discordgo.SecondaryButton, CustomID: "setup:cancel"},
+			}},
+		},
+	}
+}
+
+// handleSetupComponent advances the wizard when an admin answers a step.
+func handleSetupComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.MessageComponentData()
+
+	setupStatesMu.Lock()
+	state := setupStates[setupKey(i)]
+	setupStatesMu.Unlock()
+	if state == nil {
+		updateSetupMessage(s, i, &discordgo.InteractionResponseData{
+			Content:    "This setup has expired. Run /setup again.",
+			Components: []discordgo.MessageComponent{},
+		})
+		return
+	}
+
+	// The language step spreads its options over several menus.
+	customID := data.CustomID
+	if strings.HasPrefix(customID, "setup:language:") {
+		customID = "setup:language"
+	}
+
+	var next *discordgo.InteractionResponseData
+	switch customID {
+	case "setup:channels":
+		state.channelIDs = data.Values
+		next = setupLanguageStep()
+	case "setup:language":
+		state.language = data.Values[0]
+		next = setupStyleStep()
+	case "setup:style":
+		state.style = data.Values[0]
+		next = setupSummaryStep(state)
+	case "setup:save":
+		next = &discordgo.InteractionResponseData{Content: "✅ Setup complete. Translation is now enabled."}
+		if err := saveSetup(i.GuildID, state); err != nil {
+			next.Content = fmt.Sprintf("Failed to save the configuration: %s", err.Error())
+		}
+	case "setup:cancel":
+		next = &discordgo.InteractionResponseData{Content: "Setup cancelled. Nothing was changed."}
+	default:
+		return
+	}
+
+	// The last step removes the components so the wizard can't be reused.
+	if next.Components == nil {
+		next.Components = []discordgo.MessageComponent{}
+		setupStatesMu.Lock()
+		delete(setupStates, setupKey(i))
+		setupStatesMu.Unlock()
+	}
+	updateSetupMessage(s, i, next)
+}
+
+func updateSetupMessage(s *discordgo.Session, i *discordgo.InteractionCreate, data *discordgo.InteractionResponseData) {
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: data,
+	})
+	if err != nil {
+		log.Println("Error updating setup message,", err)
+	}
+}
+
+// saveSetup writes the configuration collected by the wizard, replacing the
+// server's translated channels.
+func saveSetup(serverID string, state *setupState) error {
+	var channelIDs [3]string
+	copy(channelIDs[:], state.channelIDs)
+	err := setTranslateChannels(serverID, channelIDs)
+	if err != nil {
+		return err
+	}
+
+	language := state.language
+	if language == targetLanguage {
+		language = ""
+	}
+	err = setGuildSetting(serverID, settingTargetLanguage, language)
+	if err != nil {
+		return err
+	}
+
+	style := state.style
+	if style == styleDefault {
+		style = ""
+	}
+	for _, channelID := range state.channelIDs {
+		err = setChannelSetting(serverID, channelID, settingStyle, style)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestSetupLanguageStep(t *testing.T) {
+	step := setupLanguageStep()
+	if len(step.Components) > 5 {
+		t.Fatalf("language step has %d rows, Discord allows 5", len(step.Components))
+	}
+
+	seen := make(map[string]bool)
+	for _, row := range step.Components {
+		menu := row.(discordgo.ActionsRow).Components[0].(discordgo.SelectMenu)
+		if len(menu.Options) == 0 || len(menu.Options) > maxSelectOptions {
+			t.Errorf("menu %s has %d options, want 1 to %d", menu.CustomID, len(menu.Options), maxSelectOptions)
+		}
+		for _, option := range menu.Options {
+			seen[option.Value] = true
+		}
+	}
+	for code := range languageCountries {
+		if !seen[code] {
+			t.Errorf("language %s isn't offered", code)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package bot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Scaling out
+//
+// One process serves a few thousand guilds. Past that, run several, each
+// connected to the gateway as one shard:
+//
+//	SHARD_COUNT=4 SHARD_ID=0 REDIS_URL=redis://cache:6379 translate-bot
+//	SHARD_COUNT=4 SHARD_ID=1 REDIS_URL=redis://cache:6379 translate-bot
+//	...
+//
+// Discord sends each shard the events of the guilds it owns, (guild ID >> 22)
+// % SHARD_COUNT. Running two processes with the same SHARD_ID keeps a warm
+// standby; they claim each message in Redis so only one translates it.
+//
+// The shards share through Redis the configuration caches (invalidate.go),
+// translations, message claims and cooldowns (sharedcache.go) and their
+// health, below. Jobs marked shared work on the database for all guilds, so
+// only the instance holding the job's lock runs them each interval.
+//
+// All instances must use the same store. The store is SQLite, so they have to
+// run on one host sharing channels.db; writes wait up to storage's busy
+// timeout for each other. There is no networked store, so scaling out stops
+// at what one host can run. The README spells out this limit for hosters.
+
+// shardHealthInterval is how often each shard reports its health. A shard
+// that hasn't reported for three intervals is considered down.
+const shardHealthInterval = 30 * time.Second
+
+var (
+	// shardID and shardCount place this instance in the gateway; shardCount
+	// is 0 when the bot isn't sharded.
+	shardID, shardCount int
+)
+
+// shardHealth is what a shard reports about itself.
+type shardHealth struct {
+	Shard     int       `json:"shard"`
+	Instance  string    `json:"instance,omitempty"`
+	Connected bool      `json:"connected"`
+	Guilds    int       `json:"guilds"`
+	Messages  int64     `json:"messages"`
+	Latency   float64   `json:"latency_seconds"`
+	Updated   time.Time `json:"updated"`
+}
+
+var (
+	localHealthMu sync.Mutex
+	// localHealth is this instance's last health report, or nil before the
+	// first one.
+	localHealth *shardHealth
+)
+
+func init() {
+	http.HandleFunc("/health", serveHealth)
+	http.HandleFunc("/shards", serveShards)
+}
+
+// initSharding reads SHARD_ID and SHARD_COUNT and has the session identify
+// as that shard.
+func initSharding(dg *discordgo.Session) error {
+	countValue := os.Getenv("SHARD_COUNT")
+	if countValue == "" {
+		return nil
+	}
+	count, err := strconv.Atoi(countValue)
+	if err != nil || count < 1 {
+		return fmt.Errorf("invalid SHARD_COUNT %q", countValue)
+	}
+	id, err := strconv.Atoi(os.Getenv("SHARD_ID"))
+	if err != nil || id < 0 || id >= count {
+		return fmt.Errorf("invalid SHARD_ID %q for %d shards", os.Getenv("SHARD_ID"), count)
+	}
+	if count > 1 && sharedRedis == nil {
+		return errors.New("running more than one shard needs REDIS_URL")
+	}
+
+	shardID, shardCount = id, count
+	dg.ShardID, dg.ShardCount = id, count
+	log.Printf("Running as shard %d of %d", id, count)
+	return nil
+}
+
+// reportShardHealth records this instance's health and shares it with the
+// other shards.
+func reportShardHealth(s *discordgo.Session) {
+	health := &shardHealth{
+		Shard:     shardID,
+		Instance:  instanceID,
+		Connected: s.DataReady,
+		Latency:   s.HeartbeatLatency().Seconds(),
+		Updated:   time.Now().UTC(),
+	}
+	s.State.RLock()
+	health.Guilds = len(s.State.Guilds)
+	s.State.RUnlock()
+	pipelineMetricsMu.Lock()
+	if metrics := pipelineMetrics["self"]; metrics != nil {
+		health.Messages = metrics.Runs
+	}
+	pipelineMetricsMu.Unlock()
+
+	localHealthMu.Lock()
+	localHealth = health
+	localHealthMu.Unlock()
+
+	encoded, err := json.Marshal(health)
+	if err != nil {
+		log.Println("Error encoding shard health,", err)
+		return
+	}
+	sharedSet(fmt.Sprintf("shard:%d", shardID), string(encoded), 3*shardHealthInterval)
+}
+
+// serveHealth reports this instance's health, failing while it is not
+// connected to Discord.
+func serveHealth(w http.ResponseWriter, r *http.Request) {
+	localHealthMu.Lock()
+	health := localHealth
+	localHealthMu.Unlock()
+
+	if health == nil || !health.Connected || time.Since(health.Updated) > 3*shardHealthInterval {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if health == nil {
+		fmt.Fprintln(w, "starting")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(health)
+}
+
+// serveShards reports the health of every shard, failing when any of them is
+// down. Without Redis only this instance's shard is known.
+func serveShards(w http.ResponseWriter, r *http.Request) {
+	count := max(shardCount, 1)
+	shards := make([]shardHealth, count)
+	down := false
+	for n := range shards {
+		shards[n].Shard = n
+		value, ok := sharedGet(fmt.Sprintf("shard:%d", n))
+		if !ok && n == shardID {
+			localHealthMu.Lock()
+			if localHealth != nil {
+				shards[n] = *localHealth
+			}
+			localHealthMu.Unlock()
+		} else if ok {
+			if err := json.Unmarshal([]byte(value), &shards[n]); err != nil {
+				log.Println("Error decoding shard health,", err)
+			}
+		}
+		if !shards[n].Connected || time.Since(shards[n].Updated) > 3*shardHealthInterval {
+			shards[n].Connected = false
+			down = true
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if down {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(shards)
+}
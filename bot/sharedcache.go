@@ -0,0 +1,110 @@
+package bot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"translate-bot/redis"
+	"translate-bot/translation"
+)
+
+// sharedPrefix namespaces the keys the bot keeps in Redis.
+const sharedPrefix = "translate-bot:"
+
+// dedupWindow is how long an instance's claim on a message keeps the other
+// instances from handling it too.
+const dedupWindow = 10 * time.Minute
+
+// translationCacheTTL is how long translations are shared between
+// instances. TRANSLATION_CACHE_TTL overrides it; 0 turns the cache off.
+var translationCacheTTL = 24 * time.Hour
+
+func init() {
+	if ttl, err := time.ParseDuration(os.Getenv("TRANSLATION_CACHE_TTL")); err == nil {
+		translationCacheTTL = ttl
+	}
+}
+
+// sharedGet returns the value other instances stored under the key. It
+// reports false when there is no value or no Redis.
+func sharedGet(key string) (string, bool) {
+	if sharedRedis == nil {
+		return "", false
+	}
+	value, err := sharedRedis.String("GET", sharedPrefix+key)
+	if err != nil {
+		if !errors.Is(err, redis.ErrNil) {
+			log.Println("Error reading shared cache,", err)
+		}
+		return "", false
+	}
+	return value, true
+}
+
+// sharedSet stores the value for all instances until the TTL passes.
+func sharedSet(key, value string, ttl time.Duration) {
+	if sharedRedis == nil {
+		return
+	}
+	_, err := sharedRedis.Do("SET", sharedPrefix+key, value, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	if err != nil {
+		log.Println("Error writing shared cache,", err)
+	}
+}
+
+// sharedClaim claims the key for this instance until the TTL passes. It
+// reports false when another instance claimed it first. Without Redis, or
+// when Redis is unreachable, every claim succeeds.
+func sharedClaim(key string, ttl time.Duration) bool {
+	if sharedRedis == nil {
+		return true
+	}
+	_, err := sharedRedis.String("SET", sharedPrefix+key, instanceID, "NX", "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	if errors.Is(err, redis.ErrNil) {
+		return false
+	}
+	if err != nil {
+		log.Println("Error claiming shared key,", err)
+	}
+	return true
+}
+
+// sharedCount adds an event to the counter for the key, which starts over
+// once the TTL passes, and returns the new count.
+func sharedCount(key string, ttl time.Duration) (int64, error) {
+	return sharedRedis.Int("EVAL", sharedCountScript, "1", sharedPrefix+key, strconv.FormatInt(ttl.Milliseconds(), 10))
+}
+
+// sharedCountScript increments a counter and sets its expiry in one step, so
+// a failure in between can't leave a counter that never expires.
+const sharedCountScript = `local count = redis.call('INCR', KEYS[1])
+if count == 1 then redis.call('PEXPIRE', KEYS[1], ARGV[1]) end
+return count`
+
+// translationCacheKey identifies a translation by everything that affects
+// its result.
+func translationCacheKey(backend, text, targetLang string, opts translation.Options) string {
+	encoded, _ := json.Marshal(opts)
+	sum := sha256.New()
+	for _, part := range []string{backend, targetLang, string(encoded), text} {
+		sum.Write([]byte(part))
+		sum.Write([]byte{0})
+	}
+	return "translation:" + hex.EncodeToString(sum.Sum(nil))
+}
+
+// dedupStage lets only one instance handle each message when several
+// receive the same events.
+func dedupStage(p *pipelineMessage) bool {
+	key := "dedup:" + p.m.ID
+	if p.edit && p.m.EditedTimestamp != nil {
+		key += ":" + p.m.EditedTimestamp.Format(time.RFC3339Nano)
+	}
+	return sharedClaim(key, dedupWindow)
+}
@@ -0,0 +1,106 @@
+package bot
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// IDs used for the guild, channel and users of a simulation when no
+// configured guild is given.
+const (
+	simulationGuildID   = "simulation"
+	simulationChannelID = "simulation"
+	simulationBotID     = "simulation-bot"
+	simulationUserID    = "simulation-user"
+)
+
+var errOffline = errors.New("simulation mode: not connected to Discord")
+
+// simulatedPost receives the messages the bot would post while simulating.
+// It is nil when the bot is connected to Discord.
+var simulatedPost func(channelID, content string)
+
+// offlineTransport fails every Discord API request, so nothing a simulation
+// does reaches Discord.
+type offlineTransport struct{}
+
+func (offlineTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errOffline
+}
+
+// Simulate runs each line read from r through the message pipeline as if it
+// had been posted in a translated channel, writing what the bot would post to
+// w instead of connecting to Discord. With a server ID the server's
+// configuration and first translated channel are used; otherwise a blank
+// server with the default settings is simulated.
+func Simulate(serverID string, r io.Reader, w io.Writer) error {
+	if err := initBackends(); err != nil {
+		return err
+	}
+
+	channelID := simulationChannelID
+	if serverID == "" {
+		serverID = simulationGuildID
+		translateChannels[serverID] = [3]string{channelID}
+		translateChannelGuilds[channelID] = serverID
+	} else {
+		channelID = translateChannels[serverID][0]
+		if channelID == "" {
+			return fmt.Errorf("server %s has no translated channels", serverID)
+		}
+	}
+
+	s, err := simulationSession(serverID, channelID)
+	if err != nil {
+		return err
+	}
+	simulatedPost = func(channelID, content string) {
+		fmt.Fprintf(w, "[#%s]\n%s\n\n", channelID, content)
+	}
+	defer func() { simulatedPost = nil }()
+
+	author := &discordgo.User{ID: simulationUserID, Username: "user"}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		if scanner.Text() == "" {
+			continue
+		}
+		messageCreate(s, &discordgo.MessageCreate{Message: &discordgo.Message{
+			ID:        strconv.Itoa(n),
+			ChannelID: channelID,
+			GuildID:   serverID,
+			Content:   scanner.Text(),
+			Author:    author,
+			Timestamp: time.Now(),
+		}})
+	}
+	return scanner.Err()
+}
+
+// simulationSession returns a session whose state knows the simulated server
+// and channel, and whose API requests all fail.
+func simulationSession(serverID, channelID string) (*discordgo.Session, error) {
+	s, err := discordgo.New("")
+	if err != nil {
+		return nil, err
+	}
+	s.Client = &http.Client{Transport: offlineTransport{}}
+	s.State.User = &discordgo.User{ID: simulationBotID, Username: "Translate Bot", Bot: true}
+
+	err = s.State.GuildAdd(&discordgo.Guild{ID: serverID, Name: "Simulation"})
+	if err != nil {
+		return nil, err
+	}
+	err = s.State.ChannelAdd(&discordgo.Channel{ID: channelID, GuildID: serverID, Name: "simulation", Type: discordgo.ChannelTypeGuildText})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
@@ -0,0 +1,130 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"translate-bot/storage"
+)
+
+// Skip log modes.
+const (
+	skipLogChannel  = "channel"
+	skipLogDatabase = "database"
+)
+
+// reasonNotConfigured is why messages outside translated channels are
+// skipped. Only the database log records it, since nearly every message in
+// a server would otherwise be reported to the log channel.
+const reasonNotConfigured = "the channel isn't translated"
+
+// skipLogRetention is how long the database log keeps skipped messages.
+const skipLogRetention = 7 * 24 * time.Hour
+
+// maxSkipsShown is how many skipped messages /skipped lists.
+const maxSkipsShown = 15
+
+// logSkip records why the message wasn't translated in the server's skip
+// log, if it keeps one.
+func logSkip(s *discordgo.Session, m *discordgo.MessageCreate, reason string) {
+	switch getGuildSetting(m.GuildID, settingSkipLog) {
+	case skipLogChannel:
+		// Dry-run mode reports skipped messages already.
+		if reason == reasonNotConfigured || isDryRun(m.GuildID) {
+			return
+		}
+		channelID := getGuildSetting(m.GuildID, settingLogChannel)
+		if channelID != "" {
+			queueMessage(s, channelID, fmt.Sprintf("⏭️ Skipped %s: %s", messageJumpURL(m.GuildID, m.ChannelID, m.ID), reason))
+		}
+	case skipLogDatabase:
+		err := store.RecordSkip(storage.Skip{
+			ServerID:  m.GuildID,
+			ChannelID: m.ChannelID,
+			MessageID: m.ID,
+			Reason:    reason,
+			CreatedAt: time.Now(),
+		})
+		if err != nil {
+			log.Println("Error recording skipped message,", err)
+		}
+	}
+}
+
+func pruneSkipLog(s *discordgo.Session) {
+	if _, err := store.PruneSkips(time.Now().Add(-skipLogRetention)); err != nil {
+		log.Println("Error pruning skip log,", err)
+	}
+}
+
+func handleConfigSkipLogCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	mode := i.ApplicationCommandData().Options[0].Options[0].StringValue()
+
+	value := mode
+	if mode == "off" {
+		value = ""
+	}
+	err := setGuildSetting(i.GuildID, settingSkipLog, value)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to update the skip log: %s", err.Error()),
+		})
+		return
+	}
+
+	var responseContent string
+	switch value {
+	case skipLogChannel:
+		responseContent = "Messages that aren't translated will be reported to the log channel with the reason."
+		if getGuildSetting(i.GuildID, settingLogChannel) == "" {
+			responseContent += " No log channel is set yet. Set one with `/config logchannel`."
+		}
+	case skipLogDatabase:
+		responseContent = fmt.Sprintf("Messages that aren't translated will be recorded with the reason for %d days. See them with `/skipped`.", int(skipLogRetention.Hours()/24))
+	default:
+		responseContent = "Skipped messages will no longer be logged."
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
+
+func handleSkippedCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var channelID string
+	for _, option := range i.ApplicationCommandData().Options {
+		if option.Name == "channel" {
+			channelID = option.ChannelValue(s).ID
+		}
+	}
+
+	skips, err := store.Skips(i.GuildID, channelID, maxSkipsShown)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to load skipped messages: %s", err.Error()),
+		})
+		return
+	}
+
+	if len(skips) == 0 {
+		responseContent := "No skipped messages were recorded."
+		if getGuildSetting(i.GuildID, settingSkipLog) != skipLogDatabase {
+			responseContent += " Record them with `/config skiplog database`."
+		}
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: responseContent,
+		})
+		return
+	}
+
+	var lines []string
+	for _, skip := range skips {
+		lines = append(lines, fmt.Sprintf("<t:%d:R> %s — %s", skip.CreatedAt.Unix(), messageJumpURL(skip.ServerID, skip.ChannelID, skip.MessageID), skip.Reason))
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: "Recently skipped messages:\n" + strings.Join(lines, "\n"),
+	})
+}
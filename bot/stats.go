@@ -0,0 +1,131 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// recordStats updates the per-member, per-language-pair and daily language
+// counters for a translated message.
+func recordStats(m *discordgo.MessageCreate, sourceLang string, characters int) error {
+	err := recordUserStats(m.GuildID, m.Author.ID, characters)
+	if err != nil {
+		return err
+	}
+	err = recordPairStats(m.GuildID, sourceLang, guildTargetLanguage(m.GuildID))
+	if err != nil {
+		return err
+	}
+	return recordLanguageUsage(m.GuildID, sourceLang)
+}
+
+func recordUserStats(serverID, userID string, characters int) error {
+	return store.RecordUserStats(serverID, userID, characters)
+}
+
+func recordPairStats(serverID, sourceLang, targetLang string) error {
+	return store.RecordPairStats(serverID, sourceLang, targetLang)
+}
+
+func handleStatsCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	subCommand := i.ApplicationCommandData().Options[0].Name
+
+	switch subCommand {
+	case "leaderboard":
+		handleStatsLeaderboardCommand(s, i)
+	case "languages":
+		handleStatsLanguagesCommand(s, i)
+	case "banwords":
+		handleStatsBanwordsCommand(s, i)
+	}
+}
+
+func handleStatsLeaderboardCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	users, err := store.TopUsers(i.GuildID, 10)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to retrieve statistics: %s", err.Error()),
+		})
+		return
+	}
+
+	var lines []string
+	for _, user := range users {
+		lines = append(lines, fmt.Sprintf("%d. <@%s> — %d translations (%d characters)", len(lines)+1, user.UserID, user.Translations, user.Characters))
+	}
+
+	responseContent := "No translations have been recorded yet."
+	if len(lines) > 0 {
+		responseContent = "Most translated members:\n" + strings.Join(lines, "\n")
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content:         responseContent,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+}
+
+func handleStatsLanguagesCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	pairs, err := store.TopPairs(i.GuildID, 15)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to retrieve statistics: %s", err.Error()),
+		})
+		return
+	}
+
+	var lines []string
+	for _, pair := range pairs {
+		lines = append(lines, fmt.Sprintf("%s %s → %s %s: %d translations", languageFlag(pair.SourceLang), pair.SourceLang, languageFlag(pair.TargetLang), pair.TargetLang, pair.Translations))
+	}
+
+	responseContent := "No translations have been recorded yet."
+	if len(lines) > 0 {
+		responseContent = "Most translated language pairs:\n" + strings.Join(lines, "\n")
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
+
+func handleStatsBanwordsCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	hits, err := store.BanwordHits(i.GuildID)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to retrieve statistics: %s", err.Error()),
+		})
+		return
+	}
+
+	var lines []string
+	matched := make(map[string]bool, len(hits))
+	for _, word := range hits {
+		matched[word.Word] = true
+		if len(lines) < 15 {
+			lines = append(lines, fmt.Sprintf("`%s`: %d messages, last <t:%d:R>", word.Word, word.Hits, word.LastHit.Unix()))
+		}
+	}
+	responseContent := "No banned words have matched yet."
+	if len(lines) > 0 {
+		responseContent = "Most matched banned words:\n" + strings.Join(lines, "\n")
+	}
+
+	var unmatched []string
+	for _, word := range sortedKeys(bannedWords) {
+		if !matched[word] {
+			unmatched = append(unmatched, word)
+		}
+	}
+	room := maxMessageLength - len([]rune(responseContent)) - 50
+	if len(unmatched) > 0 && room > 0 {
+		list := strings.Join(unmatched, ", ")
+		if len([]rune(list)) > room {
+			list = string([]rune(list)[:room]) + "…"
+		}
+		responseContent += "\nNever matched in this server: " + list
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
@@ -0,0 +1,158 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Long translations from backends that stream, such as the LLM backend, are
+// shown while they are written: a preview is posted once
+// streamPreviewCharacters have arrived and edited at most every
+// streamEditInterval, until the finished translation replaces it.
+const (
+	// streamMinCharacters is how long a message has to be for its
+	// translation to be streamed.
+	streamMinCharacters     = 200
+	streamPreviewCharacters = 80
+	streamEditInterval      = 1500 * time.Millisecond
+)
+
+// translationStream keeps the preview of a translation being streamed up to
+// date.
+type translationStream struct {
+	p         *pipelineMessage
+	channelID string
+	lastEdit  time.Time
+	// dropped is set once an outgoing processor drops the partial
+	// translation, which then isn't shown any more.
+	dropped bool
+}
+
+// streamsTranslation reports whether the message's translation should be
+// streamed. Edits, late messages, dry runs and simulations are translated in
+// one go, and so is everything while a message hook is set, since the hook
+// only sees the finished translation and may skip or reroute it.
+func streamsTranslation(p *pipelineMessage) bool {
+	return getGuildSetting(p.m.GuildID, settingStreamTranslations) != "" &&
+		!p.edit && !p.catchUp && !p.delayed && messageHook == nil &&
+		simulatedPost == nil && !isDryRun(p.m.GuildID) &&
+		utf8.RuneCountInString(p.text) >= streamMinCharacters
+}
+
+func newTranslationStream(p *pipelineMessage) *translationStream {
+	return &translationStream{p: p, channelID: outputChannel(p)}
+}
+
+// update posts or edits the preview with the translation so far, after the
+// outgoing processors have had their say on it.
+func (st *translationStream) update(translated string) {
+	p := st.p
+	if st.dropped {
+		return
+	}
+	if p.streamed == nil && utf8.RuneCountInString(translated) < streamPreviewCharacters {
+		return
+	}
+	if time.Since(st.lastEdit) < streamEditInterval {
+		return
+	}
+	st.lastEdit = time.Now()
+
+	translated, _, ok := processOutgoing(p.m.GuildID, p.m.ChannelID, translated)
+	if !ok {
+		st.dropped = true
+		discardStream(p)
+		return
+	}
+	if runes := []rune(translated); len(runes) > maxMessageLength-200 {
+		translated = string(runes[:maxMessageLength-200])
+	}
+	content := p.titleLine + formatTranslation(p.m, translated+" ▍", st.channelID != p.m.ChannelID)
+
+	if p.streamed == nil {
+		message, err := sendQueued(p.s, st.channelID, &discordgo.MessageSend{
+			Content:         content,
+			AllowedMentions: translationMentions,
+		})
+		if err != nil {
+			log.Println("Error posting translation preview,", err)
+			return
+		}
+		p.streamed = message
+		return
+	}
+	edit := discordgo.NewMessageEdit(st.channelID, p.streamed.ID).SetContent(content)
+	edit.AllowedMentions = translationMentions
+	if _, err := p.s.ChannelMessageEditComplex(edit); err != nil {
+		log.Println("Error updating translation preview,", err)
+	}
+}
+
+// postOutput posts the translation, or replaces the preview streamed into
+// the channel with it.
+func postOutput(p *pipelineMessage, channelID, content string) {
+	if p.streamed == nil || p.streamed.ChannelID != channelID {
+		discardStream(p)
+		postTranslationThen(p.s, p.m, channelID, content, linkIfTracked(p.m))
+		return
+	}
+
+	edit := discordgo.NewMessageEdit(channelID, p.streamed.ID).SetContent(content)
+	edit.AllowedMentions = translationMentions
+	message, err := p.s.ChannelMessageEditComplex(edit)
+	p.streamed = nil
+	if err != nil {
+		log.Println("Error finishing translation preview,", err)
+		return
+	}
+	if sent := linkIfTracked(p.m); sent != nil {
+		sent(message)
+	}
+	fireEvent(webhookEvent{
+		Event:     eventTranslation,
+		GuildID:   p.m.GuildID,
+		ChannelID: p.m.ChannelID,
+		MessageID: p.m.ID,
+		UserID:    p.m.Author.ID,
+		Content:   content,
+	})
+}
+
+// discardStream deletes the preview of a translation that won't be posted.
+func discardStream(p *pipelineMessage) {
+	if p.streamed == nil {
+		return
+	}
+	if err := p.s.ChannelMessageDelete(p.streamed.ChannelID, p.streamed.ID); err != nil {
+		log.Println("Error deleting translation preview,", err)
+	}
+	p.streamed = nil
+}
+
+func handleBackendStreamCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	enabled := i.ApplicationCommandData().Options[0].Options[0].BoolValue()
+
+	value := ""
+	if enabled {
+		value = "on"
+	}
+	err := setGuildSetting(i.GuildID, settingStreamTranslations, value)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to update streaming: %s", err.Error()),
+		})
+		return
+	}
+
+	responseContent := "Translations will be posted once they are complete."
+	if enabled {
+		responseContent = fmt.Sprintf("Translations of messages over %d characters will appear while the backend writes them, when it supports streaming. Currently this is the LLM backend.", streamMinCharacters)
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
@@ -0,0 +1,402 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"translate-bot/filter"
+	"translate-bot/storage"
+)
+
+// Subscription frequencies.
+const (
+	frequencyLive   = "live"
+	frequencyHourly = "hourly"
+	frequencyDaily  = "daily"
+)
+
+// frequencyIntervals is how often digests of each frequency are delivered.
+// Live subscriptions only get digests of what their rate limit held back.
+var frequencyIntervals = map[string]time.Duration{
+	frequencyLive:   liveRateWindow,
+	frequencyHourly: time.Hour,
+	frequencyDaily:  24 * time.Hour,
+}
+
+// A user gets at most liveRateLimit live translations per liveRateWindow
+// across all their subscriptions. Further messages are held back and sent
+// together once the window has passed.
+const (
+	liveRateLimit  = 5
+	liveRateWindow = 5 * time.Minute
+)
+
+// maxDigestMessages is the most direct messages one digest is split into.
+// Messages that don't fit are counted instead of delivered, which keeps busy
+// channels from flooding DMs.
+const maxDigestMessages = 3
+
+// subscriptions holds every user's subscriptions by channel.
+var subscriptions map[string][]storage.Subscription
+
+func loadSubscriptions() error {
+	all, err := store.Subscriptions()
+	if err != nil {
+		return err
+	}
+	byChannel := make(map[string][]storage.Subscription)
+	for _, subscription := range all {
+		byChannel[subscription.ChannelID] = append(byChannel[subscription.ChannelID], subscription)
+	}
+	subscriptions = byChannel
+	return nil
+}
+
+// digestEntry is a message waiting to be delivered in digests.
+type digestEntry struct {
+	author  string
+	content string
+	url     string
+	at      time.Time
+}
+
+var (
+	digestMu sync.Mutex
+	// digestPending holds the messages of subscribed channels from the last
+	// day, by channel.
+	digestPending = make(map[string][]digestEntry)
+	// digestSent is when each subscription last had a digest delivered, by
+	// user and channel.
+	digestSent = make(map[string]time.Time)
+	// liveCooldown limits how many live translations each user is sent.
+	liveCooldown = newCooldown("live", liveRateLimit, liveRateWindow)
+	// liveHeld marks live subscriptions whose messages are held back for
+	// the next digest, by user and channel.
+	liveHeld = make(map[string]bool)
+	// dmChannels caches the DM channel of each user.
+	dmChannels = make(map[string]string)
+)
+
+// subscribeStage collects messages of channels users have subscribed to for
+// their digests and delivers them to live subscribers, except during the
+// server's quiet hours. The channel may be a translated channel too, so the
+// pipeline carries on either way.
+func subscribeStage(p *pipelineMessage) bool {
+	if len(subscriptions[p.m.ChannelID]) == 0 || p.m.WebhookID != "" {
+		return true
+	}
+	content := p.m.Content
+	if strings.TrimSpace(content) == "" || filter.IsOnlyEmoji(content) || containsBannedWord(p.m.GuildID, p.m.ChannelID, content) {
+		return true
+	}
+
+	digestMu.Lock()
+	digestPending[p.m.ChannelID] = append(digestPending[p.m.ChannelID], digestEntry{
+		author:  authorName(p.m, true),
+		content: content,
+		url:     messageJumpURL(p.m.GuildID, p.m.ChannelID, p.m.ID),
+		at:      time.Now(),
+	})
+	digestMu.Unlock()
+
+	if isQuiet(p.m.GuildID) {
+		return true
+	}
+	for _, subscription := range subscriptions[p.m.ChannelID] {
+		if subscription.Frequency == frequencyLive && subscription.UserID != p.m.Author.ID {
+			sendLive(p.s, p.m, subscription)
+		}
+	}
+	return true
+}
+
+// sendLive delivers a message to a live subscriber right away, unless the
+// user is over their rate limit.
+func sendLive(s *discordgo.Session, m *discordgo.MessageCreate, subscription storage.Subscription) {
+	if !canViewChannel(s, subscription.UserID, subscription.ChannelID) {
+		return
+	}
+	key := subscription.UserID + ":" + subscription.ChannelID
+	now := time.Now()
+
+	digestMu.Lock()
+	held := liveHeld[key]
+	digestMu.Unlock()
+	if held {
+		return
+	}
+	allowed, _ := liveCooldown.allow(subscription.UserID)
+
+	digestMu.Lock()
+	if allowed {
+		digestSent[key] = now
+	} else {
+		liveHeld[key] = true
+	}
+	digestMu.Unlock()
+	if !allowed {
+		return
+	}
+
+	dmChannelID, err := dmChannel(s, subscription.UserID)
+	if err != nil {
+		log.Println("Error opening DM for live subscription,", err)
+		return
+	}
+	queueMessage(s, dmChannelID, fmt.Sprintf("**%s** in <#%s>: %s\n-# %s",
+		authorName(m, true), m.ChannelID, translateForSubscriber(s, subscription, m.Content), messageJumpURL(m.GuildID, m.ChannelID, m.ID)))
+}
+
+// dmChannel returns the ID of the user's DM channel with the bot.
+func dmChannel(s *discordgo.Session, userID string) (string, error) {
+	digestMu.Lock()
+	channelID, ok := dmChannels[userID]
+	digestMu.Unlock()
+	if ok {
+		return channelID, nil
+	}
+
+	channel, err := s.UserChannelCreate(userID)
+	if err != nil {
+		return "", err
+	}
+	digestMu.Lock()
+	dmChannels[userID] = channel.ID
+	digestMu.Unlock()
+	return channel.ID, nil
+}
+
+// digestsStarted stands in for the last delivery of subscriptions that
+// haven't had a digest since the bot started.
+var digestsStarted = time.Now()
+
+// sendSubscriptionDigests delivers the digests that are due and drops
+// messages too old for any digest.
+func sendSubscriptionDigests(s *discordgo.Session) {
+	now := time.Now()
+	for _, channelSubscriptions := range subscriptions {
+		for _, subscription := range channelSubscriptions {
+			key := subscription.UserID + ":" + subscription.ChannelID
+			digestMu.Lock()
+			lastSent, ok := digestSent[key]
+			held := liveHeld[key]
+			digestMu.Unlock()
+			if !ok {
+				lastSent = digestsStarted
+			}
+			if now.Sub(lastSent) < frequencyIntervals[subscription.Frequency] {
+				continue
+			}
+			if subscription.Frequency == frequencyLive && !held {
+				continue
+			}
+
+			sendDigest(s, subscription, lastSent)
+			digestMu.Lock()
+			digestSent[key] = now
+			delete(liveHeld, key)
+			digestMu.Unlock()
+		}
+	}
+
+	digestMu.Lock()
+	cutoff := now.Add(-frequencyIntervals[frequencyDaily])
+	for channelID, entries := range digestPending {
+		for len(entries) > 0 && entries[0].at.Before(cutoff) {
+			entries = entries[1:]
+		}
+		if len(entries) == 0 {
+			delete(digestPending, channelID)
+		} else {
+			digestPending[channelID] = entries
+		}
+	}
+	digestMu.Unlock()
+}
+
+// sendDigest delivers the channel's messages since the last digest to the
+// subscriber, translated into their language.
+func sendDigest(s *discordgo.Session, subscription storage.Subscription, since time.Time) {
+	digestMu.Lock()
+	var entries []digestEntry
+	for _, entry := range digestPending[subscription.ChannelID] {
+		if entry.at.After(since) {
+			entries = append(entries, entry)
+		}
+	}
+	digestMu.Unlock()
+	if len(entries) == 0 || !canViewChannel(s, subscription.UserID, subscription.ChannelID) {
+		return
+	}
+
+	var lines []string
+	for _, entry := range entries {
+		lines = append(lines, fmt.Sprintf("**%s**: %s", entry.author, translateForSubscriber(s, subscription, entry.content)))
+	}
+
+	dmChannelID, err := dmChannel(s, subscription.UserID)
+	if err != nil {
+		log.Println("Error opening DM for digest,", err)
+		return
+	}
+
+	chunks, included := chunkLines(fmt.Sprintf("📬 Digest of <#%s>:", subscription.ChannelID), lines, maxDigestMessages)
+	if included < len(entries) {
+		chunks[len(chunks)-1] += fmt.Sprintf("\n…and %d more, starting at %s", len(entries)-included, entries[included].url)
+	}
+	for _, chunk := range chunks {
+		queueMessage(s, dmChannelID, chunk)
+	}
+}
+
+// translateForSubscriber translates a message for a subscriber, falling back
+// to the original when it is already in their language or the server is out
+// of quota.
+func translateForSubscriber(s *discordgo.Session, subscription storage.Subscription, content string) string {
+	if detectLanguage(content) == subscription.TargetLang {
+		return content
+	}
+	characters := len([]rune(content))
+	if !checkQuota(s, subscription.ServerID, characters) {
+		return content
+	}
+	translated, err := translateTo(subscription.ServerID, content, subscription.TargetLang)
+	if err != nil {
+		log.Println("Error translating for subscriber,", err)
+		return content
+	}
+	if err := recordUsage(subscription.ServerID, characters); err != nil {
+		log.Println("Error recording usage,", err)
+	}
+	return translated
+}
+
+// chunkLines joins the lines under the header into at most maxChunks
+// messages, leaving room in each for a closing note, and returns them with
+// the number of lines they hold. Lines too long on their own are cut.
+func chunkLines(header string, lines []string, maxChunks int) ([]string, int) {
+	const limit = maxMessageLength - 100
+	var chunks []string
+	current := header
+	for included, line := range lines {
+		if runes := []rune(line); len(runes) > limit {
+			line = string(runes[:limit-1]) + "…"
+		}
+		if len([]rune(current))+1+len([]rune(line)) > limit {
+			chunks = append(chunks, current)
+			if len(chunks) == maxChunks {
+				return chunks, included
+			}
+			current = line
+			continue
+		}
+		current += "\n" + line
+	}
+	return append(chunks, current), len(lines)
+}
+
+func handleSubscribeCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Member == nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "Error: Subscribe from within a server.",
+		})
+		return
+	}
+
+	subscription := storage.Subscription{
+		UserID:    i.Member.User.ID,
+		ServerID:  i.GuildID,
+		Frequency: frequencyDaily,
+	}
+	for _, option := range i.ApplicationCommandData().Options {
+		switch option.Name {
+		case "channel":
+			subscription.ChannelID = option.ChannelValue(s).ID
+		case "language":
+			subscription.TargetLang = strings.ToLower(strings.TrimSpace(option.StringValue()))
+		case "frequency":
+			subscription.Frequency = option.StringValue()
+		}
+	}
+	if subscription.TargetLang == "" {
+		subscription.TargetLang = userLanguage(subscription.UserID)
+	}
+	if subscription.TargetLang == "" {
+		subscription.TargetLang = guildTargetLanguage(i.GuildID)
+	}
+
+	// Subscribing must not reveal channels the user can't read.
+	if !canViewChannel(s, subscription.UserID, subscription.ChannelID) {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "Error: You can't view that channel.",
+		})
+		return
+	}
+
+	err := store.Subscribe(subscription)
+	if err == nil {
+		err = reloadShared(changeSubscriptions)
+	}
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to subscribe: %s", err.Error()),
+		})
+		return
+	}
+
+	responseContent := fmt.Sprintf("Subscribed. You'll get a %s DM digest of <#%s> in %s %s.",
+		subscription.Frequency, subscription.ChannelID, languageFlag(subscription.TargetLang), subscription.TargetLang)
+	if subscription.Frequency == frequencyLive {
+		responseContent = fmt.Sprintf("Subscribed. You'll get messages in <#%s> by DM as they are posted, translated into %s %s. Busy moments are batched into one message.",
+			subscription.ChannelID, languageFlag(subscription.TargetLang), subscription.TargetLang)
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent + " Make sure DMs from server members are allowed. Stop with /unsubscribe.",
+	})
+}
+
+func handleUnsubscribeCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Member == nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "Error: Unsubscribe from within a server.",
+		})
+		return
+	}
+
+	channelID := i.ApplicationCommandData().Options[0].ChannelValue(s).ID
+	removed, err := store.Unsubscribe(i.Member.User.ID, channelID)
+	if err == nil {
+		err = reloadShared(changeSubscriptions)
+	}
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to unsubscribe: %s", err.Error()),
+		})
+		return
+	}
+
+	responseContent := fmt.Sprintf("You aren't subscribed to <#%s>.", channelID)
+	if removed {
+		responseContent = fmt.Sprintf("Unsubscribed from <#%s>.", channelID)
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
+
+// canViewChannel reports whether the user can read the channel, asking
+// Discord when the state doesn't know. It fails closed, so subscriptions
+// never reveal a channel the user lost access to.
+func canViewChannel(s *discordgo.Session, userID, channelID string) bool {
+	permissions, err := s.UserChannelPermissions(userID, channelID)
+	if err != nil {
+		log.Println("Error checking channel permissions,", err)
+		return false
+	}
+	return permissions&discordgo.PermissionViewChannel != 0
+}
@@ -0,0 +1,52 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestChunkLines(t *testing.T) {
+	const limit = maxMessageLength - 100
+
+	chunks, included := chunkLines("header", []string{"one", "two"}, 3)
+	if len(chunks) != 1 || chunks[0] != "header\none\ntwo" || included != 2 {
+		t.Errorf("chunkLines() = %q, %d, want everything in one chunk", chunks, included)
+	}
+
+	// Lines are spread over chunks that fit in a message, never split.
+	line := strings.Repeat("x", 500)
+	lines := []string{line, line, line, line, line}
+	chunks, included = chunkLines("header", lines, 3)
+	if len(chunks) != 2 || included != len(lines) {
+		t.Errorf("chunkLines() made %d chunks holding %d lines, want 2 holding %d", len(chunks), included, len(lines))
+	}
+	for _, chunk := range chunks {
+		if length := utf8.RuneCountInString(chunk); length > limit {
+			t.Errorf("chunk is %d characters long, over %d", length, limit)
+		}
+	}
+	if !strings.HasPrefix(chunks[0], "header\n") || strings.HasPrefix(chunks[1], "\n") {
+		t.Errorf("chunks start with %q and %q", chunks[0][:7], chunks[1][:1])
+	}
+
+	// Lines past the last chunk are left out and not counted.
+	chunks, included = chunkLines("header", lines, 1)
+	if len(chunks) != 1 || included != 3 {
+		t.Errorf("chunkLines() = %d chunks holding %d lines, want 1 holding 3", len(chunks), included)
+	}
+
+	// A line too long on its own is cut.
+	chunks, included = chunkLines("header", []string{strings.Repeat("é", maxMessageLength)}, 3)
+	if included != 1 {
+		t.Errorf("chunkLines() included %d lines, want 1", included)
+	}
+	for _, chunk := range chunks {
+		if length := utf8.RuneCountInString(chunk); length > limit {
+			t.Errorf("chunk is %d characters long, over %d", length, limit)
+		}
+	}
+	if last := chunks[len(chunks)-1]; !strings.HasSuffix(last, "…") {
+		t.Errorf("long line wasn't cut: ends in %q", last[len(last)-10:])
+	}
+}
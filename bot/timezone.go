@@ -0,0 +1,129 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	// Zone data is embedded so timezones work in images without
+	// /usr/share/zoneinfo.
+	_ "time/tzdata"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// commonTimezones are suggested while typing a timezone. Any other IANA
+// name works too.
+var commonTimezones = []string{
+	"UTC",
+	"America/Los_Angeles", "America/Denver", "America/Chicago", "America/New_York",
+	"America/Mexico_City", "America/Bogota", "America/Sao_Paulo", "America/Argentina/Buenos_Aires",
+	"Europe/London", "Europe/Lisbon", "Europe/Paris", "Europe/Berlin", "Europe/Madrid",
+	"Europe/Rome", "Europe/Warsaw", "Europe/Kyiv", "Europe/Istanbul", "Europe/Moscow",
+	"Africa/Lagos", "Africa/Cairo", "Africa/Johannesburg",
+	"Asia/Dubai", "Asia/Kolkata", "Asia/Bangkok", "Asia/Jakarta", "Asia/Manila",
+	"Asia/Shanghai", "Asia/Seoul", "Asia/Tokyo",
+	"Australia/Perth", "Australia/Sydney", "Pacific/Auckland",
+}
+
+var (
+	locationsMu sync.Mutex
+	// locations caches the loaded timezones by name.
+	locations = make(map[string]*time.Location)
+)
+
+// loadLocation returns the named timezone, loading it once. "Local" and the
+// empty name, which time.LoadLocation takes for the host's timezone and UTC,
+// aren't timezone names and are refused.
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+
+	locationsMu.Lock()
+	defer locationsMu.Unlock()
+
+	if location, ok := locations[name]; ok {
+		return location, nil
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations[name] = location
+	return location, nil
+}
+
+// guildLocation returns the guild's timezone, UTC unless it chose one.
+func guildLocation(guildID string) *time.Location {
+	name := getGuildSetting(guildID, settingTimezone)
+	if name == "" {
+		return time.UTC
+	}
+	location, err := loadLocation(name)
+	if err != nil {
+		log.Printf("Error loading timezone %q of guild %s, %s", name, guildID, err)
+		return time.UTC
+	}
+	return location
+}
+
+// guildNow returns the current time in the guild's timezone.
+func guildNow(guildID string) time.Time {
+	return time.Now().In(guildLocation(guildID))
+}
+
+// guildDay returns the date in the guild's timezone, as usage and
+// statistics are recorded under.
+func guildDay(guildID string) string {
+	return guildNow(guildID).Format("2006-01-02")
+}
+
+func timezoneChoices(s *discordgo.Session, i *discordgo.InteractionCreate, typed string) []*discordgo.ApplicationCommandOptionChoice {
+	typed = strings.ToLower(strings.TrimSpace(typed))
+
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, name := range commonTimezones {
+		if strings.Contains(strings.ToLower(name), typed) {
+			choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: name, Value: name})
+		}
+	}
+	return choices
+}
+
+func handleConfigTimezoneCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	name := ""
+	for _, option := range i.ApplicationCommandData().Options[0].Options {
+		if option.Name == "zone" {
+			name = strings.TrimSpace(option.StringValue())
+		}
+	}
+
+	location := time.UTC
+	if name != "" {
+		var err error
+		location, err = loadLocation(name)
+		if err != nil {
+			respond(s, i, &discordgo.InteractionResponseData{
+				Content: fmt.Sprintf("Unknown timezone %q. Use a name like Europe/Berlin or America/New_York.", name),
+			})
+			return
+		}
+		name = location.String()
+	}
+
+	err := setGuildSetting(i.GuildID, settingTimezone, name)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to update timezone: %s", err.Error()),
+		})
+		return
+	}
+	refreshQuietHours(i.GuildID)
+
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: fmt.Sprintf("This server's timezone is %s, where it's %s now. Quiet hours, digests, daily quotas and statistics follow it from now on.",
+			location, time.Now().In(location).Format("15:04")),
+	})
+}
@@ -0,0 +1,22 @@
+package bot
+
+import "testing"
+
+func TestLoadLocation(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"Europe/Berlin", false},
+		{"UTC", false},
+		{"Local", true},
+		{"", true},
+		{"Mars/Olympus_Mons", true},
+	}
+	for _, test := range tests {
+		location, err := loadLocation(test.name)
+		if (err != nil) != test.wantErr {
+			t.Errorf("loadLocation(%q) = %v, %v, want error %t", test.name, location, err, test.wantErr)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Channel topics are limited to 1024 characters.
+const maxChannelTopic = 1024
+
+func handleTranslateTopicCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	// Subcommands can't carry their own default permissions, and the rest
+	// of /translate is open to everyone.
+	if i.Member == nil || i.Member.Permissions&discordgo.PermissionManageServer == 0 {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "Error: Translating the channel topic requires the Manage Server permission.",
+		})
+		return
+	}
+
+	var language string
+	var appendTopic bool
+	for _, option := range i.ApplicationCommandData().Options[0].Options {
+		if option.Name == "language" {
+			language = strings.ToLower(strings.TrimSpace(option.StringValue()))
+		} else if option.Name == "append" {
+			appendTopic = option.BoolValue()
+		}
+	}
+
+	channel, err := lookupChannel(s, i.ChannelID)
+	if err != nil || channel.Topic == "" {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "Error: This channel has no topic to translate.",
+		})
+		return
+	}
+
+	characters := len([]rune(channel.Topic))
+	if !checkQuota(s, i.GuildID, characters) {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: "Error: This server's translation quota has been reached.",
+		})
+		return
+	}
+
+	translated, err := translateTo(i.GuildID, channel.Topic, language)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to translate topic: %s", err.Error()),
+		})
+		return
+	}
+	if err := recordUsage(i.GuildID, characters); err != nil {
+		log.Println("Error recording usage,", err)
+	}
+
+	responseContent := fmt.Sprintf("%s %s", languageFlag(language), translated)
+	if appendTopic {
+		topic := fmt.Sprintf("%s\n\n%s %s", channel.Topic, languageFlag(language), translated)
+		if len([]rune(topic)) > maxChannelTopic {
+			respond(s, i, &discordgo.InteractionResponseData{
+				Content: fmt.Sprintf("%s\n\nThe translated topic is too long to append (topics are limited to %d characters).", responseContent, maxChannelTopic),
+			})
+			return
+		}
+
+		_, err = s.ChannelEdit(channel.ID, &discordgo.ChannelEdit{Topic: topic})
+		if err != nil {
+			respond(s, i, &discordgo.InteractionResponseData{
+				Content: fmt.Sprintf("Failed to update topic: %s", err.Error()),
+			})
+			return
+		}
+		responseContent += "\n\nAppended to the channel topic."
+	}
+
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
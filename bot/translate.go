@@ -0,0 +1,117 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"translate-bot/translation"
+)
+
+// channelOptions returns the translation options configured for the channel
+// and its server.
+func channelOptions(serverID, channelID string) translation.Options {
+	return translation.Options{
+		Formality: getChannelSetting(channelID, settingFormality),
+		Preset:    getGuildSetting(serverID, settingLLMPreset),
+	}
+}
+
+var activeBackend translation.Backend
+
+// guildBackend returns the backend used for the server: its own API key when
+// one is configured, the LLM backend for licensed servers that switched it
+// on, the bot-wide backend otherwise.
+func guildBackend(serverID string) translation.Backend {
+	b, err := guildKeyedBackend(serverID)
+	if err != nil {
+		log.Println("Error using guild API key,", err)
+		return activeBackend
+	}
+	if b != nil {
+		return b
+	}
+	if premiumBackend != nil && getGuildSetting(serverID, settingUseLLM) != "" && hasPremium(serverID) {
+		return premiumBackend
+	}
+	return activeBackend
+}
+
+// guildKeyedBackend returns the backend for the server's own API key, or nil
+// when it hasn't configured one.
+func guildKeyedBackend(serverID string) (translation.Backend, error) {
+	provider := getGuildSetting(serverID, settingAPIProvider)
+	storedKey := getGuildSetting(serverID, settingAPIKey)
+	if provider == "" || storedKey == "" {
+		return nil, nil
+	}
+
+	apiKey, err := decryptSecret(storedKey)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting key: %w", err)
+	}
+	return translation.NewKeyed(provider, apiKey)
+}
+
+// translateText translates the text into the server's target language with
+// the server's backend.
+func translateText(serverID, text string) (string, error) {
+	return translateTo(serverID, text, guildTargetLanguage(serverID))
+}
+
+// translateTo translates the text into the given language with the server's
+// backend and default options.
+func translateTo(serverID, text, targetLang string) (string, error) {
+	return translateWith(serverID, text, targetLang, translation.Options{})
+}
+
+// translateWith translates the text into the given language with the
+// server's backend.
+func translateWith(serverID, text, targetLang string, opts translation.Options) (string, error) {
+	return translateUsing(context.Background(), serverID, guildBackend(serverID), text, targetLang, opts)
+}
+
+// translateUsing translates the text into the given language with the
+// backend, recording billed characters for metered backends. Translations
+// are shared with other instances through Redis, when set up. A backend that
+// keeps failing isn't called until its breaker lets a probe through, and
+// none is called while the owner paused translation.
+func translateUsing(ctx context.Context, serverID string, b translation.Backend, text, targetLang string, opts translation.Options) (string, error) {
+	if _, paused := pauseReason(serverID); paused {
+		return "", errTranslationPaused
+	}
+	cacheKey := translationCacheKey(b.Name(), text, targetLang, opts)
+	if translationCacheTTL > 0 {
+		if translated, ok := sharedGet(cacheKey); ok {
+			return translated, nil
+		}
+	}
+	key := breakerKey(b)
+	if !breakerAllow(key) {
+		return "", errBackendDown
+	}
+	start := time.Now()
+	translated, err := translation.TranslateContext(ctx, b, text, targetLang, opts)
+	if ctx.Err() != nil {
+		// A canceled request says nothing about the backend.
+		breakerResult(key, ctx.Err())
+		return "", ctx.Err()
+	}
+	recordBackendCall(key, time.Since(start), err)
+	breakerResult(key, err)
+	if err != nil {
+		return "", err
+	}
+	if translationCacheTTL > 0 {
+		sharedSet(cacheKey, translated, translationCacheTTL)
+	}
+
+	if apiKey := b.APIKey(); apiKey != "" {
+		err = recordBilling(b.Name(), apiKey, serverID, len([]rune(text)))
+		if err != nil {
+			log.Println("Error recording billing,", err)
+		}
+	}
+	return translated, nil
+}
@@ -0,0 +1,29 @@
+package bot
+
+import "testing"
+
+func TestGuildBackendLLMOptIn(t *testing.T) {
+	initTestStore(t)
+	shell, llm := &fakeBackend{name: "shell"}, &fakeBackend{name: "llm"}
+	oldActive, oldPremium := activeBackend, premiumBackend
+	activeBackend, premiumBackend = shell, llm
+	defer func() { activeBackend, premiumBackend = oldActive, oldPremium }()
+
+	const guildID = "1152"
+	if b := guildBackend(guildID); b != shell {
+		t.Errorf("guildBackend() = %s before opting in, want the bot-wide backend", b.Name())
+	}
+
+	if err := setGuildSetting(guildID, settingUseLLM, "on"); err != nil {
+		t.Fatal(err)
+	}
+	if b := guildBackend(guildID); b != llm {
+		t.Errorf("guildBackend() = %s after opting in, want the LLM backend", b.Name())
+	}
+
+	// Where licenses are required, opting in isn't enough.
+	t.Setenv("LICENSE_SECRET", "secret")
+	if b := guildBackend(guildID); b != shell {
+		t.Errorf("guildBackend() = %s without a license, want the bot-wide backend", b.Name())
+	}
+}
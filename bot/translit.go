@@ -0,0 +1,264 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var cyrillicToLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya", 'і': "i", 'ї': "yi", 'є': "ye", 'ґ': "g",
+}
+
+var greekToLatin = map[rune]string{
+	'α': "a", 'ά': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'έ': "e", 'ζ': "z",
+	'η': "i", 'ή': "i", 'θ': "th", 'ι': "i", 'ί': "i", 'κ': "k", 'λ': "l", 'μ': "m",
+	'ν': "n", 'ξ': "x", 'ο': "o", 'ό': "o", 'π': "p", 'ρ': "r", 'σ': "s", 'ς': "s",
+	'τ': "t", 'υ': "y", 'ύ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps", 'ω': "o", 'ώ': "o",
+}
+
+// kanaToLatin covers hiragana; katakana is mapped onto it before lookup.
+var kanaToLatin = map[string]string{
+	"あ": "a", "い": "i", "う": "u", "え": "e", "お": "o",
+	"か": "ka", "き": "ki", "く": "ku", "け": "ke", "こ": "ko",
+	"さ": "sa", "し": "shi", "す": "su", "せ": "se", "そ": "so",
+	"た": "ta", "ち": "chi", "つ": "tsu", "て": "te", "と": "to",
+	"な": "na", "に": "ni", "ぬ": "nu", "ね": "ne", "の": "no",
+	"は": "ha", "ひ": "hi", "ふ": "fu", "へ": "he", "ほ": "ho",
+	"ま": "ma", "み": "mi", "む": "mu", "め": "me", "も": "mo",
+	"や": "ya", "ゆ": "yu", "よ": "yo",
+	"ら": "ra", "り": "ri", "る": "ru", "れ": "re", "ろ": "ro",
+	"わ": "wa", "を": "wo", "ん": "n",
+	"が": "ga", "ぎ": "gi", "ぐ": "gu", "げ": "ge", "ご": "go",
+	"ざ": "za", "じ": "ji", "ず": "zu", "ぜ": "ze", "ぞ": "zo",
+	"だ": "da", "ぢ": "ji", "づ": "zu", "で": "de", "ど": "do",
+	"ば": "ba", "び": "bi", "ぶ": "bu", "べ": "be", "ぼ": "bo",
+	"ぱ": "pa", "ぴ": "pi", "ぷ": "pu", "ぺ": "pe", "ぽ": "po",
+	"きゃ": "kya", "きゅ": "kyu", "きょ": "kyo", "しゃ": "sha", "しゅ": "shu", "しょ": "sho",
+	"ちゃ": "cha", "ちゅ": "chu", "ちょ": "cho", "にゃ": "nya", "にゅ": "nyu", "にょ": "nyo",
+	"ひゃ": "hya", "ひゅ": "hyu", "ひょ": "hyo", "みゃ": "mya", "みゅ": "myu", "みょ": "myo",
+	"りゃ": "rya", "りゅ": "ryu", "りょ": "ryo", "ぎゃ": "gya", "ぎゅ": "gyu", "ぎょ": "gyo",
+	"じゃ": "ja", "じゅ": "ju", "じょ": "jo", "びゃ": "bya", "びゅ": "byu", "びょ": "byo",
+	"ぴゃ": "pya", "ぴゅ": "pyu", "ぴょ": "pyo",
+	"ぁ": "a", "ぃ": "i", "ぅ": "u", "ぇ": "e", "ぉ": "o", "ゃ": "ya", "ゅ": "yu", "ょ": "yo",
+}
+
+var (
+	hangulInitials = []string{"g", "kk", "n", "d", "tt", "r", "m", "b", "pp", "s", "ss", "", "j", "jj", "ch", "k", "t", "p", "h"}
+	hangulVowels   = []string{"a", "ae", "ya", "yae", "eo", "e", "yeo", "ye", "o", "wa", "wae", "oe", "yo", "u", "wo", "we", "wi", "yu", "eu", "ui", "i"}
+	hangulFinals   = []string{"", "k", "k", "k", "n", "n", "n", "t", "l", "k", "m", "l", "l", "l", "p", "l", "m", "p", "p", "t", "t", "ng", "t", "t", "k", "t", "p", "t"}
+)
+
+// latinToCyrillic is the reverse of cyrillicToLatin, longest sequences first
+// so "shch" wins over "sh".
+var latinToCyrillic = func() [][2]string {
+	seen := make(map[string]bool)
+	var pairs [][2]string
+	for _, r := range "абвгдеёжзийклмнопрстуфхцчшщыэюя" {
+		latin := cyrillicToLatin[r]
+		if latin == "" || seen[latin] {
+			continue
+		}
+		seen[latin] = true
+		pairs = append(pairs, [2]string{latin, string(r)})
+	}
+	sort.SliceStable(pairs, func(a, b int) bool { return len(pairs[a][0]) > len(pairs[b][0]) })
+	return pairs
+}()
+
+// transliterateToLatin romanizes Cyrillic, Greek, Japanese kana and Korean
+// Hangul. Characters from other scripts are left unchanged.
+func transliterateToLatin(text string) string {
+	var out strings.Builder
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		lower := unicode.ToLower(r)
+
+		if latin, ok := cyrillicToLatin[lower]; ok {
+			out.WriteString(matchCase(r, latin))
+			continue
+		}
+		if latin, ok := greekToLatin[lower]; ok {
+			out.WriteString(matchCase(r, latin))
+			continue
+		}
+		if r >= 0xAC00 && r <= 0xD7A3 {
+			syllable := int(r - 0xAC00)
+			out.WriteString(hangulInitials[syllable/588] + hangulVowels[syllable%588/28] + hangulFinals[syllable%28])
+			continue
+		}
+		if kana := toHiragana(r); kana != 0 {
+			// A small tsu doubles the following consonant.
+			if kana == 'っ' {
+				if i+1 < len(runes) {
+					if next, ok := kanaToLatin[string(toHiragana(runes[i+1]))]; ok && next != "" {
+						out.WriteByte(next[0])
+					}
+				}
+				continue
+			}
+			if i+1 < len(runes) {
+				if latin, ok := kanaToLatin[string([]rune{kana, toHiragana(runes[i+1])})]; ok {
+					out.WriteString(latin)
+					i++
+					continue
+				}
+			}
+			if latin, ok := kanaToLatin[string(kana)]; ok {
+				out.WriteString(latin)
+				continue
+			}
+		}
+		if r == 'ー' {
+			continue
+		}
+		out.WriteRune(r)
+	}
+	return out.String()
+}
+
+// transliterateToCyrillic renders Latin text in Cyrillic. Letters are
+// lowercased one at a time, since lowercasing a whole string can change its
+// length.
+func transliterateToCyrillic(text string) string {
+	var out strings.Builder
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+	for i := 0; i < len(runes); {
+		matched := false
+		for _, pair := range latinToCyrillic {
+			latin := []rune(pair[0])
+			if len(lower)-i < len(latin) || string(lower[i:i+len(latin)]) != pair[0] {
+				continue
+			}
+			cyrillic := pair[1]
+			if unicode.IsUpper(runes[i]) {
+				cyrillic = strings.ToUpper(cyrillic)
+			}
+			out.WriteString(cyrillic)
+			i += len(latin)
+			matched = true
+			break
+		}
+		if !matched {
+			out.WriteRune(runes[i])
+			i++
+		}
+	}
+	return out.String()
+}
+
+// toHiragana returns the hiragana for a hiragana or katakana rune, or 0.
+func toHiragana(r rune) rune {
+	if r >= 0x3041 && r <= 0x3096 {
+		return r
+	}
+	if r >= 0x30A1 && r <= 0x30F6 {
+		return r - 0x60
+	}
+	return 0
+}
+
+func matchCase(original rune, latin string) string {
+	if latin == "" || !unicode.IsUpper(original) {
+		return latin
+	}
+	return strings.ToUpper(latin[:1]) + latin[1:]
+}
+
+// isLatin reports whether every letter in the text is from the Latin script.
+func isLatin(text string) bool {
+	for _, r := range text {
+		if unicode.IsLetter(r) && !unicode.Is(unicode.Latin, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// memberDisplayName returns the member's nickname, global name or username.
+func memberDisplayName(member *discordgo.Member, user *discordgo.User) string {
+	if member != nil && member.Nick != "" {
+		return member.Nick
+	}
+	if user.GlobalName != "" {
+		return user.GlobalName
+	}
+	return user.Username
+}
+
+// describeTransliteration explains how to read a name in the other script.
+func describeTransliteration(name string) string {
+	if isLatin(name) {
+		return fmt.Sprintf("**%s** in Cyrillic: %s", name, transliterateToCyrillic(name))
+	}
+	latin := transliterateToLatin(name)
+	if latin == name || !isLatin(latin) {
+		return fmt.Sprintf("**%s** uses a script that can't be transliterated automatically.", name)
+	}
+	return fmt.Sprintf("**%s** can be read as: %s", name, latin)
+}
+
+func handleTransliterateNameCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+	user := data.Resolved.Users[data.TargetID]
+	member := data.Resolved.Members[data.TargetID]
+
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: describeTransliteration(memberDisplayName(member, user)),
+	})
+}
+
+// suggestTransliteration tells moderators how to address a new member whose
+// name is written in a non-Latin script.
+func suggestTransliteration(s *discordgo.Session, m *discordgo.GuildMemberAdd) {
+	if m.User.Bot || getGuildSetting(m.GuildID, settingNickSuggest) == "" {
+		return
+	}
+	name := memberDisplayName(m.Member, m.User)
+	if isLatin(name) {
+		return
+	}
+	latin := transliterateToLatin(name)
+	if latin == name || !isLatin(latin) {
+		return
+	}
+	notifyAdmins(s, m.GuildID, fmt.Sprintf("New member %s joined. %s", m.User.Mention(), describeTransliteration(name)))
+}
+
+func handleConfigNickSuggestCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	enabled := i.ApplicationCommandData().Options[0].Options[0].BoolValue()
+
+	value := ""
+	if enabled {
+		value = "on"
+	}
+	err := setGuildSetting(i.GuildID, settingNickSuggest, value)
+	if err != nil {
+		log.Println("Error updating nickname suggestions,", err)
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to update nickname suggestions: %s", err.Error()),
+		})
+		return
+	}
+
+	responseContent := "Nickname transliterations will no longer be suggested on join."
+	if enabled {
+		responseContent = "Transliterations of non-Latin names will be posted to the log channel when members join."
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
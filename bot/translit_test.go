@@ -0,0 +1,51 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTransliterateToCyrillic(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"", ""},
+		{"privet", "привет"},
+		{"Shchuka", "Щука"},
+		{"Ivan Petrov", "Иван Петров"},
+		{"Zhenya_99", "Женя_99"},
+		// These grow or shrink when lowercased as a whole string.
+		{"ȺȺȺ", "ȺȺȺ"},
+		{"Ⱥa", "Ⱥа"},
+		{"ẞa", "ẞа"},
+		{"İ̇İa", "И̇Иа"},
+	}
+	for _, test := range tests {
+		got := transliterateToCyrillic(test.text)
+		if got != test.want {
+			t.Errorf("transliterateToCyrillic(%q) = %q, want %q", test.text, got, test.want)
+		}
+		if strings.ContainsRune(got, '�') {
+			t.Errorf("transliterateToCyrillic(%q) = %q, contains U+FFFD", test.text, got)
+		}
+	}
+}
+
+func TestTransliterateToLatin(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Привет", "Privet"},
+		{"Ωμέγα", "Omega"},
+		{"さっき", "sakki"},
+		{"한국", "hanguk"},
+		{"Mixed 名前", "Mixed 名前"},
+	}
+	for _, test := range tests {
+		if got := transliterateToLatin(test.text); got != test.want {
+			t.Errorf("transliterateToLatin(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
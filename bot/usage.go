@@ -0,0 +1,216 @@
+package bot
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var (
+	quotaNotifiedMu sync.Mutex
+	// quotaNotified remembers the last period each guild was told about an
+	// exceeded quota, so admins are notified once per day or month.
+	quotaNotified = make(map[string]string)
+)
+
+func recordUsage(serverID string, characters int) error {
+	return store.RecordUsage(serverID, guildDay(serverID), characters)
+}
+
+// keyFingerprint identifies an API key in billing records without storing the
+// key itself.
+func keyFingerprint(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// recordBilling records billed characters under the UTC date, since bills
+// cover all servers a key is used in.
+func recordBilling(backendName, apiKey, serverID string, characters int) error {
+	return store.RecordBilling(backendName, keyFingerprint(apiKey), serverID, time.Now().UTC().Format("2006-01-02"), characters)
+}
+
+// ExportBilling writes billed characters per backend, API key and server for
+// the given month (YYYY-MM) as CSV, so hosters can split costs between the
+// communities they serve.
+func ExportBilling(month string) error {
+	records, err := store.Billing(month)
+	if err != nil {
+		return err
+	}
+
+	w := csv.NewWriter(os.Stdout)
+	w.Write([]string{"month", "backend", "key_id", "server_id", "characters"})
+	for _, record := range records {
+		w.Write([]string{month, record.Backend, record.KeyID, record.ServerID, strconv.Itoa(record.Characters)})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// usageSince returns the number of characters translated for the server on or
+// after the given day.
+func usageSince(serverID, day string) (int, error) {
+	return store.UsageSince(serverID, day)
+}
+
+// quotaLimit returns the character limit for the server, combining the guild's
+// own setting with the hoster's ceiling from the environment. Zero means no
+// limit.
+func quotaLimit(serverID, key, envName string) int {
+	limit, _ := strconv.Atoi(getGuildSetting(serverID, key))
+	ceiling, _ := strconv.Atoi(os.Getenv(envName))
+	if ceiling > 0 && (limit <= 0 || limit > ceiling) {
+		return ceiling
+	}
+	return limit
+}
+
+// quotaPeriod is a window a character quota applies to.
+type quotaPeriod struct {
+	name  string
+	key   string
+	env   string
+	start string
+}
+
+// quotaPeriods returns the server's quota periods, which start at midnight
+// in its timezone.
+func quotaPeriods(serverID string) []quotaPeriod {
+	now := guildNow(serverID)
+	return []quotaPeriod{
+		{"daily", settingDailyQuota, "DAILY_CHARACTER_QUOTA", now.Format("2006-01-02")},
+		{"monthly", settingMonthlyQuota, "MONTHLY_CHARACTER_QUOTA", now.Format("2006-01") + "-01"},
+	}
+}
+
+// notifyQuotaOnce notifies the guild's admins unless they were already told
+// about this event in the current period.
+func notifyQuotaOnce(s *discordgo.Session, serverID, event, periodStart, content string) {
+	notifyKey := serverID + ":" + event
+	quotaNotifiedMu.Lock()
+	notified := quotaNotified[notifyKey] == periodStart
+	quotaNotified[notifyKey] = periodStart
+	quotaNotifiedMu.Unlock()
+	if notified {
+		return
+	}
+	notifyAdmins(s, serverID, content)
+	fireEvent(webhookEvent{
+		Event:   eventQuota,
+		GuildID: serverID,
+		Content: content,
+	})
+}
+
+// checkQuota reports whether translating the given number of characters keeps
+// the server within its daily and monthly quotas. Admins are notified the first
+// time a quota is exceeded in each period.
+func checkQuota(s *discordgo.Session, serverID string, characters int) bool {
+	for _, period := range quotaPeriods(serverID) {
+		limit := quotaLimit(serverID, period.key, period.env)
+		if limit <= 0 {
+			continue
+		}
+		used, err := usageSince(serverID, period.start)
+		if err != nil {
+			log.Println("Error checking usage,", err)
+			return true
+		}
+		if used+characters > limit {
+			notifyQuotaOnce(s, serverID, period.name+":100", period.start,
+				fmt.Sprintf("The %s translation quota of %d characters has been reached. Translation is paused until it resets.", period.name, limit))
+			return false
+		}
+	}
+
+	return true
+}
+
+// warnQuotaUsage notifies admins once per period when the server has used 80%
+// of a quota, so they can react before translation stops.
+func warnQuotaUsage(s *discordgo.Session, serverID string) {
+	for _, period := range quotaPeriods(serverID) {
+		limit := quotaLimit(serverID, period.key, period.env)
+		if limit <= 0 {
+			continue
+		}
+		used, err := usageSince(serverID, period.start)
+		if err != nil {
+			log.Println("Error checking usage,", err)
+			return
+		}
+		if used*100 >= limit*80 {
+			notifyQuotaOnce(s, serverID, period.name+":80", period.start,
+				fmt.Sprintf("This server has used %d of its %d character %s translation quota (%d%%).", used, limit, period.name, used*100/limit))
+		}
+	}
+}
+
+// warnBackendQuotaExhausted notifies admins once per day when the translation
+// provider itself rejects requests because the account's quota is used up.
+func warnBackendQuotaExhausted(s *discordgo.Session, serverID string) {
+	notifyQuotaOnce(s, serverID, "backend", guildDay(serverID),
+		"The translation provider reports that its API quota is exhausted. Translations will fail until the quota resets or the plan is upgraded.")
+}
+
+func handleConfigQuotaCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	options := i.ApplicationCommandData().Options[0].Options
+	if len(options) == 0 {
+		daily := quotaLimit(i.GuildID, settingDailyQuota, "DAILY_CHARACTER_QUOTA")
+		monthly := quotaLimit(i.GuildID, settingMonthlyQuota, "MONTHLY_CHARACTER_QUOTA")
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Current quota: %s per day, %s per month.", formatQuota(daily), formatQuota(monthly)),
+		})
+		return
+	}
+
+	for _, option := range options {
+		key, envName := settingDailyQuota, "DAILY_CHARACTER_QUOTA"
+		if option.Name == "monthly" {
+			key, envName = settingMonthlyQuota, "MONTHLY_CHARACTER_QUOTA"
+		}
+
+		limit := option.IntValue()
+		ceiling, _ := strconv.ParseInt(os.Getenv(envName), 10, 64)
+		if limit < 0 || (ceiling > 0 && (limit == 0 || limit > ceiling)) {
+			respond(s, i, &discordgo.InteractionResponseData{
+				Content: fmt.Sprintf("Error: The %s quota must be between 1 and %s.", option.Name, formatQuota(int(ceiling))),
+			})
+			return
+		}
+
+		value := ""
+		if limit > 0 {
+			value = strconv.FormatInt(limit, 10)
+		}
+		err := setGuildSetting(i.GuildID, key, value)
+		if err != nil {
+			respond(s, i, &discordgo.InteractionResponseData{
+				Content: fmt.Sprintf("Failed to update %s quota: %s", option.Name, err.Error()),
+			})
+			return
+		}
+	}
+
+	daily := quotaLimit(i.GuildID, settingDailyQuota, "DAILY_CHARACTER_QUOTA")
+	monthly := quotaLimit(i.GuildID, settingMonthlyQuota, "MONTHLY_CHARACTER_QUOTA")
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: fmt.Sprintf("Quota updated: %s per day, %s per month.", formatQuota(daily), formatQuota(monthly)),
+	})
+}
+
+func formatQuota(limit int) string {
+	if limit <= 0 {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%d characters", limit)
+}
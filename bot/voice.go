@@ -0,0 +1,384 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"translate-bot/translation"
+)
+
+const (
+	// voiceSilence is how long a speaker has to be quiet before their
+	// utterance is transcribed.
+	voiceSilence = 800 * time.Millisecond
+	// voiceMaxUtterance caps how much audio is buffered before transcribing,
+	// so long monologues still produce captions in near real time.
+	voiceMaxUtterance = 15 * time.Second
+
+	opusFrameSamples = 960
+)
+
+// voiceSession is an active caption session in one guild.
+type voiceSession struct {
+	guildID          string
+	captionChannelID string
+	stage            bool
+	vc               *discordgo.VoiceConnection
+	done             chan struct{}
+
+	mu       sync.Mutex
+	speakers map[uint32]string
+}
+
+// utterance buffers the audio of one speaker until they pause.
+type utterance struct {
+	packets  []*discordgo.Packet
+	started  time.Time
+	lastSeen time.Time
+}
+
+var (
+	voiceSessionsMu sync.Mutex
+	voiceSessions   = make(map[string]*voiceSession)
+)
+
+func startVoiceSession(s *discordgo.Session, guildID, voiceChannelID, captionChannelID string, stage bool) error {
+	voiceSessionsMu.Lock()
+	defer voiceSessionsMu.Unlock()
+
+	if _, exists := voiceSessions[guildID]; exists {
+		return fmt.Errorf("captions are already running in this server")
+	}
+
+	vc, err := s.ChannelVoiceJoin(guildID, voiceChannelID, true, false)
+	if err != nil {
+		return err
+	}
+
+	session := &voiceSession{
+		guildID:          guildID,
+		captionChannelID: captionChannelID,
+		stage:            stage,
+		vc:               vc,
+		done:             make(chan struct{}),
+		speakers:         make(map[uint32]string),
+	}
+	vc.AddHandler(func(vc *discordgo.VoiceConnection, vs *discordgo.VoiceSpeakingUpdate) {
+		session.mu.Lock()
+		session.speakers[uint32(vs.SSRC)] = vs.UserID
+		session.mu.Unlock()
+	})
+	voiceSessions[guildID] = session
+
+	go session.receive(s)
+	return nil
+}
+
+func stopVoiceSession(guildID string) error {
+	voiceSessionsMu.Lock()
+	session, exists := voiceSessions[guildID]
+	delete(voiceSessions, guildID)
+	voiceSessionsMu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("captions are not running in this server")
+	}
+	close(session.done)
+	return session.vc.Disconnect()
+}
+
+// receive buffers incoming audio per speaker and hands each finished
+// utterance off for transcription.
+func (v *voiceSession) receive(s *discordgo.Session) {
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+
+	utterances := make(map[uint32]*utterance)
+	for {
+		select {
+		case <-v.done:
+			return
+		case packet, ok := <-v.vc.OpusRecv:
+			if !ok {
+				return
+			}
+			u := utterances[packet.SSRC]
+			if u == nil {
+				u = &utterance{started: time.Now()}
+				utterances[packet.SSRC] = u
+			}
+			u.packets = append(u.packets, packet)
+			u.lastSeen = time.Now()
+		case <-ticker.C:
+			for ssrc, u := range utterances {
+				if time.Since(u.lastSeen) < voiceSilence && time.Since(u.started) < voiceMaxUtterance {
+					continue
+				}
+				delete(utterances, ssrc)
+				go v.caption(s, ssrc, u.packets)
+			}
+		}
+	}
+}
+
+// caption transcribes an utterance, translates it and posts the caption.
+func (v *voiceSession) caption(s *discordgo.Session, ssrc uint32, packets []*discordgo.Packet) {
+	// Skip blips such as coughs or clicks that are too short to transcribe.
+	if len(packets) < 10 {
+		return
+	}
+
+	text, err := transcribe(encodeOggOpus(packets))
+	if err != nil {
+		log.Println("Error transcribing voice,", err)
+		return
+	}
+	if text == "" || containsBannedWord(v.guildID, v.captionChannelID, text) {
+		return
+	}
+
+	characters := len([]rune(text))
+	if !checkQuota(s, v.guildID, characters) {
+		return
+	}
+	translatedText, err := translateText(v.guildID, text)
+	if err != nil {
+		log.Println("Error translating caption,", err)
+		return
+	}
+	if err := recordUsage(v.guildID, characters); err != nil {
+		log.Println("Error recording usage,", err)
+	}
+
+	v.mu.Lock()
+	userID := v.speakers[ssrc]
+	v.mu.Unlock()
+
+	speaker := "Unknown speaker"
+	if member, err := s.State.Member(v.guildID, userID); err == nil {
+		speaker = member.User.Username
+		if member.Nick != "" {
+			speaker = member.Nick
+		}
+	}
+
+	icon := "🎙️"
+	if v.stage {
+		icon = "🎤"
+	}
+	queueMessage(s, v.captionChannelID, fmt.Sprintf("%s **%s**: %s", icon, speaker, translatedText))
+}
+
+// transcribe sends audio to an OpenAI-compatible speech-to-text endpoint
+// (Whisper by default) and returns the recognized text.
+func transcribe(audio []byte) (string, error) {
+	endpoint := os.Getenv("STT_URL")
+	if endpoint == "" {
+		endpoint = "https://api.openai.com/v1/audio/transcriptions"
+	}
+	model := os.Getenv("STT_MODEL")
+	if model == "" {
+		model = "whisper-1"
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("model", model)
+	file, err := form.CreateFormFile("file", "speech.ogg")
+	if err != nil {
+		return "", err
+	}
+	file.Write(audio)
+	form.Close()
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if apiKey := os.Getenv("STT_API_KEY"); apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := translation.HTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("speech-to-text returned %s: %s", resp.Status, respBody)
+	}
+
+	var result struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	return result.Text, nil
+}
+
+// encodeOggOpus wraps raw Opus packets from Discord in an Ogg container so
+// speech-to-text services can read them without decoding to PCM first.
+func encodeOggOpus(packets []*discordgo.Packet) []byte {
+	var out bytes.Buffer
+	const serial = 1
+	sequence := uint32(0)
+
+	head := []byte("OpusHead")
+	head = append(head, 1, 2)                            // version, channels
+	head = binary.LittleEndian.AppendUint16(head, 312)   // pre-skip
+	head = binary.LittleEndian.AppendUint32(head, 48000) // input sample rate
+	head = append(head, 0, 0, 0)                         // output gain, mapping family
+	writeOggPage(&out, head, 0, serial, sequence, 0x02)
+	sequence++
+
+	tags := []byte("OpusTags")
+	vendor := "translate-bot"
+	tags = binary.LittleEndian.AppendUint32(tags, uint32(len(vendor)))
+	tags = append(tags, vendor...)
+	tags = binary.LittleEndian.AppendUint32(tags, 0)
+	writeOggPage(&out, tags, 0, serial, sequence, 0)
+	sequence++
+
+	first := packets[0].Timestamp
+	for i, packet := range packets {
+		granule := uint64(packet.Timestamp-first) + opusFrameSamples
+		headerType := byte(0)
+		if i == len(packets)-1 {
+			headerType = 0x04
+		}
+		writeOggPage(&out, packet.Opus, granule, serial, sequence, headerType)
+		sequence++
+	}
+
+	return out.Bytes()
+}
+
+func writeOggPage(w *bytes.Buffer, packet []byte, granule uint64, serial, sequence uint32, headerType byte) {
+	var segments []byte
+	remaining := len(packet)
+	for remaining >= 255 {
+		segments = append(segments, 255)
+		remaining -= 255
+	}
+	segments = append(segments, byte(remaining))
+
+	page := []byte("OggS")
+	page = append(page, 0, headerType)
+	page = binary.LittleEndian.AppendUint64(page, granule)
+	page = binary.LittleEndian.AppendUint32(page, serial)
+	page = binary.LittleEndian.AppendUint32(page, sequence)
+	page = binary.LittleEndian.AppendUint32(page, 0) // checksum, filled in below
+	page = append(page, byte(len(segments)))
+	page = append(page, segments...)
+	page = append(page, packet...)
+
+	binary.LittleEndian.PutUint32(page[22:], oggChecksum(page))
+	w.Write(page)
+}
+
+var oggCRCTable = func() [256]uint32 {
+	var table [256]uint32
+	for i := range table {
+		r := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if r&0x80000000 != 0 {
+				r = r<<1 ^ 0x04c11db7
+			} else {
+				r <<= 1
+			}
+		}
+		table[i] = r
+	}
+	return table
+}()
+
+func oggChecksum(page []byte) uint32 {
+	var crc uint32
+	for _, b := range page {
+		crc = crc<<8 ^ oggCRCTable[byte(crc>>24)^b]
+	}
+	return crc
+}
+
+func handleVoiceCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	subCommand := i.ApplicationCommandData().Options[0].Name
+
+	switch subCommand {
+	case "join":
+		handleVoiceJoinCommand(s, i)
+	case "leave":
+		handleVoiceLeaveCommand(s, i)
+	}
+}
+
+func handleVoiceJoinCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if !requireFeature(s, i, featureVoice) || !requirePremium(s, i, featureVoice) {
+		return
+	}
+
+	var voiceChannel, captionChannel *discordgo.Channel
+	for _, option := range i.ApplicationCommandData().Options[0].Options {
+		if option.Name == "channel" {
+			voiceChannel = option.ChannelValue(s)
+		} else if option.Name == "captions" {
+			captionChannel = option.ChannelValue(s)
+		}
+	}
+
+	// Stage channels have their own text chat, which is the natural companion
+	// channel for captions when none is given.
+	stage := voiceChannel.Type == discordgo.ChannelTypeGuildStageVoice
+	captionChannelID := i.ChannelID
+	if captionChannel != nil {
+		captionChannelID = captionChannel.ID
+	} else if stage {
+		captionChannelID = voiceChannel.ID
+	}
+
+	err := startVoiceSession(s, i.GuildID, voiceChannel.ID, captionChannelID, stage)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to start voice captions: %s", err.Error()),
+		})
+		return
+	}
+
+	if stage {
+		if instance, err := s.StageInstance(voiceChannel.ID); err == nil {
+			queueMessage(s, captionChannelID, fmt.Sprintf("📢 Live translated captions for **%s**", instance.Topic))
+		}
+	}
+
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: fmt.Sprintf("Joined %s. Translated captions will be posted in <#%s>.", voiceChannel.Mention(), captionChannelID),
+	})
+}
+
+func handleVoiceLeaveCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	err := stopVoiceSession(i.GuildID)
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to stop voice captions: %s", err.Error()),
+		})
+		return
+	}
+
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: "Voice captions stopped.",
+	})
+}
@@ -0,0 +1,181 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"translate-bot/storage"
+)
+
+// warningWindow is how far back warnings count towards a timeout.
+const warningWindow = 24 * time.Hour
+
+// warningRetention is how long warnings are kept.
+const warningRetention = 90 * 24 * time.Hour
+
+// defaultTimeoutMinutes is how long members are timed out for when the
+// server didn't choose a duration. Discord allows up to 28 days.
+const (
+	defaultTimeoutMinutes = 60
+	maxTimeoutMinutes     = 28 * 24 * 60
+)
+
+// maxWarningsShown is how many warnings /warnings lists.
+const maxWarningsShown = 10
+
+// recordWarning counts a moderation hit against the message's author and
+// times them out once they reach the server's limit within warningWindow.
+// Dry runs don't warn anyone.
+func recordWarning(s *discordgo.Session, m *discordgo.MessageCreate, reason string) {
+	if isDryRun(m.GuildID) {
+		return
+	}
+	err := store.RecordWarning(storage.Warning{
+		ServerID:  m.GuildID,
+		UserID:    m.Author.ID,
+		ChannelID: m.ChannelID,
+		MessageID: m.ID,
+		Reason:    reason,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		log.Println("Error recording warning,", err)
+		return
+	}
+
+	limit, _ := strconv.Atoi(getGuildSetting(m.GuildID, settingTimeoutHits))
+	if limit <= 0 || simulatedPost != nil {
+		return
+	}
+	count, err := store.WarningCount(m.GuildID, m.Author.ID, time.Now().Add(-warningWindow))
+	if err != nil {
+		log.Println("Error counting warnings,", err)
+		return
+	}
+	if count < limit {
+		return
+	}
+
+	minutes, _ := strconv.Atoi(getGuildSetting(m.GuildID, settingTimeoutMinutes))
+	if minutes <= 0 {
+		minutes = defaultTimeoutMinutes
+	}
+	until := time.Now().Add(time.Duration(minutes) * time.Minute)
+	if err := s.GuildMemberTimeout(m.GuildID, m.Author.ID, &until); err != nil {
+		log.Println("Error timing out member,", err)
+		return
+	}
+	if channelID := moderatorChannel(s, m.GuildID); channelID != "" {
+		queueMessage(s, channelID, fmt.Sprintf("⏱️ %s was timed out until <t:%d:f> after %d warnings in %d hours.", m.Author.Mention(), until.Unix(), count, int(warningWindow.Hours())))
+	}
+}
+
+func pruneWarnings(s *discordgo.Session) {
+	if _, err := store.PruneWarnings(time.Now().Add(-warningRetention)); err != nil {
+		log.Println("Error pruning warnings,", err)
+	}
+}
+
+func handleModerationTimeoutCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var hits, minutes int64
+	for _, option := range i.ApplicationCommandData().Options[0].Options {
+		switch option.Name {
+		case "warnings":
+			hits = option.IntValue()
+		case "minutes":
+			minutes = option.IntValue()
+		}
+	}
+	if hits < 0 || minutes < 0 || minutes > maxTimeoutMinutes {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Error: Timeouts can last 1 to %d minutes.", maxTimeoutMinutes),
+		})
+		return
+	}
+
+	hitsValue, minutesValue := "", ""
+	if hits > 0 {
+		hitsValue = strconv.FormatInt(hits, 10)
+		if minutes > 0 {
+			minutesValue = strconv.FormatInt(minutes, 10)
+		}
+	}
+	err := setGuildSetting(i.GuildID, settingTimeoutHits, hitsValue)
+	if err == nil {
+		err = setGuildSetting(i.GuildID, settingTimeoutMinutes, minutesValue)
+	}
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to update automatic timeouts: %s", err.Error()),
+		})
+		return
+	}
+
+	responseContent := "Members will no longer be timed out automatically."
+	if hits > 0 {
+		if minutes == 0 {
+			minutes = defaultTimeoutMinutes
+		}
+		responseContent = fmt.Sprintf("Members will be timed out for %d minutes after %d warnings in %d hours.", minutes, hits, int(warningWindow.Hours()))
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
+
+func handleWarningsCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var user *discordgo.User
+	clear := false
+	for _, option := range i.ApplicationCommandData().Options {
+		switch option.Name {
+		case "user":
+			user = option.UserValue(s)
+		case "clear":
+			clear = option.BoolValue()
+		}
+	}
+
+	if clear {
+		if err := store.ClearWarnings(i.GuildID, user.ID); err != nil {
+			respond(s, i, &discordgo.InteractionResponseData{
+				Content: fmt.Sprintf("Failed to clear warnings: %s", err.Error()),
+			})
+			return
+		}
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Cleared the warnings of %s.", user.Mention()),
+		})
+		return
+	}
+
+	warnings, err := store.Warnings(i.GuildID, user.ID, maxWarningsShown)
+	if err == nil && len(warnings) == 0 {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("%s has no warnings.", user.Mention()),
+		})
+		return
+	}
+	var recent int
+	if err == nil {
+		recent, err = store.WarningCount(i.GuildID, user.ID, time.Now().Add(-warningWindow))
+	}
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to load warnings: %s", err.Error()),
+		})
+		return
+	}
+
+	lines := []string{fmt.Sprintf("%s has %d warnings in the last %d hours. Most recent:", user.Mention(), recent, int(warningWindow.Hours()))}
+	for _, warning := range warnings {
+		lines = append(lines, fmt.Sprintf("<t:%d:R> %s — %s", warning.CreatedAt.Unix(), messageJumpURL(warning.ServerID, warning.ChannelID, warning.MessageID), warning.Reason))
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: strings.Join(lines, "\n"),
+	})
+}
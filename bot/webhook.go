@@ -0,0 +1,187 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Events sent to a guild's event webhook.
+const (
+	eventTranslation  = "translation"
+	eventBanword      = "banword"
+	eventToxicity     = "toxicity"
+	eventBackendError = "backend_error"
+	eventQuota        = "quota"
+)
+
+var webhookEvents = []string{eventTranslation, eventBanword, eventToxicity, eventBackendError, eventQuota}
+
+// webhookClient only connects to public addresses, so a webhook URL can't be
+// used to reach the bot's own network. The check is made on each address the
+// host resolves to as it is dialed, and redirects aren't followed, since they
+// could lead anywhere.
+var webhookClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext:         (&net.Dialer{Timeout: 10 * time.Second, Control: dialPublicOnly}).DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+	},
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+var errNonPublicAddress = errors.New("not a public address")
+
+// nonPublicPrefixes are ranges that aren't reachable on the internet but that
+// netip.Addr.IsGlobalUnicast and IsPrivate let through.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+}
+
+// dialPublicOnly refuses connections to addresses that aren't public.
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !isPublicAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", errNonPublicAddress, addrPort.Addr())
+	}
+	return nil
+}
+
+// isPublicAddr reports whether the address is reachable on the internet.
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// webhookEvent is the JSON body posted to an event webhook.
+type webhookEvent struct {
+	Event     string    `json:"event"`
+	GuildID   string    `json:"guild_id"`
+	ChannelID string    `json:"channel_id,omitempty"`
+	MessageID string    `json:"message_id,omitempty"`
+	UserID    string    `json:"user_id,omitempty"`
+	Content   string    `json:"content,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// fireEvent posts the event to the guild's event webhook in the background
+// when the guild subscribed to it. Simulations never fire events.
+func fireEvent(event webhookEvent) {
+	webhookURL := getGuildSetting(event.GuildID, settingWebhookURL)
+	if webhookURL == "" || simulatedPost != nil {
+		return
+	}
+	if events := getGuildSetting(event.GuildID, settingWebhookEvents); events != "" && !slices.Contains(strings.Split(events, ","), event.Event) {
+		return
+	}
+	event.Time = time.Now().UTC()
+
+	go func() {
+		body, err := json.Marshal(event)
+		if err != nil {
+			log.Println("Error encoding webhook event,", err)
+			return
+		}
+		resp, err := webhookClient.Post(webhookURL, "application/json", bytes.NewReader(body))
+		if err != nil {
+			log.Println("Error sending webhook event,", err)
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			log.Printf("Webhook for guild %s responded with %s", event.GuildID, resp.Status)
+		}
+	}()
+}
+
+func handleConfigWebhookCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	webhookURL, events := "", ""
+	for _, option := range i.ApplicationCommandData().Options[0].Options {
+		if option.Name == "url" {
+			webhookURL = strings.TrimSpace(option.StringValue())
+		} else if option.Name == "events" {
+			events = strings.ToLower(strings.ReplaceAll(option.StringValue(), " ", ""))
+		}
+	}
+
+	if webhookURL != "" {
+		parsed, err := url.Parse(webhookURL)
+		if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+			respond(s, i, &discordgo.InteractionResponseData{
+				Content: "Error: The webhook URL must be an https:// URL.",
+			})
+			return
+		}
+		// Hosts that resolve to private addresses are refused when the
+		// event is sent; addresses given outright are refused right away.
+		addr, err := netip.ParseAddr(parsed.Hostname())
+		if strings.EqualFold(parsed.Hostname(), "localhost") || (err == nil && !isPublicAddr(addr)) {
+			respond(s, i, &discordgo.InteractionResponseData{
+				Content: "Error: The webhook URL must point to a public address.",
+			})
+			return
+		}
+	}
+	for _, event := range strings.Split(events, ",") {
+		if event != "" && !slices.Contains(webhookEvents, event) {
+			respond(s, i, &discordgo.InteractionResponseData{
+				Content: fmt.Sprintf("Error: Unknown event '%s'. Events are %s.", event, strings.Join(webhookEvents, ", ")),
+			})
+			return
+		}
+	}
+	if webhookURL == "" {
+		events = ""
+	}
+
+	err := setGuildSetting(i.GuildID, settingWebhookURL, webhookURL)
+	if err == nil {
+		err = setGuildSetting(i.GuildID, settingWebhookEvents, events)
+	}
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to update event webhook: %s", err.Error()),
+		})
+		return
+	}
+
+	responseContent := "Events will no longer be sent to a webhook."
+	if webhookURL != "" {
+		subscribed := strings.Join(webhookEvents, ", ")
+		if events != "" {
+			subscribed = strings.ReplaceAll(events, ",", ", ")
+		}
+		responseContent = fmt.Sprintf("These events will be posted to the webhook as JSON: %s.", subscribed)
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
@@ -0,0 +1,135 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// recordUserLocale remembers the client locale a user last interacted with.
+// Discord only reports locales on interactions, so this is how the bot learns
+// which language to welcome members in.
+func recordUserLocale(userID string, locale discordgo.Locale) error {
+	if userID == "" || locale == "" {
+		return nil
+	}
+	return store.SetUserLocale(userID, string(locale))
+}
+
+// userLanguage returns the language code of the user's known client locale,
+// or an empty string when it isn't known.
+func userLanguage(userID string) string {
+	locale, err := store.UserLocale(userID)
+	if err != nil {
+		return ""
+	}
+	language, _, _ := strings.Cut(locale, "-")
+	return strings.ToLower(language)
+}
+
+func guildMemberAdd(s *discordgo.Session, m *discordgo.GuildMemberAdd) {
+	channelID := getGuildSetting(m.GuildID, settingWelcomeChannel)
+	message := getGuildSetting(m.GuildID, settingWelcomeMessage)
+	if channelID == "" || message == "" || m.User.Bot {
+		return
+	}
+
+	// Members whose locale is unknown get the message as written.
+	welcome := message
+	if language := userLanguage(m.User.ID); language != "" && language != detectLanguage(message) {
+		characters := len([]rune(message))
+		if checkQuota(s, m.GuildID, characters) {
+			translated, err := translateTo(m.GuildID, message, language)
+			if err != nil {
+				log.Println("Error translating welcome message,", err)
+			} else {
+				welcome = translated
+				if err := recordUsage(m.GuildID, characters); err != nil {
+					log.Println("Error recording usage,", err)
+				}
+			}
+		}
+	}
+
+	queueMessage(s, channelID, fmt.Sprintf("%s %s", m.User.Mention(), welcome))
+}
+
+// memberJoinMessage stands in for member join events when the bot runs
+// without the Server Members intent. It only sees joins in servers that
+// announce them in their system channel.
+func memberJoinMessage(s *discordgo.Session, m *discordgo.MessageCreate) {
+	if m.Type != discordgo.MessageTypeGuildMemberJoin || m.GuildID == "" || m.Author == nil {
+		return
+	}
+	member := m.Member
+	if member == nil {
+		member = &discordgo.Member{}
+	}
+	member.GuildID = m.GuildID
+	member.User = m.Author
+	join := &discordgo.GuildMemberAdd{Member: member}
+	guildMemberAdd(s, join)
+	suggestTransliteration(s, join)
+}
+
+func handleWelcomeCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	subCommand := i.ApplicationCommandData().Options[0].Name
+
+	switch subCommand {
+	case "set":
+		handleWelcomeSetCommand(s, i)
+	case "clear":
+		handleWelcomeClearCommand(s, i)
+	}
+}
+
+func handleWelcomeSetCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var channel *discordgo.Channel
+	var message string
+	for _, option := range i.ApplicationCommandData().Options[0].Options {
+		if option.Name == "channel" {
+			channel = option.ChannelValue(s)
+		} else if option.Name == "message" {
+			message = strings.ReplaceAll(option.StringValue(), "\\n", "\n")
+		}
+	}
+
+	err := setGuildSetting(i.GuildID, settingWelcomeChannel, channel.ID)
+	if err == nil {
+		err = setGuildSetting(i.GuildID, settingWelcomeMessage, message)
+	}
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to set welcome message: %s", err.Error()),
+		})
+		return
+	}
+
+	responseContent := fmt.Sprintf("New members will be welcomed in %s, in their own language when it is known.", channel.Mention())
+	if os.Getenv("GUILD_MEMBERS_INTENT") == "" {
+		responseContent += " Joins are only noticed while this server posts join messages in its system channel."
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: responseContent,
+	})
+}
+
+func handleWelcomeClearCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	err := setGuildSetting(i.GuildID, settingWelcomeChannel, "")
+	if err == nil {
+		err = setGuildSetting(i.GuildID, settingWelcomeMessage, "")
+	}
+	if err != nil {
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Failed to clear welcome message: %s", err.Error()),
+		})
+		return
+	}
+
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: "Welcome messages disabled.",
+	})
+}
@@ -0,0 +1,46 @@
+package filter
+
+import "strings"
+
+// confusables maps letters that look like Latin ones, mostly Cyrillic and
+// Greek, to the Latin letter they are mistaken for. Swapping them in is the
+// most common way around a word filter.
+var confusables = map[rune]rune{
+	// Cyrillic
+	'а': 'a', 'в': 'b', 'с': 'c', 'ԁ': 'd', 'е': 'e', 'ё': 'e', 'һ': 'h',
+	'н': 'h', 'і': 'i', 'ї': 'i', 'ј': 'j', 'к': 'k', 'ӏ': 'l', 'м': 'm',
+	'о': 'o', 'р': 'p', 'ԛ': 'q', 'ѕ': 's', 'т': 't', 'ѵ': 'v', 'ԝ': 'w',
+	'х': 'x', 'у': 'y', 'ү': 'y',
+	// Greek
+	'α': 'a', 'β': 'b', 'ϲ': 'c', 'ε': 'e', 'ι': 'i', 'κ': 'k', 'η': 'n',
+	'ο': 'o', 'ρ': 'p', 'τ': 't', 'υ': 'u', 'ν': 'v', 'χ': 'x', 'γ': 'y',
+	// Latin
+	'ı': 'i',
+}
+
+// invisibles are characters that render as nothing and are slipped into
+// words to split them.
+var invisibles = strings.NewReplacer(
+	"\u00ad", "", // soft hyphen
+	"\u200b", "", // zero width space
+	"\u200c", "", // zero width non-joiner
+	"\u200d", "", // zero width joiner
+	"\u2060", "", // word joiner
+	"\ufeff", "", // zero width no-break space
+)
+
+// foldConfusables replaces fullwidth forms and look-alike letters with the
+// plain Latin characters they imitate and drops invisible characters.
+func foldConfusables(text string) string {
+	text = invisibles.Replace(text)
+	return strings.Map(func(r rune) rune {
+		// Fullwidth forms of ASCII, as used in East Asian typography.
+		if r >= '！' && r <= '～' {
+			return r - '！' + '!'
+		}
+		if latin, ok := confusables[r]; ok {
+			return latin
+		}
+		return r
+	}, text)
+}
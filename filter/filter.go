@@ -0,0 +1,81 @@
+// Package filter decides which messages are translated and cleans up text
+// before and after translation.
+package filter
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Normalize prepares text for matching against banned words: it is lower
+// cased and look-alike characters are folded into the letters they imitate.
+// Banned words are normalized the same way before they are matched.
+func Normalize(text string) string {
+	return foldConfusables(strings.ToLower(text))
+}
+
+// FindBannedWords returns the banned words of the text, each once. The ban
+// list is keyed by word.
+func FindBannedWords[V any](text string, bannedWords map[string]V) []string {
+	var found []string
+	words := strings.Fields(Normalize(text))
+	for _, word := range words {
+		if _, exists := bannedWords[word]; exists && !slices.Contains(found, word) {
+			found = append(found, word)
+		}
+	}
+	return found
+}
+
+// MaskBannedWords replaces each of the banned words in the text with
+// asterisks, keeping the rest of it readable.
+func MaskBannedWords(text string, bannedWords []string) string {
+	words := strings.Fields(text)
+	for n, word := range words {
+		if slices.Contains(bannedWords, Normalize(word)) {
+			words[n] = strings.Repeat("*", len([]rune(word)))
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// AreTextsSimilar reports whether a translation differs from the original by
+// at most two words, which means the message didn't need translating.
+func AreTextsSimilar(original, translated string) bool {
+	original = strings.ToLower(strings.TrimSpace(original))
+	translated = strings.ToLower(strings.TrimSpace(translated))
+
+	if original == translated {
+		return true
+	}
+
+	originalWords := strings.Fields(original)
+	translatedWords := strings.Fields(translated)
+
+	diffCount := 0
+	for i := range originalWords {
+		if i >= len(translatedWords) || originalWords[i] != translatedWords[i] {
+			diffCount++
+			if diffCount > 2 {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// IsOnlyEmoji reports whether the text consists of emoji and symbols only.
+func IsOnlyEmoji(s string) bool {
+	for _, r := range s {
+		if !IsEmoji(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func IsEmoji(r rune) bool {
+	return unicode.Is(unicode.S, r) || unicode.Is(unicode.So, r) || unicode.Is(unicode.Mn, r)
+}
@@ -0,0 +1,49 @@
+package filter
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Hello World", "hello world"},
+		// Cyrillic and Greek look-alikes.
+		{"sсаm", "scam"},
+		{"ΒΑΚΕ", "bake"},
+		// Fullwidth forms.
+		{"ＳＰＡＭ!", "spam!"},
+		// Invisible characters splitting a word.
+		{"sp\u200bam", "spam"},
+		{"s\u00adp\u200da\ufeffm", "spam"},
+	}
+	for _, test := range tests {
+		if got := Normalize(test.text); got != test.want {
+			t.Errorf("Normalize(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+func TestFindBannedWords(t *testing.T) {
+	banned := map[string]bool{"spam": true, "scam": true}
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"nothing to see here", nil},
+		{"buy SPAM now", []string{"spam"}},
+		{"spam spam and more spam", []string{"spam"}},
+		{"a sсаm wrapped in ｓｐａｍ", []string{"scam", "spam"}},
+		{"sp\u200bam", []string{"spam"}},
+		// Only whole words count.
+		{"spammer", nil},
+	}
+	for _, test := range tests {
+		if got := FindBannedWords(test.text, banned); !slices.Equal(got, test.want) {
+			t.Errorf("FindBannedWords(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package filter
+
+import (
+	"embed"
+	"path"
+	"slices"
+	"strings"
+)
+
+// packFiles holds the preset banword packs, one word per line, named after
+// the language they are for.
+//
+//go:embed packs/*.txt
+var packFiles embed.FS
+
+// packs maps each preset pack's language to its words.
+var packs = loadPacks()
+
+func loadPacks() map[string]map[string]struct{} {
+	entries, err := packFiles.ReadDir("packs")
+	if err != nil {
+		panic(err)
+	}
+	loaded := make(map[string]map[string]struct{}, len(entries))
+	for _, entry := range entries {
+		data, err := packFiles.ReadFile(path.Join("packs", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+		words := make(map[string]struct{})
+		for _, word := range strings.Fields(Normalize(string(data))) {
+			words[word] = struct{}{}
+		}
+		loaded[strings.TrimSuffix(entry.Name(), ".txt")] = words
+	}
+	return loaded
+}
+
+// Packs returns the languages there are preset banword packs for.
+func Packs() []string {
+	languages := make([]string, 0, len(packs))
+	for language := range packs {
+		languages = append(languages, language)
+	}
+	slices.Sort(languages)
+	return languages
+}
+
+// PackWords returns the words of the language's preset pack, or nil when
+// there is no pack for it.
+func PackWords(language string) map[string]struct{} {
+	return packs[language]
+}
@@ -0,0 +1,21 @@
+package filter
+
+import (
+	"regexp"
+	"strings"
+)
+
+// profanityPattern matches common English profanity in translator output.
+var profanityPattern = regexp.MustCompile(`(?i)\b(?:motherfuck\w*|fuck\w*|bullshit\w*|shit\w*|bitch\w*|cunt\w*|asshole\w*|bastards?|dickheads?|wank\w*|twats?|sluts?|whores?|piss(?:ed)?)\b`)
+
+// ContainsProfanity reports whether the text contains profanity.
+func ContainsProfanity(text string) bool {
+	return profanityPattern.MatchString(text)
+}
+
+// MaskProfanity replaces each profane word with asterisks.
+func MaskProfanity(text string) string {
+	return profanityPattern.ReplaceAllStringFunc(text, func(word string) string {
+		return strings.Repeat("*", len([]rune(word)))
+	})
+}
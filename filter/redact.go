@@ -0,0 +1,23 @@
+package filter
+
+import "regexp"
+
+var (
+	mentionPattern = regexp.MustCompile(`<(?:@[!&]?|#)\d+>`)
+	emailPattern   = regexp.MustCompile(`[\w.+-]+@[\w-]+\.[\w.-]+`)
+	linkPattern    = regexp.MustCompile(`https?://\S+`)
+	numberPattern  = regexp.MustCompile(`\d{4,}`)
+)
+
+// Redact removes personal details from text that is sent to a third party.
+// Mentions and email addresses are always removed; strict redaction also
+// removes links and long numbers such as phone or account numbers.
+func Redact(text string, strict bool) string {
+	text = mentionPattern.ReplaceAllString(text, "[mention]")
+	text = emailPattern.ReplaceAllString(text, "[email]")
+	if strict {
+		text = linkPattern.ReplaceAllString(text, "[link]")
+		text = numberPattern.ReplaceAllString(text, "[number]")
+	}
+	return text
+}
@@ -0,0 +1,98 @@
+package filter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// ToxicityScorer rates how toxic a text is, from 0 for harmless to 1 for
+// almost certainly toxic.
+type ToxicityScorer interface {
+	Name() string
+	Score(text string) (float64, error)
+}
+
+var toxicityClient = &http.Client{Timeout: 10 * time.Second}
+
+// NewToxicityScorerFromEnv returns the Perspective API scorer when
+// PERSPECTIVE_API_KEY is set, a local model server when TOXICITY_URL is set,
+// or nil when toxicity checks aren't available.
+func NewToxicityScorerFromEnv() ToxicityScorer {
+	if apiKey := os.Getenv("PERSPECTIVE_API_KEY"); apiKey != "" {
+		return &perspectiveScorer{apiKey: apiKey}
+	}
+	if endpoint := os.Getenv("TOXICITY_URL"); endpoint != "" {
+		return &localScorer{endpoint: endpoint}
+	}
+	return nil
+}
+
+type perspectiveScorer struct {
+	apiKey string
+}
+
+func (p *perspectiveScorer) Name() string { return "perspective" }
+
+func (p *perspectiveScorer) Score(text string) (float64, error) {
+	request := map[string]any{
+		"comment":             map[string]string{"text": text},
+		"requestedAttributes": map[string]any{"TOXICITY": map[string]any{}},
+		"doNotStore":          true,
+	}
+	var result struct {
+		AttributeScores struct {
+			Toxicity struct {
+				SummaryScore struct {
+					Value float64 `json:"value"`
+				} `json:"summaryScore"`
+			} `json:"TOXICITY"`
+		} `json:"attributeScores"`
+	}
+	endpoint := "https://commentanalyzer.googleapis.com/v1alpha1/comments:analyze?key=" + url.QueryEscape(p.apiKey)
+	if err := postJSON(endpoint, request, &result); err != nil {
+		return 0, err
+	}
+	return result.AttributeScores.Toxicity.SummaryScore.Value, nil
+}
+
+// localScorer asks a self-hosted model server, which receives {"text": ...}
+// and answers {"score": ...}.
+type localScorer struct {
+	endpoint string
+}
+
+func (l *localScorer) Name() string { return "local" }
+
+func (l *localScorer) Score(text string) (float64, error) {
+	var result struct {
+		Score float64 `json:"score"`
+	}
+	if err := postJSON(l.endpoint, map[string]string{"text": text}, &result); err != nil {
+		return 0, err
+	}
+	return result.Score, nil
+}
+
+func postJSON(endpoint string, request, result any) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	resp, err := toxicityClient.Post(endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("toxicity check returned %s: %s", resp.Status, respBody)
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
@@ -0,0 +1,259 @@
+// Package redis is a minimal Redis client speaking the RESP2 protocol. It
+// covers what the bot needs to coordinate several instances: plain commands
+// and pub/sub.
+package redis
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNil is returned for replies that hold no value, such as GET of a
+// missing key.
+var ErrNil = errors.New("redis: nil reply")
+
+// Error is an error reply from the server.
+type Error string
+
+func (e Error) Error() string { return "redis: " + string(e) }
+
+const dialTimeout = 5 * time.Second
+
+// commandTimeout bounds each command, so a stalled server can't hold the
+// client's lock forever.
+var commandTimeout = 5 * time.Second
+
+// Client sends commands over a single connection, which is opened on first
+// use and again after it fails.
+type Client struct {
+	addr     string
+	password string
+	db       int
+
+	mu   sync.Mutex
+	conn *conn
+}
+
+// ParseURL returns a client for a redis://[:password@]host[:port][/db] URL.
+func ParseURL(rawURL string) (*Client, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "redis" {
+		return nil, fmt.Errorf("redis: unsupported URL scheme %q", u.Scheme)
+	}
+	c := &Client{addr: u.Host}
+	if u.Port() == "" {
+		c.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		c.password, _ = u.User.Password()
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		if c.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("redis: invalid database %q", db)
+		}
+	}
+	return c, nil
+}
+
+// Do sends the command and returns its reply: a string, an int64, a []any
+// or nil. Error replies are returned as Error.
+func (c *Client) Do(args ...string) (any, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		cn, err := c.dial()
+		if err != nil {
+			return nil, err
+		}
+		c.conn = cn
+	}
+	reply, err := c.conn.do(args...)
+	if err != nil {
+		if _, ok := err.(Error); !ok {
+			// The connection is in an unknown state; start over next time.
+			c.conn.Close()
+			c.conn = nil
+		}
+	}
+	return reply, err
+}
+
+// String sends the command and returns its reply as a string.
+func (c *Client) String(args ...string) (string, error) {
+	reply, err := c.Do(args...)
+	if err != nil {
+		return "", err
+	}
+	switch reply := reply.(type) {
+	case string:
+		return reply, nil
+	case int64:
+		return strconv.FormatInt(reply, 10), nil
+	case nil:
+		return "", ErrNil
+	}
+	return "", fmt.Errorf("redis: unexpected reply %T", reply)
+}
+
+// Int sends the command and returns its reply as an integer.
+func (c *Client) Int(args ...string) (int64, error) {
+	reply, err := c.String(args...)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(reply, 10, 64)
+}
+
+// Publish posts the message to the pub/sub channel.
+func (c *Client) Publish(channel, message string) error {
+	_, err := c.Do("PUBLISH", channel, message)
+	return err
+}
+
+// Subscribe calls handle with every message posted to the pub/sub channel. It
+// runs until the process exits, reconnecting when the connection drops, and
+// calls connected each time the subscription is (re)established.
+func (c *Client) Subscribe(channel string, connected func(), handle func(message string)) {
+	for {
+		err := c.subscribe(channel, connected, handle)
+		log.Println("Redis subscription lost,", err)
+		time.Sleep(5 * time.Second)
+	}
+}
+
+func (c *Client) subscribe(channel string, connected func(), handle func(message string)) error {
+	cn, err := c.dial()
+	if err != nil {
+		return err
+	}
+	defer cn.Close()
+
+	if _, err := cn.do("SUBSCRIBE", channel); err != nil {
+		return err
+	}
+	if connected != nil {
+		connected()
+	}
+	for {
+		reply, err := cn.read()
+		if err != nil {
+			return err
+		}
+		parts, ok := reply.([]any)
+		if !ok || len(parts) != 3 || parts[0] != "message" {
+			continue
+		}
+		if message, ok := parts[2].(string); ok {
+			handle(message)
+		}
+	}
+}
+
+func (c *Client) dial() (*conn, error) {
+	netConn, err := net.DialTimeout("tcp", c.addr, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	cn := &conn{Conn: netConn, r: bufio.NewReader(netConn)}
+	if c.password != "" {
+		if _, err := cn.do("AUTH", c.password); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+	if c.db != 0 {
+		if _, err := cn.do("SELECT", strconv.Itoa(c.db)); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+	return cn, nil
+}
+
+type conn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (cn *conn) do(args ...string) (any, error) {
+	if err := cn.SetDeadline(time.Now().Add(commandTimeout)); err != nil {
+		return nil, err
+	}
+	// Subscriptions reuse the connection and block on reads indefinitely.
+	defer cn.SetDeadline(time.Time{})
+	if err := cn.write(args...); err != nil {
+		return nil, err
+	}
+	return cn.read()
+}
+
+func (cn *conn) write(args ...string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	_, err := io.WriteString(cn.Conn, b.String())
+	return err
+}
+
+func (cn *conn) read() (any, error) {
+	line, err := cn.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, Error(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		length, err := strconv.Atoi(line[1:])
+		if err != nil || length < 0 {
+			return nil, err
+		}
+		data := make([]byte, length+2)
+		if _, err := io.ReadFull(cn.r, data); err != nil {
+			return nil, err
+		}
+		return string(data[:length]), nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil || count < 0 {
+			return nil, err
+		}
+		items := make([]any, count)
+		for n := range items {
+			if items[n], err = cn.read(); err != nil {
+				// Nested errors are part of the reply, not of the
+				// connection.
+				if _, ok := err.(Error); !ok {
+					return nil, err
+				}
+				items[n] = err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply %q", line)
+}
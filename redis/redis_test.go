@@ -0,0 +1,131 @@
+package redis
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestDoTimeout checks that a server which never answers fails the command
+// instead of blocking the client.
+func TestDoTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			server, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go io.Copy(io.Discard, server)
+		}
+	}()
+
+	defer func(timeout time.Duration) { commandTimeout = timeout }(commandTimeout)
+	commandTimeout = 50 * time.Millisecond
+
+	c := &Client{addr: listener.Addr().String()}
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.Do("PING")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		var netErr net.Error
+		if !errors.As(err, &netErr) || !netErr.Timeout() {
+			t.Errorf("Do() error = %v, want a timeout", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Do() blocked on a stalled server")
+	}
+	if c.conn != nil {
+		t.Error("Do() kept the timed out connection")
+	}
+}
+
+// TestDoClearsDeadline checks that a connection used for a command can
+// still block on reads afterwards, as subscriptions do.
+func TestDoClearsDeadline(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	defer func(timeout time.Duration) { commandTimeout = timeout }(commandTimeout)
+	commandTimeout = 50 * time.Millisecond
+
+	go func() {
+		r := bufio.NewReader(server)
+		for n := 0; n < 5; n++ { // *2, $4, AUTH, $6, secret
+			r.ReadString('\n')
+		}
+		server.Write([]byte("+OK\r\n"))
+		time.Sleep(2 * commandTimeout)
+		server.Write([]byte(":1\r\n"))
+	}()
+
+	cn := &conn{Conn: client, r: bufio.NewReader(client)}
+	if _, err := cn.do("AUTH", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if reply, err := cn.read(); err != nil || reply != int64(1) {
+		t.Errorf("read() = %v, %v, want 1", reply, err)
+	}
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		reply string
+		want  any
+	}{
+		{"+OK\r\n", "OK"},
+		{":42\r\n", int64(42)},
+		{"$5\r\nhello\r\n", "hello"},
+		{"$0\r\n\r\n", ""},
+		{"$-1\r\n", nil},
+		{"$7\r\nbad\r\nok\r\n", "bad\r\nok"},
+		{"*2\r\n$7\r\nmessage\r\n:1\r\n", []any{"message", int64(1)}},
+		{"*-1\r\n", nil},
+		{"*2\r\n+OK\r\n-ERR nested\r\n", []any{"OK", Error("ERR nested")}},
+	}
+	for _, test := range tests {
+		cn := &conn{r: bufio.NewReader(strings.NewReader(test.reply))}
+		got, err := cn.read()
+		if err != nil {
+			t.Errorf("read(%q) error = %v", test.reply, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("read(%q) = %#v, want %#v", test.reply, got, test.want)
+		}
+	}
+
+	errorTests := []string{
+		"-ERR unknown command\r\n",
+		"",
+		"\r\n",
+		"?what\r\n",
+		":notanumber\r\n",
+		"$10\r\nshort\r\n",
+		"*2\r\n+OK\r\n",
+	}
+	for _, reply := range errorTests {
+		cn := &conn{r: bufio.NewReader(strings.NewReader(reply))}
+		if got, err := cn.read(); err == nil {
+			t.Errorf("read(%q) = %#v, want an error", reply, got)
+		}
+	}
+
+	cn := &conn{r: bufio.NewReader(strings.NewReader("-WRONGTYPE not a list\r\n"))}
+	if _, err := cn.read(); err != Error("WRONGTYPE not a list") {
+		t.Errorf("read() error = %#v, want the server's Error", err)
+	}
+}
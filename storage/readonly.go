@@ -0,0 +1,73 @@
+package storage
+
+import "time"
+
+// ReadOnly returns a Store that reads from the store but discards all writes,
+// so the bot can run against real configuration without changing it.
+func ReadOnly(store Store) Store {
+	return readOnly{store}
+}
+
+type readOnly struct {
+	Store
+}
+
+func (readOnly) SetTranslateChannels(serverID string, channelIDs [3]string) error { return nil }
+
+func (readOnly) AddBannedWord(word BannedWord) (bool, error) { return true, nil }
+
+func (readOnly) RemoveBannedWord(serverID, word string) (bool, error) { return true, nil }
+
+func (readOnly) PruneBannedWords(before time.Time) (int64, error) { return 0, nil }
+
+func (readOnly) SetBotSetting(key, value string) error { return nil }
+
+func (readOnly) SetGuildSetting(serverID, key, value string) error { return nil }
+
+func (readOnly) SetChannelSetting(serverID, channelID, key, value string) error { return nil }
+
+func (readOnly) SetUserLocale(userID, locale string) error { return nil }
+
+func (readOnly) RecordUsage(serverID, day string, characters int) error { return nil }
+
+func (readOnly) RecordBilling(backend, keyID, serverID, day string, characters int) error {
+	return nil
+}
+
+func (readOnly) RecordUserStats(serverID, userID string, characters int) error { return nil }
+
+func (readOnly) RecordPairStats(serverID, sourceLang, targetLang string) error { return nil }
+
+func (readOnly) RecordLanguageUsage(serverID, day, sourceLang string) error { return nil }
+
+func (readOnly) RecordError(serverID, day string) error { return nil }
+
+func (readOnly) RecordBanwordHit(serverID, word string, at time.Time) error { return nil }
+
+func (readOnly) RecordMessage(message HistoryMessage) error { return nil }
+
+func (readOnly) SetRoute(route Route) error { return nil }
+
+func (readOnly) RemoveRoute(serverID, sourceChannelID, destinationChannelID string) (bool, error) {
+	return true, nil
+}
+
+func (readOnly) Subscribe(subscription Subscription) error { return nil }
+
+func (readOnly) Unsubscribe(userID, channelID string) (bool, error) { return true, nil }
+
+func (readOnly) LinkMessage(link MessageLink) error { return nil }
+
+func (readOnly) PruneMessageLinks(serverID string, before time.Time) (int64, error) { return 0, nil }
+
+func (readOnly) RecordSkip(skip Skip) error { return nil }
+
+func (readOnly) PruneSkips(before time.Time) (int64, error) { return 0, nil }
+
+func (readOnly) Close() error { return nil }
+
+func (readOnly) RecordWarning(warning Warning) error { return nil }
+
+func (readOnly) ClearWarnings(serverID, userID string) error { return nil }
+
+func (readOnly) PruneWarnings(before time.Time) (int64, error) { return 0, nil }
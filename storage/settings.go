@@ -0,0 +1,175 @@
+package storage
+
+import "sync"
+
+// Settings holds bot, guild and channel settings in memory, writing changes
+// through to the store.
+type Settings struct {
+	store SettingStore
+
+	mu      sync.RWMutex
+	bot     map[string]string
+	guild   map[string]map[string]string
+	channel map[string]map[string]string
+}
+
+// LoadSettings reads all bot, guild and channel settings from the store.
+func LoadSettings(store SettingStore) (*Settings, error) {
+	bot, err := store.BotSettings()
+	if err != nil {
+		return nil, err
+	}
+	guild, err := store.GuildSettings()
+	if err != nil {
+		return nil, err
+	}
+	channel, err := store.ChannelSettings()
+	if err != nil {
+		return nil, err
+	}
+	return &Settings{store: store, bot: bot, guild: guild, channel: channel}, nil
+}
+
+// Bot returns a setting of the bot as a whole, or an empty string when it
+// isn't set.
+func (s *Settings) Bot(key string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.bot[key]
+}
+
+// SetBot stores a setting of the bot as a whole. An empty value removes it.
+func (s *Settings) SetBot(key, value string) error {
+	if err := s.store.SetBotSetting(key, value); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	setBot(s.bot, key, value)
+	s.mu.Unlock()
+	return nil
+}
+
+// CacheBot records a setting of the bot that was stored elsewhere without
+// writing it to the store.
+func (s *Settings) CacheBot(key, value string) {
+	s.mu.Lock()
+	setBot(s.bot, key, value)
+	s.mu.Unlock()
+}
+
+// Guild returns a setting of the server, or an empty string when it isn't set.
+func (s *Settings) Guild(serverID, key string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.guild[serverID][key]
+}
+
+// SetGuild stores a setting for the server. An empty value removes it.
+func (s *Settings) SetGuild(serverID, key, value string) error {
+	if err := s.store.SetGuildSetting(serverID, key, value); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	set(s.guild, serverID, key, value)
+	s.mu.Unlock()
+	return nil
+}
+
+// CacheGuild records a setting of the server that was stored elsewhere, such
+// as by another instance of the bot, without writing it to the store.
+func (s *Settings) CacheGuild(serverID, key, value string) {
+	s.mu.Lock()
+	set(s.guild, serverID, key, value)
+	s.mu.Unlock()
+}
+
+// GuildAll returns a copy of all settings of the server.
+func (s *Settings) GuildAll(serverID string) map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return copySettings(s.guild[serverID])
+}
+
+// Guilds returns a copy of the settings of every server that has any.
+func (s *Settings) Guilds() map[string]map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	guilds := make(map[string]map[string]string, len(s.guild))
+	for serverID, settings := range s.guild {
+		guilds[serverID] = copySettings(settings)
+	}
+	return guilds
+}
+
+// Channel returns a setting of the channel, or an empty string when it isn't
+// set.
+func (s *Settings) Channel(channelID, key string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.channel[channelID][key]
+}
+
+// SetChannel stores a setting for the channel. An empty value removes it.
+func (s *Settings) SetChannel(serverID, channelID, key, value string) error {
+	if err := s.store.SetChannelSetting(serverID, channelID, key, value); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	set(s.channel, channelID, key, value)
+	s.mu.Unlock()
+	return nil
+}
+
+// CacheChannel records a setting of the channel that was stored elsewhere
+// without writing it to the store.
+func (s *Settings) CacheChannel(channelID, key, value string) {
+	s.mu.Lock()
+	set(s.channel, channelID, key, value)
+	s.mu.Unlock()
+}
+
+// ChannelsWith returns the channels that have the setting.
+func (s *Settings) ChannelsWith(key string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var channelIDs []string
+	for channelID, settings := range s.channel {
+		if settings[key] != "" {
+			channelIDs = append(channelIDs, channelID)
+		}
+	}
+	return channelIDs
+}
+
+func set(settings map[string]map[string]string, id, key, value string) {
+	if value == "" {
+		delete(settings[id], key)
+		if len(settings[id]) == 0 {
+			delete(settings, id)
+		}
+		return
+	}
+	if settings[id] == nil {
+		settings[id] = make(map[string]string)
+	}
+	settings[id][key] = value
+}
+
+func setBot(settings map[string]string, key, value string) {
+	if value == "" {
+		delete(settings, key)
+		return
+	}
+	settings[key] = value
+}
+
+func copySettings(settings map[string]string) map[string]string {
+	copied := make(map[string]string, len(settings))
+	for key, value := range settings {
+		copied[key] = value
+	}
+	return copied
+}
@@ -0,0 +1,819 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// SQLite is a Store backed by a SQLite database.
+type SQLite struct {
+	db *sql.DB
+}
+
+var _ Store = (*SQLite)(nil)
+
+// busyTimeout is how long a write waits for another connection, or another
+// instance sharing the file, to finish its own before giving up.
+const busyTimeout = 5 * time.Second
+
+// Open opens the SQLite database at the path, creating any missing tables.
+func Open(path string) (*SQLite, error) {
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	db, err := sql.Open("sqlite", fmt.Sprintf("%s%s_pragma=busy_timeout(%d)", path, separator, busyTimeout.Milliseconds()))
+	if err != nil {
+		return nil, err
+	}
+	if err := createTables(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLite{db: db}, nil
+}
+
+func (s *SQLite) Close() error {
+	return s.db.Close()
+}
+
+func createTables(db *sql.DB) error {
+	channelTableQuery := `CREATE TABLE IF NOT EXISTS channels (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		server_id TEXT NOT NULL,
+		channel_id1 TEXT,
+		channel_id2 TEXT,
+		channel_id3 TEXT,
+		channel_id4 TEXT,
+		channel_id5 TEXT,
+		UNIQUE(server_id)
+	);`
+
+	wordbanTableQuery := `CREATE TABLE IF NOT EXISTS wordban (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		word TEXT NOT NULL UNIQUE,
+		severity TEXT NOT NULL DEFAULT '',
+		expires_at TEXT NOT NULL DEFAULT ''
+	);`
+
+	botSettingsTableQuery := `CREATE TABLE IF NOT EXISTS bot_settings (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	);`
+
+	guildSettingsTableQuery := `CREATE TABLE IF NOT EXISTS guild_settings (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		server_id TEXT NOT NULL,
+		key TEXT NOT NULL,
+		value TEXT NOT NULL,
+		UNIQUE(server_id, key)
+	);`
+
+	channelSettingsTableQuery := `CREATE TABLE IF NOT EXISTS channel_settings (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		server_id TEXT NOT NULL,
+		channel_id TEXT NOT NULL,
+		key TEXT NOT NULL,
+		value TEXT NOT NULL,
+		UNIQUE(channel_id, key)
+	);`
+
+	usageTableQuery := `CREATE TABLE IF NOT EXISTS usage (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		server_id TEXT NOT NULL,
+		day TEXT NOT NULL,
+		characters INTEGER NOT NULL DEFAULT 0,
+		UNIQUE(server_id, day)
+	);`
+
+	userStatsTableQuery := `CREATE TABLE IF NOT EXISTS user_stats (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		server_id TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		translations INTEGER NOT NULL DEFAULT 0,
+		characters INTEGER NOT NULL DEFAULT 0,
+		UNIQUE(server_id, user_id)
+	);`
+
+	pairStatsTableQuery := `CREATE TABLE IF NOT EXISTS pair_stats (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		server_id TEXT NOT NULL,
+		source_lang TEXT NOT NULL,
+		target_lang TEXT NOT NULL,
+		translations INTEGER NOT NULL DEFAULT 0,
+		UNIQUE(server_id, source_lang, target_lang)
+	);`
+
+	banwordStatsTableQuery := `CREATE TABLE IF NOT EXISTS banword_stats (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		server_id TEXT NOT NULL,
+		word TEXT NOT NULL,
+		hits INTEGER NOT NULL DEFAULT 0,
+		last_hit TEXT NOT NULL,
+		UNIQUE(server_id, word)
+	);`
+
+	languageUsageTableQuery := `CREATE TABLE IF NOT EXISTS language_usage (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		server_id TEXT NOT NULL,
+		day TEXT NOT NULL,
+		source_lang TEXT NOT NULL,
+		translations INTEGER NOT NULL DEFAULT 0,
+		UNIQUE(server_id, day, source_lang)
+	);`
+
+	errorCountsTableQuery := `CREATE TABLE IF NOT EXISTS error_counts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		server_id TEXT NOT NULL,
+		day TEXT NOT NULL,
+		errors INTEGER NOT NULL DEFAULT 0,
+		UNIQUE(server_id, day)
+	);`
+
+	billingTableQuery := `CREATE TABLE IF NOT EXISTS billing (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		backend TEXT NOT NULL,
+		key_id TEXT NOT NULL,
+		server_id TEXT NOT NULL,
+		day TEXT NOT NULL,
+		characters INTEGER NOT NULL DEFAULT 0,
+		UNIQUE(backend, key_id, server_id, day)
+	);`
+
+	userLocalesTableQuery := `CREATE TABLE IF NOT EXISTS user_locales (
+		user_id TEXT PRIMARY KEY,
+		locale TEXT NOT NULL
+	);`
+
+	messageHistoryTableQuery := `CREATE TABLE IF NOT EXISTS message_history (
+		message_id TEXT PRIMARY KEY,
+		server_id TEXT NOT NULL,
+		channel_id TEXT NOT NULL,
+		author_id TEXT NOT NULL,
+		author_name TEXT NOT NULL,
+		content TEXT NOT NULL,
+		output TEXT NOT NULL,
+		created_at TEXT NOT NULL
+	);`
+
+	routesTableQuery := `CREATE TABLE IF NOT EXISTS routes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		server_id TEXT NOT NULL,
+		source_channel_id TEXT NOT NULL,
+		destination_channel_id TEXT NOT NULL,
+		target_lang TEXT NOT NULL,
+		author_mode TEXT NOT NULL,
+		UNIQUE(source_channel_id, destination_channel_id)
+	);`
+
+	subscriptionsTableQuery := `CREATE TABLE IF NOT EXISTS subscriptions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id TEXT NOT NULL,
+		server_id TEXT NOT NULL,
+		channel_id TEXT NOT NULL,
+		target_lang TEXT NOT NULL,
+		frequency TEXT NOT NULL,
+		UNIQUE(user_id, channel_id)
+	);`
+
+	messageLinksTableQuery := `CREATE TABLE IF NOT EXISTS message_links (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		server_id TEXT NOT NULL,
+		original_channel_id TEXT NOT NULL,
+		original_message_id TEXT NOT NULL,
+		translation_channel_id TEXT NOT NULL,
+		translation_message_id TEXT NOT NULL,
+		created_at TEXT NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS message_links_original ON message_links (original_message_id);
+	CREATE INDEX IF NOT EXISTS message_links_translation ON message_links (translation_message_id);`
+
+	skipsTableQuery := `CREATE TABLE IF NOT EXISTS skips (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		server_id TEXT NOT NULL,
+		channel_id TEXT NOT NULL,
+		message_id TEXT NOT NULL,
+		reason TEXT NOT NULL,
+		created_at TEXT NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS skips_server ON skips (server_id, created_at);`
+
+	warningsTableQuery := `CREATE TABLE IF NOT EXISTS warnings (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		server_id TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		channel_id TEXT NOT NULL,
+		message_id TEXT NOT NULL,
+		reason TEXT NOT NULL,
+		created_at TEXT NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS warnings_user ON warnings (server_id, user_id, created_at);`
+
+	queries := []string{
+		channelTableQuery,
+		wordbanTableQuery,
+		botSettingsTableQuery,
+		guildSettingsTableQuery,
+		channelSettingsTableQuery,
+		usageTableQuery,
+		userStatsTableQuery,
+		pairStatsTableQuery,
+		languageUsageTableQuery,
+		banwordStatsTableQuery,
+		errorCountsTableQuery,
+		billingTableQuery,
+		userLocalesTableQuery,
+		messageHistoryTableQuery,
+		routesTableQuery,
+		subscriptionsTableQuery,
+		messageLinksTableQuery,
+		skipsTableQuery,
+		warningsTableQuery,
+	}
+	for _, query := range queries {
+		if _, err := db.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	// Columns added to tables after they were first created.
+	if err := addColumn(db, "wordban", "severity", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addColumn(db, "wordban", "expires_at", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	return addColumn(db, "wordban", "server_id", "TEXT NOT NULL DEFAULT ''")
+}
+
+// addColumn adds the column to a table created by an older version, unless
+// the table has it already.
+func addColumn(db *sql.DB, table, column, definition string) error {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", table, column).Scan(&count)
+	if err != nil || count > 0 {
+		return err
+	}
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
+
+func (s *SQLite) TranslateChannels() (map[string][3]string, error) {
+	rows, err := s.db.Query("SELECT server_id, channel_id1, channel_id2, channel_id3 FROM channels")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	translateChannels := make(map[string][3]string)
+	for rows.Next() {
+		var serverID sql.NullString
+		var channelID1, channelID2, channelID3 sql.NullString
+		if err := rows.Scan(&serverID, &channelID1, &channelID2, &channelID3); err != nil {
+			return nil, err
+		}
+		translateChannels[serverID.String] = [3]string{
+			channelID1.String,
+			channelID2.String,
+			channelID3.String,
+		}
+	}
+
+	return translateChannels, rows.Err()
+}
+
+func (s *SQLite) SetTranslateChannels(serverID string, channelIDs [3]string) error {
+	var values [3]sql.NullString
+	for n, channelID := range channelIDs {
+		values[n] = sql.NullString{String: channelID, Valid: channelID != ""}
+	}
+	_, err := s.db.Exec("INSERT OR REPLACE INTO channels (server_id, channel_id1, channel_id2, channel_id3) VALUES (?, ?, ?, ?)", serverID, values[0], values[1], values[2])
+	return err
+}
+
+func (s *SQLite) BannedWords() ([]BannedWord, error) {
+	rows, err := s.db.Query("SELECT word, severity, expires_at, server_id FROM wordban")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bannedWords []BannedWord
+	for rows.Next() {
+		var word BannedWord
+		var expiresAt string
+		if err := rows.Scan(&word.Word, &word.Severity, &expiresAt, &word.ServerID); err != nil {
+			return nil, err
+		}
+		if expiresAt != "" {
+			word.ExpiresAt, _ = time.Parse(time.RFC3339, expiresAt)
+		}
+		bannedWords = append(bannedWords, word)
+	}
+
+	return bannedWords, rows.Err()
+}
+
+func (s *SQLite) AddBannedWord(word BannedWord) (bool, error) {
+	expiresAt := ""
+	if !word.ExpiresAt.IsZero() {
+		expiresAt = word.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	// Words banned before servers were recorded are taken over by the
+	// first server to ban them again.
+	result, err := s.db.Exec(`INSERT INTO wordban (word, severity, expires_at, server_id) VALUES (?, ?, ?, ?)
+		ON CONFLICT(word) DO UPDATE SET severity = excluded.severity, expires_at = excluded.expires_at, server_id = excluded.server_id
+		WHERE server_id IN ('', excluded.server_id)
+			AND (severity != excluded.severity OR expires_at != excluded.expires_at OR server_id != excluded.server_id)`,
+		word.Word, word.Severity, expiresAt, word.ServerID)
+	if err != nil {
+		return false, err
+	}
+	added, err := result.RowsAffected()
+	return added > 0, err
+}
+
+func (s *SQLite) RemoveBannedWord(serverID, word string) (bool, error) {
+	result, err := s.db.Exec("DELETE FROM wordban WHERE word = ? AND server_id IN ('', ?)", word, serverID)
+	if err != nil {
+		return false, err
+	}
+	removed, err := result.RowsAffected()
+	return removed > 0, err
+}
+
+func (s *SQLite) PruneBannedWords(before time.Time) (int64, error) {
+	result, err := s.db.Exec("DELETE FROM wordban WHERE expires_at != '' AND expires_at < ?", before.UTC().Format(time.RFC3339))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (s *SQLite) BotSettings() (map[string]string, error) {
+	rows, err := s.db.Query("SELECT key, value FROM bot_settings")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	settings := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+		settings[key] = value
+	}
+	return settings, rows.Err()
+}
+
+func (s *SQLite) SetBotSetting(key, value string) error {
+	var err error
+	if value == "" {
+		_, err = s.db.Exec("DELETE FROM bot_settings WHERE key = ?", key)
+	} else {
+		_, err = s.db.Exec("INSERT OR REPLACE INTO bot_settings (key, value) VALUES (?, ?)", key, value)
+	}
+	return err
+}
+
+func (s *SQLite) GuildSettings() (map[string]map[string]string, error) {
+	return s.keyValues("SELECT server_id, key, value FROM guild_settings")
+}
+
+func (s *SQLite) SetGuildSetting(serverID, key, value string) error {
+	var err error
+	if value == "" {
+		_, err = s.db.Exec("DELETE FROM guild_settings WHERE server_id = ? AND key = ?", serverID, key)
+	} else {
+		_, err = s.db.Exec("INSERT OR REPLACE INTO guild_settings (server_id, key, value) VALUES (?, ?, ?)", serverID, key, value)
+	}
+	return err
+}
+
+func (s *SQLite) ChannelSettings() (map[string]map[string]string, error) {
+	return s.keyValues("SELECT channel_id, key, value FROM channel_settings")
+}
+
+func (s *SQLite) SetChannelSetting(serverID, channelID, key, value string) error {
+	var err error
+	if value == "" {
+		_, err = s.db.Exec("DELETE FROM channel_settings WHERE channel_id = ? AND key = ?", channelID, key)
+	} else {
+		_, err = s.db.Exec("INSERT OR REPLACE INTO channel_settings (server_id, channel_id, key, value) VALUES (?, ?, ?, ?)", serverID, channelID, key, value)
+	}
+	return err
+}
+
+// keyValues reads (id, key, value) rows into a map of settings by ID.
+func (s *SQLite) keyValues(query string) (map[string]map[string]string, error) {
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	settings := make(map[string]map[string]string)
+	for rows.Next() {
+		var id, key, value string
+		if err := rows.Scan(&id, &key, &value); err != nil {
+			return nil, err
+		}
+		set(settings, id, key, value)
+	}
+	return settings, rows.Err()
+}
+
+func (s *SQLite) SetUserLocale(userID, locale string) error {
+	_, err := s.db.Exec("INSERT OR REPLACE INTO user_locales (user_id, locale) VALUES (?, ?)", userID, locale)
+	return err
+}
+
+func (s *SQLite) UserLocale(userID string) (string, error) {
+	var locale string
+	err := s.db.QueryRow("SELECT locale FROM user_locales WHERE user_id = ?", userID).Scan(&locale)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return locale, err
+}
+
+func (s *SQLite) RecordUsage(serverID, day string, characters int) error {
+	_, err := s.db.Exec(`INSERT INTO usage (server_id, day, characters) VALUES (?, ?, ?)
+		ON CONFLICT(server_id, day) DO UPDATE SET characters = characters + excluded.characters`,
+		serverID, day, characters)
+	return err
+}
+
+func (s *SQLite) UsageSince(serverID, day string) (int, error) {
+	var characters int
+	err := s.db.QueryRow("SELECT COALESCE(SUM(characters), 0) FROM usage WHERE server_id = ? AND day >= ?", serverID, day).Scan(&characters)
+	return characters, err
+}
+
+func (s *SQLite) RecordBilling(backend, keyID, serverID, day string, characters int) error {
+	_, err := s.db.Exec(`INSERT INTO billing (backend, key_id, server_id, day, characters) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(backend, key_id, server_id, day) DO UPDATE SET characters = characters + excluded.characters`,
+		backend, keyID, serverID, day, characters)
+	return err
+}
+
+func (s *SQLite) Billing(month string) ([]BillingRecord, error) {
+	rows, err := s.db.Query(`SELECT backend, key_id, server_id, SUM(characters) FROM billing
+		WHERE day LIKE ? GROUP BY backend, key_id, server_id ORDER BY backend, key_id, server_id`, month+"-%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []BillingRecord
+	for rows.Next() {
+		var record BillingRecord
+		if err := rows.Scan(&record.Backend, &record.KeyID, &record.ServerID, &record.Characters); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+func (s *SQLite) RecordUserStats(serverID, userID string, characters int) error {
+	_, err := s.db.Exec(`INSERT INTO user_stats (server_id, user_id, translations, characters) VALUES (?, ?, 1, ?)
+		ON CONFLICT(server_id, user_id) DO UPDATE SET translations = translations + 1, characters = characters + excluded.characters`,
+		serverID, userID, characters)
+	return err
+}
+
+func (s *SQLite) TopUsers(serverID string, limit int) ([]UserStats, error) {
+	rows, err := s.db.Query("SELECT user_id, translations, characters FROM user_stats WHERE server_id = ? ORDER BY translations DESC LIMIT ?", serverID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []UserStats
+	for rows.Next() {
+		var user UserStats
+		if err := rows.Scan(&user.UserID, &user.Translations, &user.Characters); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+func (s *SQLite) RecordPairStats(serverID, sourceLang, targetLang string) error {
+	_, err := s.db.Exec(`INSERT INTO pair_stats (server_id, source_lang, target_lang, translations) VALUES (?, ?, ?, 1)
+		ON CONFLICT(server_id, source_lang, target_lang) DO UPDATE SET translations = translations + 1`,
+		serverID, sourceLang, targetLang)
+	return err
+}
+
+func (s *SQLite) TopPairs(serverID string, limit int) ([]PairStats, error) {
+	rows, err := s.db.Query("SELECT source_lang, target_lang, translations FROM pair_stats WHERE server_id = ? ORDER BY translations DESC LIMIT ?", serverID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pairs []PairStats
+	for rows.Next() {
+		var pair PairStats
+		if err := rows.Scan(&pair.SourceLang, &pair.TargetLang, &pair.Translations); err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs, rows.Err()
+}
+
+func (s *SQLite) RecordBanwordHit(serverID, word string, at time.Time) error {
+	lastHit := at.UTC().Format(time.RFC3339)
+	_, err := s.db.Exec(`INSERT INTO banword_stats (server_id, word, hits, last_hit) VALUES (?, ?, 1, ?)
+		ON CONFLICT(server_id, word) DO UPDATE SET hits = hits + 1, last_hit = excluded.last_hit`,
+		serverID, word, lastHit)
+	return err
+}
+
+func (s *SQLite) BanwordHits(serverID string) ([]BanwordHits, error) {
+	rows, err := s.db.Query("SELECT word, hits, last_hit FROM banword_stats WHERE server_id = ? ORDER BY hits DESC", serverID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hits []BanwordHits
+	for rows.Next() {
+		var word BanwordHits
+		var lastHit string
+		if err := rows.Scan(&word.Word, &word.Hits, &lastHit); err != nil {
+			return nil, err
+		}
+		word.LastHit, _ = time.Parse(time.RFC3339, lastHit)
+		hits = append(hits, word)
+	}
+	return hits, rows.Err()
+}
+
+func (s *SQLite) RecordLanguageUsage(serverID, day, sourceLang string) error {
+	_, err := s.db.Exec(`INSERT INTO language_usage (server_id, day, source_lang, translations) VALUES (?, ?, ?, 1)
+		ON CONFLICT(server_id, day, source_lang) DO UPDATE SET translations = translations + 1`,
+		serverID, day, sourceLang)
+	return err
+}
+
+func (s *SQLite) LanguageUsageSince(serverID, day string) ([]LanguageCount, error) {
+	rows, err := s.db.Query(`SELECT source_lang, SUM(translations) AS total FROM language_usage
+		WHERE server_id = ? AND day >= ? GROUP BY source_lang ORDER BY total DESC`, serverID, day)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var languages []LanguageCount
+	for rows.Next() {
+		var language LanguageCount
+		if err := rows.Scan(&language.Language, &language.Translations); err != nil {
+			return nil, err
+		}
+		languages = append(languages, language)
+	}
+	return languages, rows.Err()
+}
+
+func (s *SQLite) RecordError(serverID, day string) error {
+	_, err := s.db.Exec(`INSERT INTO error_counts (server_id, day, errors) VALUES (?, ?, 1)
+		ON CONFLICT(server_id, day) DO UPDATE SET errors = errors + 1`,
+		serverID, day)
+	return err
+}
+
+func (s *SQLite) ErrorsSince(serverID, day string) (int, error) {
+	var errors int
+	err := s.db.QueryRow("SELECT COALESCE(SUM(errors), 0) FROM error_counts WHERE server_id = ? AND day >= ?", serverID, day).Scan(&errors)
+	return errors, err
+}
+
+func (s *SQLite) RecordMessage(message HistoryMessage) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO message_history (message_id, server_id, channel_id, author_id, author_name, content, output, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		message.MessageID, message.ServerID, message.ChannelID, message.AuthorID, message.AuthorName,
+		message.Content, message.Output, message.CreatedAt.UTC().Format(time.RFC3339))
+	return err
+}
+
+func (s *SQLite) Messages(serverID string, limit int) ([]HistoryMessage, error) {
+	rows, err := s.db.Query(`SELECT message_id, server_id, channel_id, author_id, author_name, content, output, created_at FROM (
+		SELECT * FROM message_history WHERE ? = '' OR server_id = ? ORDER BY created_at DESC LIMIT ?
+	) ORDER BY created_at`, serverID, serverID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []HistoryMessage
+	for rows.Next() {
+		var message HistoryMessage
+		var createdAt string
+		if err := rows.Scan(&message.MessageID, &message.ServerID, &message.ChannelID, &message.AuthorID,
+			&message.AuthorName, &message.Content, &message.Output, &createdAt); err != nil {
+			return nil, err
+		}
+		message.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		messages = append(messages, message)
+	}
+	return messages, rows.Err()
+}
+
+func (s *SQLite) Routes() ([]Route, error) {
+	rows, err := s.db.Query("SELECT server_id, source_channel_id, destination_channel_id, target_lang, author_mode FROM routes ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var routes []Route
+	for rows.Next() {
+		var route Route
+		if err := rows.Scan(&route.ServerID, &route.SourceChannelID, &route.DestinationChannelID, &route.TargetLang, &route.AuthorMode); err != nil {
+			return nil, err
+		}
+		routes = append(routes, route)
+	}
+	return routes, rows.Err()
+}
+
+func (s *SQLite) SetRoute(route Route) error {
+	_, err := s.db.Exec(`INSERT INTO routes (server_id, source_channel_id, destination_channel_id, target_lang, author_mode) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(source_channel_id, destination_channel_id) DO UPDATE SET target_lang = excluded.target_lang, author_mode = excluded.author_mode`,
+		route.ServerID, route.SourceChannelID, route.DestinationChannelID, route.TargetLang, route.AuthorMode)
+	return err
+}
+
+func (s *SQLite) RemoveRoute(serverID, sourceChannelID, destinationChannelID string) (bool, error) {
+	result, err := s.db.Exec("DELETE FROM routes WHERE server_id = ? AND source_channel_id = ? AND destination_channel_id = ?",
+		serverID, sourceChannelID, destinationChannelID)
+	if err != nil {
+		return false, err
+	}
+	removed, err := result.RowsAffected()
+	return removed > 0, err
+}
+
+func (s *SQLite) Subscriptions() ([]Subscription, error) {
+	rows, err := s.db.Query("SELECT user_id, server_id, channel_id, target_lang, frequency FROM subscriptions ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subscriptions []Subscription
+	for rows.Next() {
+		var subscription Subscription
+		if err := rows.Scan(&subscription.UserID, &subscription.ServerID, &subscription.ChannelID, &subscription.TargetLang, &subscription.Frequency); err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	return subscriptions, rows.Err()
+}
+
+func (s *SQLite) Subscribe(subscription Subscription) error {
+	_, err := s.db.Exec(`INSERT INTO subscriptions (user_id, server_id, channel_id, target_lang, frequency) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, channel_id) DO UPDATE SET target_lang = excluded.target_lang, frequency = excluded.frequency`,
+		subscription.UserID, subscription.ServerID, subscription.ChannelID, subscription.TargetLang, subscription.Frequency)
+	return err
+}
+
+func (s *SQLite) Unsubscribe(userID, channelID string) (bool, error) {
+	result, err := s.db.Exec("DELETE FROM subscriptions WHERE user_id = ? AND channel_id = ?", userID, channelID)
+	if err != nil {
+		return false, err
+	}
+	removed, err := result.RowsAffected()
+	return removed > 0, err
+}
+
+func (s *SQLite) LinkMessage(link MessageLink) error {
+	_, err := s.db.Exec(`INSERT INTO message_links (server_id, original_channel_id, original_message_id, translation_channel_id, translation_message_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		link.ServerID, link.OriginalChannelID, link.OriginalMessageID, link.TranslationChannelID, link.TranslationMessageID, link.CreatedAt.UTC().Format(time.RFC3339))
+	return err
+}
+
+func (s *SQLite) MessageLinks(messageID string) ([]MessageLink, error) {
+	rows, err := s.db.Query(`SELECT server_id, original_channel_id, original_message_id, translation_channel_id, translation_message_id, created_at
+		FROM message_links WHERE original_message_id = ? OR translation_message_id = ? ORDER BY id`, messageID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []MessageLink
+	for rows.Next() {
+		var link MessageLink
+		var createdAt string
+		if err := rows.Scan(&link.ServerID, &link.OriginalChannelID, &link.OriginalMessageID, &link.TranslationChannelID, &link.TranslationMessageID, &createdAt); err != nil {
+			return nil, err
+		}
+		link.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+func (s *SQLite) PruneMessageLinks(serverID string, before time.Time) (int64, error) {
+	result, err := s.db.Exec("DELETE FROM message_links WHERE (? = '' OR server_id = ?) AND created_at < ?",
+		serverID, serverID, before.UTC().Format(time.RFC3339))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (s *SQLite) RecordSkip(skip Skip) error {
+	_, err := s.db.Exec("INSERT INTO skips (server_id, channel_id, message_id, reason, created_at) VALUES (?, ?, ?, ?, ?)",
+		skip.ServerID, skip.ChannelID, skip.MessageID, skip.Reason, skip.CreatedAt.UTC().Format(time.RFC3339))
+	return err
+}
+
+func (s *SQLite) Skips(serverID, channelID string, limit int) ([]Skip, error) {
+	rows, err := s.db.Query(`SELECT server_id, channel_id, message_id, reason, created_at FROM skips
+		WHERE server_id = ? AND (? = '' OR channel_id = ?) ORDER BY id DESC LIMIT ?`, serverID, channelID, channelID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var skips []Skip
+	for rows.Next() {
+		var skip Skip
+		var createdAt string
+		if err := rows.Scan(&skip.ServerID, &skip.ChannelID, &skip.MessageID, &skip.Reason, &createdAt); err != nil {
+			return nil, err
+		}
+		skip.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		skips = append(skips, skip)
+	}
+	return skips, rows.Err()
+}
+
+func (s *SQLite) PruneSkips(before time.Time) (int64, error) {
+	result, err := s.db.Exec("DELETE FROM skips WHERE created_at < ?", before.UTC().Format(time.RFC3339))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (s *SQLite) RecordWarning(warning Warning) error {
+	_, err := s.db.Exec("INSERT INTO warnings (server_id, user_id, channel_id, message_id, reason, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		warning.ServerID, warning.UserID, warning.ChannelID, warning.MessageID, warning.Reason, warning.CreatedAt.UTC().Format(time.RFC3339))
+	return err
+}
+
+func (s *SQLite) Warnings(serverID, userID string, limit int) ([]Warning, error) {
+	rows, err := s.db.Query(`SELECT server_id, user_id, channel_id, message_id, reason, created_at FROM warnings
+		WHERE server_id = ? AND user_id = ? ORDER BY id DESC LIMIT ?`, serverID, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var warnings []Warning
+	for rows.Next() {
+		var warning Warning
+		var createdAt string
+		if err := rows.Scan(&warning.ServerID, &warning.UserID, &warning.ChannelID, &warning.MessageID, &warning.Reason, &createdAt); err != nil {
+			return nil, err
+		}
+		warning.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		warnings = append(warnings, warning)
+	}
+	return warnings, rows.Err()
+}
+
+func (s *SQLite) WarningCount(serverID, userID string, since time.Time) (int, error) {
+	var count int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM warnings WHERE server_id = ? AND user_id = ? AND created_at >= ?",
+		serverID, userID, since.UTC().Format(time.RFC3339)).Scan(&count)
+	return count, err
+}
+
+func (s *SQLite) ClearWarnings(serverID, userID string) error {
+	_, err := s.db.Exec("DELETE FROM warnings WHERE server_id = ? AND user_id = ?", serverID, userID)
+	return err
+}
+
+func (s *SQLite) PruneWarnings(before time.Time) (int64, error) {
+	result, err := s.db.Exec("DELETE FROM warnings WHERE created_at < ?", before.UTC().Format(time.RFC3339))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestOpenBusyTimeout checks that writers from several instances sharing the
+// file wait for each other instead of failing right away.
+func TestOpenBusyTimeout(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "channels.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var timeout int64
+	if err := s.db.QueryRow("PRAGMA busy_timeout").Scan(&timeout); err != nil {
+		t.Fatal(err)
+	}
+	if timeout != busyTimeout.Milliseconds() {
+		t.Errorf("busy_timeout = %d, want %d", timeout, busyTimeout.Milliseconds())
+	}
+}
+
+// TestBannedWordOwner checks that servers can't change or remove the words
+// another server banned.
+func TestBannedWordOwner(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "channels.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	added, err := s.AddBannedWord(BannedWord{Word: "spam", Severity: "escalate", ServerID: "1"})
+	if err != nil || !added {
+		t.Fatalf("AddBannedWord() = %t, %v, want the word added", added, err)
+	}
+	added, err = s.AddBannedWord(BannedWord{Word: "spam", Severity: "warn", ServerID: "2"})
+	if err != nil || added {
+		t.Errorf("AddBannedWord() from another server = %t, %v, want it left alone", added, err)
+	}
+	removed, err := s.RemoveBannedWord("2", "spam")
+	if err != nil || removed {
+		t.Errorf("RemoveBannedWord() from another server = %t, %v, want it left alone", removed, err)
+	}
+
+	words, err := s.BannedWords()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []BannedWord{{Word: "spam", Severity: "escalate", ServerID: "1"}}
+	if !reflect.DeepEqual(words, want) {
+		t.Errorf("BannedWords() = %+v, want %+v", words, want)
+	}
+
+	removed, err = s.RemoveBannedWord("1", "spam")
+	if err != nil || !removed {
+		t.Errorf("RemoveBannedWord() = %t, %v, want the word removed", removed, err)
+	}
+
+	// Words banned before servers were recorded belong to whoever bans
+	// them next.
+	if _, err := s.db.Exec("INSERT INTO wordban (word, severity) VALUES ('old', 'block')"); err != nil {
+		t.Fatal(err)
+	}
+	added, err = s.AddBannedWord(BannedWord{Word: "old", Severity: "escalate", ServerID: "2"})
+	if err != nil || !added {
+		t.Errorf("AddBannedWord() of an old word = %t, %v, want it taken over", added, err)
+	}
+}
+
+func TestBotSettings(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "channels.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.SetBotSetting("paused", "maintenance"); err != nil {
+		t.Fatal(err)
+	}
+	settings, err := LoadSettings(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := settings.Bot("paused"); got != "maintenance" {
+		t.Errorf("Bot(paused) = %q, want maintenance", got)
+	}
+	if guilds := settings.Guilds(); len(guilds) != 0 {
+		t.Errorf("Guilds() = %v, want no guilds", guilds)
+	}
+
+	if err := settings.SetBot("paused", ""); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := s.BotSettings()
+	if err != nil || len(stored) != 0 {
+		t.Errorf("BotSettings() = %v, %v, want none", stored, err)
+	}
+}
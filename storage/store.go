@@ -0,0 +1,283 @@
+// Package storage persists the bot's configuration and records. The bot talks
+// to a Store; SQLite is the implementation used today.
+package storage
+
+import "time"
+
+// Store is the persistence the bot needs.
+type Store interface {
+	ChannelConfig
+	BanWords
+	SettingStore
+	Stats
+	History
+	Routes
+	Subscriptions
+	MessageLinks
+	SkipLog
+	Warnings
+	Close() error
+}
+
+// ChannelConfig stores the translated channels of each server.
+type ChannelConfig interface {
+	// TranslateChannels returns the translated channels of every server.
+	TranslateChannels() (map[string][3]string, error)
+	// SetTranslateChannels replaces the server's translated channels. Empty
+	// IDs leave a slot unset.
+	SetTranslateChannels(serverID string, channelIDs [3]string) error
+}
+
+// BanWords stores the words that stop a message from being translated.
+type BanWords interface {
+	BannedWords() ([]BannedWord, error)
+	// AddBannedWord adds the word or changes its severity and expiry,
+	// reporting false when it was already banned just like that or when
+	// another server banned it.
+	AddBannedWord(word BannedWord) (bool, error)
+	// RemoveBannedWord removes the word unless another server banned it,
+	// reporting whether it was removed.
+	RemoveBannedWord(serverID, word string) (bool, error)
+	// PruneBannedWords removes the words that expired before the time and
+	// returns how many were removed.
+	PruneBannedWords(before time.Time) (int64, error)
+}
+
+// BannedWord is an entry of the ban list. Severity is empty for words
+// banned before severities existed. A zero ExpiresAt never expires.
+// ServerID is the server that banned the word, empty for words banned before
+// entries recorded it.
+type BannedWord struct {
+	Word      string
+	Severity  string
+	ExpiresAt time.Time
+	ServerID  string
+}
+
+// SettingStore stores bot, guild, channel and user settings.
+type SettingStore interface {
+	// BotSettings returns the settings that apply to the bot as a whole.
+	BotSettings() (map[string]string, error)
+	// SetBotSetting stores a bot setting. An empty value removes it.
+	SetBotSetting(key, value string) error
+	// GuildSettings returns the settings of every server by server ID.
+	GuildSettings() (map[string]map[string]string, error)
+	// SetGuildSetting stores a server setting. An empty value removes it.
+	SetGuildSetting(serverID, key, value string) error
+	// ChannelSettings returns the settings of every channel by channel ID.
+	ChannelSettings() (map[string]map[string]string, error)
+	// SetChannelSetting stores a channel setting. An empty value removes it.
+	SetChannelSetting(serverID, channelID, key, value string) error
+
+	SetUserLocale(userID, locale string) error
+	// UserLocale returns the user's client locale, or an empty string when
+	// it isn't known.
+	UserLocale(userID string) (string, error)
+}
+
+// Stats records translation activity. Days are formatted as YYYY-MM-DD in
+// the server's timezone, UTC unless it chose one, except billing days, which
+// are in UTC.
+type Stats interface {
+	RecordUsage(serverID, day string, characters int) error
+	// UsageSince returns the characters translated for the server on or
+	// after the day.
+	UsageSince(serverID, day string) (int, error)
+
+	RecordBilling(backend, keyID, serverID, day string, characters int) error
+	// Billing returns billed characters per backend, key and server for the
+	// month (YYYY-MM).
+	Billing(month string) ([]BillingRecord, error)
+
+	RecordUserStats(serverID, userID string, characters int) error
+	// TopUsers returns the server's members with the most translations.
+	TopUsers(serverID string, limit int) ([]UserStats, error)
+
+	RecordPairStats(serverID, sourceLang, targetLang string) error
+	// TopPairs returns the server's most translated language pairs.
+	TopPairs(serverID string, limit int) ([]PairStats, error)
+
+	RecordLanguageUsage(serverID, day, sourceLang string) error
+	// LanguageUsageSince returns translations per source language on or
+	// after the day, most used first.
+	LanguageUsageSince(serverID, day string) ([]LanguageCount, error)
+
+	RecordError(serverID, day string) error
+	// ErrorsSince returns the server's translation errors on or after the
+	// day.
+	ErrorsSince(serverID, day string) (int, error)
+
+	RecordBanwordHit(serverID, word string, at time.Time) error
+	// BanwordHits returns how often each banned word matched in the server,
+	// most matched first.
+	BanwordHits(serverID string) ([]BanwordHits, error)
+}
+
+// History stores translated messages so they can be replayed later.
+type History interface {
+	// RecordMessage stores the message, replacing an earlier record of it.
+	RecordMessage(message HistoryMessage) error
+	// Messages returns the server's most recent messages, oldest first. An
+	// empty server ID returns messages of all servers.
+	Messages(serverID string, limit int) ([]HistoryMessage, error)
+}
+
+// HistoryMessage is a message the bot handled and what it posted for it.
+type HistoryMessage struct {
+	MessageID  string
+	ServerID   string
+	ChannelID  string
+	AuthorID   string
+	AuthorName string
+	Content    string
+	// Output is what the bot posted, or empty when it skipped the message.
+	Output    string
+	CreatedAt time.Time
+}
+
+// Routes stores per-guild translation routes between channels.
+type Routes interface {
+	// Routes returns the routes of every server.
+	Routes() ([]Route, error)
+	// SetRoute adds the route, replacing an existing route between the same
+	// channels.
+	SetRoute(route Route) error
+	// RemoveRoute removes the route between the channels, reporting false
+	// when there was none.
+	RemoveRoute(serverID, sourceChannelID, destinationChannelID string) (bool, error)
+}
+
+// Route sends translations of messages in one channel to another channel.
+type Route struct {
+	ServerID             string
+	SourceChannelID      string
+	DestinationChannelID string
+	TargetLang           string
+	// AuthorMode decides how the author is shown on routed translations.
+	AuthorMode string
+}
+
+// Subscriptions stores users' subscriptions to translations of a channel
+// delivered by direct message.
+type Subscriptions interface {
+	// Subscriptions returns the subscriptions of every user.
+	Subscriptions() ([]Subscription, error)
+	// Subscribe adds the subscription, replacing the user's existing
+	// subscription to the same channel.
+	Subscribe(subscription Subscription) error
+	// Unsubscribe removes the user's subscription to the channel, reporting
+	// false when there was none.
+	Unsubscribe(userID, channelID string) (bool, error)
+}
+
+// Subscription delivers translations of a channel's messages to a user.
+type Subscription struct {
+	UserID     string
+	ServerID   string
+	ChannelID  string
+	TargetLang string
+	// Frequency decides how often the translations are delivered.
+	Frequency string
+}
+
+// MessageLinks stores which messages are translations of which originals.
+type MessageLinks interface {
+	// LinkMessage records that a translation was posted for an original.
+	LinkMessage(link MessageLink) error
+	// MessageLinks returns the links of the message, whether it is the
+	// original or a translation.
+	MessageLinks(messageID string) ([]MessageLink, error)
+	// PruneMessageLinks removes the server's links created before the time,
+	// or every server's when serverID is empty, and returns how many there
+	// were.
+	PruneMessageLinks(serverID string, before time.Time) (int64, error)
+}
+
+// MessageLink connects an original message to one of its translations.
+type MessageLink struct {
+	ServerID             string
+	OriginalChannelID    string
+	OriginalMessageID    string
+	TranslationChannelID string
+	TranslationMessageID string
+	CreatedAt            time.Time
+}
+
+// SkipLog stores why messages weren't translated.
+type SkipLog interface {
+	// RecordSkip stores a skipped message.
+	RecordSkip(skip Skip) error
+	// Skips returns the server's most recent skipped messages, newest
+	// first, only those of the channel unless channelID is empty.
+	Skips(serverID, channelID string, limit int) ([]Skip, error)
+	// PruneSkips removes skipped messages recorded before the time.
+	PruneSkips(before time.Time) (int64, error)
+}
+
+// Skip is a message that wasn't translated and the reason why.
+type Skip struct {
+	ServerID  string
+	ChannelID string
+	MessageID string
+	Reason    string
+	CreatedAt time.Time
+}
+
+// BillingRecord is the number of characters billed to one API key for one
+// server.
+type BillingRecord struct {
+	Backend    string
+	KeyID      string
+	ServerID   string
+	Characters int
+}
+
+// UserStats is a member's translation activity.
+type UserStats struct {
+	UserID       string
+	Translations int
+	Characters   int
+}
+
+// PairStats is the number of translations between two languages.
+type PairStats struct {
+	SourceLang   string
+	TargetLang   string
+	Translations int
+}
+
+// BanwordHits counts the messages a banned word matched in a server.
+type BanwordHits struct {
+	Word    string
+	Hits    int
+	LastHit time.Time
+}
+
+// LanguageCount is the number of translations from one language.
+type LanguageCount struct {
+	Language     string
+	Translations int
+}
+
+// Warnings stores the moderation hits of server members.
+type Warnings interface {
+	RecordWarning(warning Warning) error
+	// Warnings returns the member's most recent warnings, newest first.
+	Warnings(serverID, userID string, limit int) ([]Warning, error)
+	// WarningCount returns how many warnings the member got since the time.
+	WarningCount(serverID, userID string, since time.Time) (int, error)
+	// ClearWarnings removes the member's warnings.
+	ClearWarnings(serverID, userID string) error
+	// PruneWarnings removes warnings recorded before the time.
+	PruneWarnings(before time.Time) (int64, error)
+}
+
+// Warning is a message of a member that hit the moderation filters.
+type Warning struct {
+	ServerID  string
+	UserID    string
+	ChannelID string
+	MessageID string
+	Reason    string
+	CreatedAt time.Time
+}
@@ -0,0 +1,113 @@
+package translation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// languageCode matches the language codes passed to translate-shell, so
+// that user input can't be read as one of its options.
+var languageCode = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z]+)?$`)
+
+// Dictionary looks up senses and synonyms of a single word.
+type Dictionary interface {
+	Define(word, sourceLang, targetLang string) (string, error)
+}
+
+// NewAzureDictionary returns a dictionary backed by the Azure Translator
+// dictionary lookup API.
+func NewAzureDictionary(apiKey, region string) Dictionary {
+	return &azureDictionary{apiKey: apiKey, region: region}
+}
+
+func (b *translateShellBackend) Define(word, sourceLang, targetLang string) (string, error) {
+	for _, lang := range []string{sourceLang, targetLang} {
+		if !languageCode.MatchString(lang) {
+			return "", fmt.Errorf("invalid language code %q", lang)
+		}
+	}
+	cmd := exec.Command(b.path, "-no-ansi", "-d", sourceLang+":"+targetLang, "--", word)
+
+	var out bytes.Buffer
+	var stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf("cmd.Run() failed with %s: %s", err, stderr.String())
+	}
+
+	return strings.TrimSpace(out.String()), nil
+}
+
+type azureDictionary struct {
+	apiKey string
+	region string
+}
+
+func (d *azureDictionary) Define(word, sourceLang, targetLang string) (string, error) {
+	query := url.Values{"api-version": {"3.0"}, "from": {sourceLang}, "to": {targetLang}}
+	body, err := json.Marshal([]map[string]string{{"Text": word}})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodPost, "https://api.cognitive.microsofttranslator.com/dictionary/lookup?"+query.Encode(), bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Ocp-Apim-Subscription-Key", d.apiKey)
+	if d.region != "" {
+		req.Header.Set("Ocp-Apim-Subscription-Region", d.region)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := HTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("azure returned %s: %s", resp.Status, respBody)
+	}
+
+	var result []struct {
+		Translations []struct {
+			DisplayTarget    string  `json:"displayTarget"`
+			PosTag           string  `json:"posTag"`
+			Confidence       float64 `json:"confidence"`
+			BackTranslations []struct {
+				DisplayText string `json:"displayText"`
+			} `json:"backTranslations"`
+		} `json:"translations"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	if len(result) == 0 {
+		return "", nil
+	}
+
+	var lines []string
+	for _, translation := range result[0].Translations {
+		var synonyms []string
+		for _, back := range translation.BackTranslations {
+			synonyms = append(synonyms, back.DisplayText)
+		}
+		line := fmt.Sprintf("**%s** (%s)", translation.DisplayTarget, strings.ToLower(translation.PosTag))
+		if len(synonyms) > 0 {
+			line += ": " + strings.Join(synonyms, ", ")
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n"), nil
+}
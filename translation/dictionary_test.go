@@ -0,0 +1,31 @@
+package translation
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTranslateShellDefineArguments(t *testing.T) {
+	// The fake translate-shell prints its arguments, one per line.
+	path := filepath.Join(t.TempDir(), "trans")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\nfor arg; do echo \"$arg\"; done\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	b := &translateShellBackend{path: path}
+
+	got, err := b.Define("-no-translate", "en", "pt-BR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "-no-ansi\n-d\nen:pt-BR\n--\n-no-translate"
+	if got != want {
+		t.Errorf("Define() ran translate-shell with %q, want %q", got, want)
+	}
+
+	for _, langs := range [][2]string{{"-x", "en"}, {"en", "en:fr"}, {"", "en"}, {"english", "fr"}} {
+		if _, err := b.Define("word", langs[0], langs[1]); err == nil {
+			t.Errorf("Define(%q, %q) accepted an invalid language code", langs[0], langs[1])
+		}
+	}
+}
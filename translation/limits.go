@@ -0,0 +1,172 @@
+package translation
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Limits bound how the bot uses a backend. Zero MaxCharacters and
+// RequestsPerSecond mean no limit.
+type Limits struct {
+	// Timeout is how long a request may take.
+	Timeout time.Duration
+	// MaxCharacters is the longest text sent in one request. Longer texts
+	// are split into several.
+	MaxCharacters int
+	// RequestsPerSecond is how often requests may be sent. Requests over
+	// the rate wait their turn.
+	RequestsPerSecond float64
+}
+
+// defaultLimits apply to whatever the environment leaves out. Translate-shell
+// starts a process per request and LLMs answer slowly, so they get longer;
+// Google recommends requests of at most 5000 characters.
+var defaultLimits = map[string]Limits{
+	"translate-shell": {Timeout: 30 * time.Second},
+	"deepl":           {Timeout: 15 * time.Second},
+	"google":          {Timeout: 15 * time.Second, MaxCharacters: 5000},
+	"llm":             {Timeout: 60 * time.Second},
+}
+
+// LimitsFor returns the limits of the named backend, overridden by
+// <NAME>_TIMEOUT, <NAME>_MAX_CHARACTERS and <NAME>_REQUESTS_PER_SECOND,
+// where NAME is the backend name in upper case, such as TRANSLATE_SHELL.
+func LimitsFor(name string) Limits {
+	limits, ok := defaultLimits[name]
+	if !ok {
+		limits.Timeout = HTTPClient.Timeout
+	}
+
+	prefix := strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+	if timeout, err := time.ParseDuration(os.Getenv(prefix + "TIMEOUT")); err == nil && timeout > 0 {
+		limits.Timeout = timeout
+	}
+	if maxCharacters, err := strconv.Atoi(os.Getenv(prefix + "MAX_CHARACTERS")); err == nil && maxCharacters >= 0 {
+		limits.MaxCharacters = maxCharacters
+	}
+	if perSecond, err := strconv.ParseFloat(os.Getenv(prefix+"REQUESTS_PER_SECOND"), 64); err == nil && perSecond >= 0 {
+		limits.RequestsPerSecond = perSecond
+	}
+	return limits
+}
+
+// clientFor returns an HTTP client with the backend's timeout.
+func clientFor(name string) *http.Client {
+	client := *HTTPClient
+	client.Timeout = LimitsFor(name).Timeout
+	return &client
+}
+
+// Translate translates the text with the backend within the backend's
+// limits, waiting for its turn and splitting text longer than
+// MaxCharacters at line, sentence or word breaks.
+func Translate(b Backend, text, targetLang string, opts Options) (string, error) {
+	return TranslateContext(context.Background(), b, text, targetLang, opts)
+}
+
+// TranslateContext is Translate with a context that cancels the requests of
+// backends that support it.
+func TranslateContext(ctx context.Context, b Backend, text, targetLang string, opts Options) (string, error) {
+	limits := LimitsFor(b.Name())
+	chunks, separators := splitText(text, limits.MaxCharacters)
+
+	var translated strings.Builder
+	partial := opts.Partial
+	for n, chunk := range chunks {
+		waitTurn(b.Name(), limits.RequestsPerSecond)
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		if partial != nil {
+			// Later chunks continue the translation of the earlier ones.
+			done := translated.String()
+			opts.Partial = func(text string) { partial(done + text) }
+		}
+		var result string
+		var err error
+		if cb, ok := b.(ContextBackend); ok {
+			result, err = cb.TranslateContext(ctx, chunk, targetLang, opts)
+		} else {
+			result, err = b.Translate(chunk, targetLang, opts)
+		}
+		if err != nil {
+			return "", err
+		}
+		translated.WriteString(result)
+		translated.WriteString(separators[n])
+	}
+	return translated.String(), nil
+}
+
+// splitText cuts the text into chunks of at most max characters, returning
+// them with the separator that followed each.
+func splitText(text string, max int) (chunks, separators []string) {
+	for max > 0 && utf8.RuneCountInString(text) > max {
+		// Byte offset of the first character past the limit.
+		limit := 0
+		for n := 0; n < max; n++ {
+			_, size := utf8.DecodeRuneInString(text[limit:])
+			limit += size
+		}
+		window := text[:limit]
+
+		cut, separator := -1, ""
+		if at := strings.LastIndex(window, "\n"); at > 0 {
+			cut, separator = at, "\n"
+		} else if at := lastSentenceEnd(window); at > 0 {
+			cut, separator = at, " "
+		} else if at := strings.LastIndex(window, " "); at > 0 {
+			cut, separator = at, " "
+		}
+		if cut < 0 {
+			chunks, separators = append(chunks, window), append(separators, "")
+			text = text[limit:]
+			continue
+		}
+		chunks, separators = append(chunks, window[:cut]), append(separators, separator)
+		text = text[cut+1:]
+	}
+	return append(chunks, text), append(separators, "")
+}
+
+// lastSentenceEnd returns the offset of the space after the last sentence in
+// the text, or -1.
+func lastSentenceEnd(text string) int {
+	end := -1
+	for _, mark := range []string{". ", "! ", "? "} {
+		if at := strings.LastIndex(text, mark); at >= 0 && at+1 > end {
+			end = at + 1
+		}
+	}
+	return end
+}
+
+var (
+	turnsMu sync.Mutex
+	// nextTurn is when each backend may be sent its next request.
+	nextTurn = make(map[string]time.Time)
+)
+
+// waitTurn blocks until the backend may be sent another request at the
+// rate.
+func waitTurn(name string, perSecond float64) {
+	if perSecond <= 0 {
+		return
+	}
+	turnsMu.Lock()
+	now := time.Now()
+	turn := nextTurn[name]
+	if turn.Before(now) {
+		turn = now
+	}
+	nextTurn[name] = turn.Add(time.Duration(float64(time.Second) / perSecond))
+	turnsMu.Unlock()
+
+	time.Sleep(time.Until(turn))
+}
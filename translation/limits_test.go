@@ -0,0 +1,107 @@
+package translation
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordingBackend upper-cases texts and records each request it's sent.
+type recordingBackend struct {
+	name     string
+	requests []string
+	sent     []time.Time
+}
+
+func (b *recordingBackend) Name() string   { return b.name }
+func (b *recordingBackend) APIKey() string { return "" }
+
+func (b *recordingBackend) Translate(text, targetLang string, opts Options) (string, error) {
+	b.requests = append(b.requests, text)
+	b.sent = append(b.sent, time.Now())
+	return strings.ToUpper(text), nil
+}
+
+func TestLimitsFor(t *testing.T) {
+	if got := LimitsFor("google"); got.Timeout != 15*time.Second || got.MaxCharacters != 5000 {
+		t.Errorf("LimitsFor(google) = %+v, want the defaults", got)
+	}
+	if got := LimitsFor("unknown"); got.Timeout != HTTPClient.Timeout {
+		t.Errorf("LimitsFor(unknown).Timeout = %v, want %v", got.Timeout, HTTPClient.Timeout)
+	}
+
+	t.Setenv("TRANSLATE_SHELL_TIMEOUT", "5s")
+	t.Setenv("TRANSLATE_SHELL_MAX_CHARACTERS", "100")
+	t.Setenv("TRANSLATE_SHELL_REQUESTS_PER_SECOND", "2.5")
+	want := Limits{Timeout: 5 * time.Second, MaxCharacters: 100, RequestsPerSecond: 2.5}
+	if got := LimitsFor("translate-shell"); got != want {
+		t.Errorf("LimitsFor(translate-shell) = %+v, want %+v", got, want)
+	}
+
+	// Invalid values leave the defaults alone.
+	t.Setenv("GOOGLE_TIMEOUT", "soon")
+	t.Setenv("GOOGLE_MAX_CHARACTERS", "-1")
+	if got := LimitsFor("google"); got.Timeout != 15*time.Second || got.MaxCharacters != 5000 {
+		t.Errorf("LimitsFor(google) with invalid overrides = %+v, want the defaults", got)
+	}
+}
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		text string
+		max  int
+		want []string
+		seps []string
+	}{
+		{"short", 10, []string{"short"}, []string{""}},
+		{"no limit at all", 0, []string{"no limit at all"}, []string{""}},
+		{"first line\nsecond line", 15, []string{"first line", "second line"}, []string{"\n", ""}},
+		{"One. Two three four", 12, []string{"One.", "Two three", "four"}, []string{" ", " ", ""}},
+		{"abcdefgh", 3, []string{"abc", "def", "gh"}, []string{"", "", ""}},
+		{"ééééé", 2, []string{"éé", "éé", "é"}, []string{"", "", ""}},
+	}
+	for _, test := range tests {
+		chunks, seps := splitText(test.text, test.max)
+		if strings.Join(chunks, "|") != strings.Join(test.want, "|") || strings.Join(seps, "|") != strings.Join(test.seps, "|") {
+			t.Errorf("splitText(%q, %d) = %q, %q, want %q, %q", test.text, test.max, chunks, seps, test.want, test.seps)
+		}
+	}
+}
+
+func TestTranslateSplitsLongText(t *testing.T) {
+	t.Setenv("RECORDING_MAX_CHARACTERS", "12")
+	b := &recordingBackend{name: "recording"}
+
+	got, err := Translate(b, "first line\nOne. Two three", "en", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "FIRST LINE\nONE. TWO THREE"; got != want {
+		t.Errorf("Translate() = %q, want %q", got, want)
+	}
+	if len(b.requests) != 3 {
+		t.Errorf("Translate() sent %q, want three requests", b.requests)
+	}
+	for _, request := range b.requests {
+		if len(request) > 12 {
+			t.Errorf("Translate() sent %q, longer than the limit", request)
+		}
+	}
+}
+
+func TestTranslateWaitsTurn(t *testing.T) {
+	t.Setenv("PACED_REQUESTS_PER_SECOND", "20")
+	b := &recordingBackend{name: "paced"}
+
+	for n := 0; n < 3; n++ {
+		if _, err := Translate(b, "text", "en", Options{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// At 20 requests per second, requests are at least 50ms apart.
+	for n := 1; n < len(b.sent); n++ {
+		if gap := b.sent[n].Sub(b.sent[n-1]); gap < 45*time.Millisecond {
+			t.Errorf("request %d was sent %v after the previous one, want at least 50ms", n, gap)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package translation
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Presets are additions to the system prompt that help the model with the
+// jargon of common kinds of communities.
+var Presets = map[string]string{
+	"gaming":   "The messages come from a gaming community. Keep game names, item names and gamer slang such as gg, nerf, buff or aggro recognizable, and translate slang into the equivalent slang of the target language rather than literally.",
+	"business": "The messages come from a professional workspace. Use precise, formal business language and keep product names, acronyms and technical terms unchanged.",
+	"anime":    "The messages come from an anime and manga fandom. Keep Japanese honorifics (-san, -kun, -senpai), character names and fandom terms such as isekai or waifu untranslated.",
+}
+
+// NewLLMFromEnv builds the LLM backend from LLM_API_KEY, LLM_URL and
+// LLM_MODEL. It returns nil when no key is set.
+func NewLLMFromEnv() Backend {
+	apiKey := os.Getenv("LLM_API_KEY")
+	if apiKey == "" {
+		return nil
+	}
+	endpoint := os.Getenv("LLM_URL")
+	if endpoint == "" {
+		endpoint = "https://api.openai.com/v1/chat/completions"
+	}
+	model := os.Getenv("LLM_MODEL")
+	if model == "" {
+		model = "gpt-4o-mini"
+	}
+	return &llmBackend{endpoint: endpoint, model: model, apiKey: apiKey}
+}
+
+// llmBackend translates with an OpenAI-compatible chat completions API.
+type llmBackend struct {
+	endpoint string
+	model    string
+	apiKey   string
+}
+
+func (b *llmBackend) Name() string {
+	return "llm"
+}
+
+func (b *llmBackend) APIKey() string {
+	return b.apiKey
+}
+
+// systemPrompt instructs the model to translate, applying the options.
+func (b *llmBackend) systemPrompt(targetLang string, opts Options) string {
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "You translate chat messages from a Discord server into the language with the code %q. ", targetLang)
+	prompt.WriteString("Reply with the translation only, keeping emoji, mentions and formatting intact.")
+	switch opts.Formality {
+	case FormalityFormal:
+		prompt.WriteString(" Use a formal, polite register (for example Sie, vous or usted).")
+	case FormalityInformal:
+		prompt.WriteString(" Use an informal, casual register (for example du, tu or tú).")
+	}
+	if preset := Presets[opts.Preset]; preset != "" {
+		prompt.WriteString(" " + preset)
+	}
+	if len(opts.Context) > 0 {
+		prompt.WriteString("\n\nEarlier messages in the conversation, for context only. Do not translate them:\n")
+		prompt.WriteString(strings.Join(opts.Context, "\n"))
+	}
+	return prompt.String()
+}
+
+func (b *llmBackend) Translate(text, targetLang string, opts Options) (string, error) {
+	return b.TranslateContext(context.Background(), text, targetLang, opts)
+}
+
+func (b *llmBackend) TranslateContext(ctx context.Context, text, targetLang string, opts Options) (string, error) {
+	type message struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	}
+	body, err := json.Marshal(struct {
+		Model    string    `json:"model"`
+		Messages []message `json:"messages"`
+		Stream   bool      `json:"stream,omitempty"`
+	}{
+		Model: b.model,
+		Messages: []message{
+			{Role: "system", Content: b.systemPrompt(targetLang, opts)},
+			{Role: "user", Content: text},
+		},
+		Stream: opts.Partial != nil,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+b.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := clientFor(b.Name()).Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		if resp.StatusCode == http.StatusTooManyRequests && bytes.Contains(respBody, []byte("insufficient_quota")) {
+			return "", ErrQuotaExceeded
+		}
+		return "", fmt.Errorf("llm returned %s: %s", resp.Status, respBody)
+	}
+
+	if opts.Partial != nil {
+		return readStream(resp.Body, opts.Partial)
+	}
+
+	var result struct {
+		Choices []struct {
+			Message message `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	if len(result.Choices) == 0 {
+		return "", fmt.Errorf("llm returned no choices")
+	}
+	return strings.TrimSpace(result.Choices[0].Message.Content), nil
+}
+
+// readStream collects a streamed chat completion, passing the text received
+// so far to partial after every chunk.
+func readStream(body io.Reader, partial func(translated string)) (string, error) {
+	var translated strings.Builder
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			break
+		}
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return "", err
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+		translated.WriteString(chunk.Choices[0].Delta.Content)
+		partial(strings.TrimSpace(translated.String()))
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	if translated.Len() == 0 {
+		return "", fmt.Errorf("llm returned no content")
+	}
+	return strings.TrimSpace(translated.String()), nil
+}
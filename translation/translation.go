@@ -0,0 +1,306 @@
+// Package translation implements the machine translation backends the bot
+// can use and the dictionary lookups some of them support.
+package translation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Backend translates text into a target language.
+type Backend interface {
+	Name() string
+	// APIKey returns the key requests are billed to, or an empty string for
+	// backends that aren't metered.
+	APIKey() string
+	Translate(text, targetLang string, opts Options) (string, error)
+}
+
+// QuotaReporter is implemented by backends whose provider reports how much
+// of the account's quota is used.
+type QuotaReporter interface {
+	Quota() (used, limit int64, err error)
+}
+
+// ContextBackend is implemented by backends whose requests can be canceled.
+type ContextBackend interface {
+	Backend
+	TranslateContext(ctx context.Context, text, targetLang string, opts Options) (string, error)
+}
+
+// Options tunes a translation. Backends ignore options they don't support.
+type Options struct {
+	// Formality is FormalityFormal, FormalityInformal or empty for the
+	// backend's default register.
+	Formality string
+	// Preset names an entry of Presets, or is empty.
+	Preset string
+	// Context holds earlier messages of the conversation, already redacted,
+	// that help the translator resolve pronouns and short replies.
+	Context []string
+	// Partial, when set, is called with the translation so far by backends
+	// that stream their output.
+	Partial func(translated string) `json:"-"`
+}
+
+const (
+	FormalityFormal   = "formal"
+	FormalityInformal = "informal"
+)
+
+// ErrQuotaExceeded is returned when the provider rejects a request because
+// the account's quota is used up.
+var ErrQuotaExceeded = errors.New("translation provider quota exceeded")
+
+// HTTPClient is shared by the backends that call web APIs. Backends use it
+// with the timeout from their Limits.
+var HTTPClient = &http.Client{Timeout: 15 * time.Second}
+
+// NewFromEnv builds the backend selected by TRANSLATE_BACKEND,
+// defaulting to translate-shell.
+func NewFromEnv() (Backend, error) {
+	switch name := os.Getenv("TRANSLATE_BACKEND"); name {
+	case "", "translate-shell":
+		path := os.Getenv("TRANSLATE_PATH")
+		if path == "" {
+			return nil, fmt.Errorf("TRANSLATE_PATH environment variable is not set")
+		}
+		if _, err := exec.LookPath(path); err != nil {
+			return nil, fmt.Errorf("TRANSLATE_PATH is not an executable: %w", err)
+		}
+		return &translateShellBackend{path: path}, nil
+	case "deepl":
+		apiKey := os.Getenv("DEEPL_API_KEY")
+		if apiKey == "" {
+			return nil, fmt.Errorf("DEEPL_API_KEY environment variable is not set")
+		}
+		return &deeplBackend{apiKey: apiKey}, nil
+	case "google":
+		apiKey := os.Getenv("GOOGLE_API_KEY")
+		if apiKey == "" {
+			return nil, fmt.Errorf("GOOGLE_API_KEY environment variable is not set")
+		}
+		return &googleBackend{apiKey: apiKey}, nil
+	default:
+		return nil, fmt.Errorf("unknown translation backend %q", name)
+	}
+}
+
+// NewKeyed builds a metered backend for the provider using the key.
+func NewKeyed(provider, apiKey string) (Backend, error) {
+	switch provider {
+	case "deepl":
+		return &deeplBackend{apiKey: apiKey}, nil
+	case "google":
+		return &googleBackend{apiKey: apiKey}, nil
+	default:
+		return nil, fmt.Errorf("unknown API key provider %q", provider)
+	}
+}
+
+type translateShellBackend struct {
+	path string
+}
+
+func (b *translateShellBackend) Name() string {
+	return "translate-shell"
+}
+
+func (b *translateShellBackend) APIKey() string {
+	return ""
+}
+
+func (b *translateShellBackend) Translate(text, targetLang string, opts Options) (string, error) {
+	return b.TranslateContext(context.Background(), text, targetLang, opts)
+}
+
+func (b *translateShellBackend) TranslateContext(ctx context.Context, text, targetLang string, opts Options) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, LimitsFor(b.Name()).Timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, b.path, "-b", ":"+targetLang)
+
+	var out bytes.Buffer
+	var stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+
+	cmd.Stdin = strings.NewReader(text)
+
+	err := cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf("cmd.Run() failed with %s: %s", err, stderr.String())
+	}
+
+	return strings.TrimSpace(out.String()), nil
+}
+
+type deeplBackend struct {
+	apiKey string
+}
+
+func (b *deeplBackend) Name() string {
+	return "deepl"
+}
+
+func (b *deeplBackend) APIKey() string {
+	return b.apiKey
+}
+
+func (b *deeplBackend) Translate(text, targetLang string, opts Options) (string, error) {
+	return b.TranslateContext(context.Background(), text, targetLang, opts)
+}
+
+func (b *deeplBackend) TranslateContext(ctx context.Context, text, targetLang string, opts Options) (string, error) {
+	endpoint := "https://api.deepl.com/v2/translate"
+	if strings.HasSuffix(b.apiKey, ":fx") {
+		endpoint = "https://api-free.deepl.com/v2/translate"
+	}
+
+	// DeepL no longer accepts plain "EN" as a target language.
+	targetLang = strings.ToUpper(targetLang)
+	if targetLang == "EN" {
+		targetLang = "EN-US"
+	}
+
+	form := url.Values{"text": {text}, "target_lang": {targetLang}}
+	// The "prefer_" variants fall back to the default register for languages
+	// without formality support instead of failing the request.
+	switch opts.Formality {
+	case FormalityFormal:
+		form.Set("formality", "prefer_more")
+	case FormalityInformal:
+		form.Set("formality", "prefer_less")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "DeepL-Auth-Key "+b.apiKey)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := clientFor(b.Name()).Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	// DeepL uses 456 to signal that the character quota is used up.
+	if resp.StatusCode == 456 {
+		return "", ErrQuotaExceeded
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("deepl returned %s: %s", resp.Status, body)
+	}
+
+	var result struct {
+		Translations []struct {
+			Text string `json:"text"`
+		} `json:"translations"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	if len(result.Translations) == 0 {
+		return "", fmt.Errorf("deepl returned no translations")
+	}
+	return result.Translations[0].Text, nil
+}
+
+// Quota asks DeepL how many characters of the billing period were used.
+func (b *deeplBackend) Quota() (used, limit int64, err error) {
+	endpoint := "https://api.deepl.com/v2/usage"
+	if strings.HasSuffix(b.apiKey, ":fx") {
+		endpoint = "https://api-free.deepl.com/v2/usage"
+	}
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Header.Set("Authorization", "DeepL-Auth-Key "+b.apiKey)
+
+	resp, err := clientFor(b.Name()).Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return 0, 0, fmt.Errorf("deepl returned %s: %s", resp.Status, body)
+	}
+
+	var result struct {
+		CharacterCount int64 `json:"character_count"`
+		CharacterLimit int64 `json:"character_limit"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, 0, err
+	}
+	return result.CharacterCount, result.CharacterLimit, nil
+}
+
+type googleBackend struct {
+	apiKey string
+}
+
+func (b *googleBackend) Name() string {
+	return "google"
+}
+
+func (b *googleBackend) APIKey() string {
+	return b.apiKey
+}
+
+func (b *googleBackend) Translate(text, targetLang string, opts Options) (string, error) {
+	return b.TranslateContext(context.Background(), text, targetLang, opts)
+}
+
+func (b *googleBackend) TranslateContext(ctx context.Context, text, targetLang string, opts Options) (string, error) {
+	form := url.Values{"q": {text}, "target": {targetLang}, "format": {"text"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://translation.googleapis.com/language/translate/v2", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	// Passing the key as a header keeps it out of URLs in error messages.
+	req.Header.Set("X-Goog-Api-Key", b.apiKey)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := clientFor(b.Name()).Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		if resp.StatusCode == http.StatusForbidden && bytes.Contains(body, []byte("LimitExceeded")) {
+			return "", ErrQuotaExceeded
+		}
+		return "", fmt.Errorf("google returned %s: %s", resp.Status, body)
+	}
+
+	var result struct {
+		Data struct {
+			Translations []struct {
+				TranslatedText string `json:"translatedText"`
+			} `json:"translations"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	if len(result.Data.Translations) == 0 {
+		return "", fmt.Errorf("google returned no translations")
+	}
+	return result.Data.Translations[0].TranslatedText, nil
+}